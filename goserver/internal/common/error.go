@@ -3,44 +3,66 @@ package common
 
 // 错误码
 const (
-	OK               = 200  // Success
-	NotLoggedIn      = 1000 // 未登录
-	ParameterIllegal = 1001 // 参数不合法
-	InvalidUserId    = 1002 // 无效的userId
-	InvalidRoomId    = 1003 // 无效的roomId
-	Unauthorized     = 1004 // 未授权
-	ServerError      = 1005 // 系统错误
-	NotData          = 1006 // 没有数据
-	NotUser          = 1007
-	NotRoom          = 1008
-	ModelAddError    = 1009 // 添加错误
-	ModelDeleteError = 1010 // 删除错误
-	ModelStoreError  = 1011 // 存储错误
-	OperationFailure = 1012 // 操作失败
-	RoutingNotExist  = 1013 // 路由不存在
-	HasLoggedIn      = 1014
+	OK                = 200  // Success
+	NotLoggedIn       = 1000 // 未登录
+	ParameterIllegal  = 1001 // 参数不合法
+	InvalidUserId     = 1002 // 无效的userId
+	InvalidRoomId     = 1003 // 无效的roomId
+	Unauthorized      = 1004 // 未授权
+	ServerError       = 1005 // 系统错误
+	NotData           = 1006 // 没有数据
+	NotUser           = 1007
+	NotRoom           = 1008
+	ModelAddError     = 1009 // 添加错误
+	ModelDeleteError  = 1010 // 删除错误
+	ModelStoreError   = 1011 // 存储错误
+	OperationFailure  = 1012 // 操作失败
+	RoutingNotExist   = 1013 // 路由不存在
+	HasLoggedIn       = 1014
+	UserExists        = 1015 // 用户名已存在
+	EmailExists       = 1016 // 邮箱已被使用
+	PasswordError     = 1017 // 密码错误
+	RoomExists        = 1018 // 房间名称已存在
+	RoomLocked        = 1019 // 房间已锁定
+	RoomFull          = 1020 // 房间人数已满
+	RoomPassError     = 1021 // 房间密码错误
+	UserBanned        = 1022 // 已被禁止进入房间
+	ProtocolVersion   = 1023 // 不支持的协议版本
+	ResumeFailed      = 1024 // 会话已失效, 需重新登录
+	MeetingNotStarted = 1025 // 预约的会议未开始
 )
 
 // GetErrorMessage 根据错误码 获取错误信息
 func GetErrorMessage(code uint64, message string) string {
 	var codeMessage string
 	codeMap := map[uint64]string{
-		OK:               "Success",
-		NotLoggedIn:      "未登录",
-		ParameterIllegal: "参数不合法",
-		InvalidUserId:    "无效的用户ID",
-		InvalidRoomId:    "无效的房间ID",
-		Unauthorized:     "未授权",
-		NotData:          "没有数据",
-		NotUser:          "用户不存在",
-		NotRoom:          "房间不存在",
-		ServerError:      "系统错误",
-		ModelAddError:    "添加错误",
-		ModelDeleteError: "删除错误",
-		ModelStoreError:  "存储错误",
-		OperationFailure: "操作失败",
-		RoutingNotExist:  "路由不存在",
-		HasLoggedIn:      "用户已登录",
+		OK:                "Success",
+		NotLoggedIn:       "未登录",
+		ParameterIllegal:  "参数不合法",
+		InvalidUserId:     "无效的用户ID",
+		InvalidRoomId:     "无效的房间ID",
+		Unauthorized:      "未授权",
+		NotData:           "没有数据",
+		NotUser:           "用户不存在",
+		NotRoom:           "房间不存在",
+		ServerError:       "系统错误",
+		ModelAddError:     "添加错误",
+		ModelDeleteError:  "删除错误",
+		ModelStoreError:   "存储错误",
+		OperationFailure:  "操作失败",
+		RoutingNotExist:   "路由不存在",
+		HasLoggedIn:       "用户已登录",
+		UserExists:        "用户名已存在",
+		EmailExists:       "邮箱已被使用",
+		PasswordError:     "密码错误",
+		RoomExists:        "房间名称已存在",
+		RoomLocked:        "房间已锁定",
+		RoomFull:          "房间人数已满",
+		RoomPassError:     "房间密码错误",
+		UserBanned:        "已被禁止进入房间",
+		ProtocolVersion:   "不支持的协议版本",
+		ResumeFailed:      "会话已失效, 请重新登录",
+		MeetingNotStarted: "会议未开始",
 	}
 
 	if message == "" {
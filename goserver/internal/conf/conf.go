@@ -73,6 +73,10 @@ func Init() error {
 		return errors.Wrap(err, "mapping [avatar] section")
 	} else if err = File.Section("attachment").MapTo(&Attachment); err != nil {
 		return errors.Wrap(err, "mapping [attachment] section")
+	} else if err = File.Section("recording").MapTo(&Recording); err != nil {
+		return errors.Wrap(err, "mapping [recording] section")
+	} else if err = File.Section("websocket").MapTo(&WebSocket); err != nil {
+		return errors.Wrap(err, "mapping [websocket] section")
 	}
 
 	// ----- Server 设置 -----
@@ -89,6 +93,9 @@ func Init() error {
 	// 子路径应以 / 开头, 不以 / 结尾, i.e. conf.Server.Subpath + "/data".
 	Server.Subpath = strings.TrimRight(Server.URL.Path, "/")
 	Server.SubpathDepth = strings.Count(Server.Subpath, "/")
+	if Server.ShutdownTimeout <= 0 {
+		Server.ShutdownTimeout = 30 * time.Second
+	}
 
 	// ----- Database 设置 -----
 	Database.Path = ensureAbs(Database.Path)
@@ -96,6 +103,57 @@ func Init() error {
 	// ----- Cors 跨域设置 -----
 	Cors.MaxAge = Cors.MaxAge * time.Second
 
+	// ----- WebSocket 设置 -----
+	if WebSocket.SendQueueSize <= 0 {
+		WebSocket.SendQueueSize = 256
+	}
+	if WebSocket.SlowConsumer != "disconnect" {
+		WebSocket.SlowConsumer = "drop"
+	}
+	if WebSocket.WriteTimeout <= 0 {
+		WebSocket.WriteTimeout = 10 * time.Second
+	}
+	if WebSocket.PongTimeout <= 0 {
+		WebSocket.PongTimeout = 60 * time.Second
+	}
+	// ping 间隔须小于 pong 超时, 否则连接会在收到 pong 前超时
+	if WebSocket.PingInterval <= 0 || WebSocket.PingInterval >= WebSocket.PongTimeout {
+		WebSocket.PingInterval = WebSocket.PongTimeout * 9 / 10
+	}
+	if WebSocket.HeartbeatTimeout <= 0 {
+		WebSocket.HeartbeatTimeout = 6 * time.Minute
+	}
+	if WebSocket.CleanInterval <= 0 {
+		WebSocket.CleanInterval = 30 * time.Second
+	}
+	// 在线状态由定时任务刷新, 超时须大于刷新间隔
+	if WebSocket.OnlineTimeout <= WebSocket.CleanInterval {
+		WebSocket.OnlineTimeout = 3 * time.Minute
+		if WebSocket.OnlineTimeout <= WebSocket.CleanInterval {
+			WebSocket.OnlineTimeout = WebSocket.CleanInterval * 3
+		}
+	}
+
+	// ----- Attachment 设置 -----
+	Attachment.Path = ensureAbs(Attachment.Path)
+	if Attachment.MaxSize <= 0 {
+		Attachment.MaxSize = 4
+	}
+	if Attachment.MaxFiles <= 0 {
+		Attachment.MaxFiles = 5
+	}
+
+	// ----- Recording 设置 -----
+	if Recording.MaxChunkSize <= 0 {
+		Recording.MaxChunkSize = 16
+	}
+	if Recording.MaxSize <= 0 {
+		Recording.MaxSize = 4096
+	}
+	if Recording.CleanInterval <= 0 {
+		Recording.CleanInterval = time.Hour
+	}
+
 	// ----- Avatar 设置 -----
 	Avatar.AvatarUploadPath = ensureAbs(Avatar.AvatarUploadPath)
 	Avatar.RepositoryAvatarUploadPath = ensureAbs(Avatar.RepositoryAvatarUploadPath)
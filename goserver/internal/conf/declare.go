@@ -20,9 +20,12 @@ type ServerOpts struct {
   HTTPAddr    string `ini:"HTTP_ADDR"`
   HTTPPort    string `ini:"HTTP_PORT"`
   SocketPort  string `ini:"SOCKET_PORT"`
+  RPCAddr     string `ini:"RPC_ADDR"` // 内部 RPC 监听地址, 为空时使用本节点注册的 IP
   RPCPort     string `ini:"RPC_PORT"`
   ExternalURL string `ini:"EXTERNAL_URL"`
 
+  ShutdownTimeout time.Duration `ini:"SHUTDOWN_TIMEOUT"` // 关闭服务时等待连接断开的最长时间
+
   CertFile string
   KeyFile  string
 
@@ -87,7 +90,10 @@ type CorsOpts struct {
 
 // SecurityOpts 安全设置
 type SecurityOpts struct {
-  SecretKey string
+  SecretKey       string
+  AccessTokenTTL  time.Duration `ini:"ACCESS_TOKEN_TTL"`
+  RefreshTokenTTL time.Duration `ini:"REFRESH_TOKEN_TTL"`
+  ClusterSecret   string        `ini:"CLUSTER_SECRET"` // 节点间内部 RPC 的共享密钥
 }
 
 // AttachmentOpts 附件设置
@@ -99,6 +105,30 @@ type AttachmentOpts struct {
   MaxFiles     int
 }
 
+// RecordingOpts 会议录制设置, 录制文件保存在 AppDataPath 下的 recordings 目录
+type RecordingOpts struct {
+  Enabled       bool
+  MaxChunkSize  int64         // 每个录制分片的最大大小(MB)
+  MaxSize       int64         // 每个录制文件的最大大小(MB)
+  AllowedTypes  []string      `delim:"|"`
+  Retention     time.Duration // 录制文件的保留时长, 为 0 时不自动删除
+  CleanInterval time.Duration // 清理过期录制文件的间隔
+}
+
+// WebSocketOpts websocket 连接设置
+type WebSocketOpts struct {
+  SendQueueSize int           `ini:"SEND_QUEUE_SIZE"` // 每个连接待发送队列的长度
+  SlowConsumer  string        `ini:"SLOW_CONSUMER"`   // 待发送队列已满时: drop 丢弃消息 | disconnect 断开连接
+  WriteTimeout  time.Duration `ini:"WRITE_TIMEOUT"`
+  PingInterval  time.Duration `ini:"PING_INTERVAL"`
+  PongTimeout   time.Duration `ini:"PONG_TIMEOUT"`
+  ResumeGrace   time.Duration `ini:"RESUME_GRACE"` // 连接断开后保留会话的时间, 为 0 时不保留
+
+  HeartbeatTimeout time.Duration `ini:"HEARTBEAT_TIMEOUT"` // 超过该时长未收到 heartbeat 或 pong 则关闭连接
+  OnlineTimeout    time.Duration `ini:"ONLINE_TIMEOUT"`    // Redis 中的在线状态超过该时长未刷新视为离线
+  CleanInterval    time.Duration `ini:"CLEAN_INTERVAL"`    // 清理超时连接并刷新在线状态的间隔
+}
+
 var (
   BuildTime   string
   BuildCommit string
@@ -115,8 +145,11 @@ var (
   Security SecurityOpts
   Redis    RedisOpts
 
+  WebSocket WebSocketOpts
+
   Avatar     AvatarOpts
   Attachment AttachmentOpts
+  Recording  RecordingOpts
 
   // ConfigFile app.ini 配置文件路径
   ConfigFile string
@@ -25,6 +25,18 @@ func SetMockServer(t *testing.T, opts ServerOpts) {
 	})
 }
 
+var mockSecurity sync.Mutex
+
+func SetMockSecurity(t *testing.T, opts SecurityOpts) {
+	mockSecurity.Lock()
+	before := Security
+	Security = opts
+	t.Cleanup(func() {
+		Security = before
+		mockSecurity.Unlock()
+	})
+}
+
 var mockAvatar sync.Mutex
 
 func SetMockAvatar(t *testing.T, opts AvatarOpts) {
@@ -36,3 +48,39 @@ func SetMockAvatar(t *testing.T, opts AvatarOpts) {
 		mockAvatar.Unlock()
 	})
 }
+
+var mockWebSocket sync.Mutex
+
+func SetMockWebSocket(t *testing.T, opts WebSocketOpts) {
+	mockWebSocket.Lock()
+	before := WebSocket
+	WebSocket = opts
+	t.Cleanup(func() {
+		WebSocket = before
+		mockWebSocket.Unlock()
+	})
+}
+
+var mockRecording sync.Mutex
+
+func SetMockRecording(t *testing.T, opts RecordingOpts) {
+	mockRecording.Lock()
+	before := Recording
+	Recording = opts
+	t.Cleanup(func() {
+		Recording = before
+		mockRecording.Unlock()
+	})
+}
+
+var mockAttachment sync.Mutex
+
+func SetMockAttachment(t *testing.T, opts AttachmentOpts) {
+	mockAttachment.Lock()
+	before := Attachment
+	Attachment = opts
+	t.Cleanup(func() {
+		Attachment = before
+		mockAttachment.Unlock()
+	})
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 
 	"github.com/gin-gonic/gin"
+	"io.wandao.meeting/internal/common"
 	"io.wandao.meeting/internal/conf"
 	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/libs/cache"
 	"io.wandao.meeting/internal/utils/jwtutil"
 
 	log "unknwon.dev/clog/v2"
@@ -18,7 +20,8 @@ import (
 
 type APIContext struct {
 	*gin.Context
-	User *db.User
+	User   *db.User
+	Claims *jwtutil.UserClaims
 }
 
 const (
@@ -26,15 +29,20 @@ const (
 	SuccessCode = 0
 )
 
+const (
+	userKey   = "user"   // 授权用户
+	claimsKey = "claims" // 授权令牌声明
+)
+
 // ResponseData 响应 JSON 结构体
 type ResponseData struct {
-	Code    uint8       `json:"code"`
+	Code    uint64      `json:"code"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
 }
 
 // genResult 按照接口格式生成原数据数组
-func genResult(code uint8, message string, data interface{}) ResponseData {
+func genResult(code uint64, message string, data interface{}) ResponseData {
 	jsonMap := ResponseData{
 		Code:    code,
 		Message: message,
@@ -53,6 +61,16 @@ func (c *APIContext) ResultError(message string) {
 	})
 }
 
+// ResultCode 按 common 错误码响应错误
+// c.ResultCode(common.UserExists, "")
+func (c *APIContext) ResultCode(code uint64, message string) {
+	c.JSON(http.StatusOK, ResponseData{
+		Code:    code,
+		Message: common.GetErrorMessage(code, message),
+		Data:    nil,
+	})
+}
+
 // ResultSuccess 响应成功
 func (c *APIContext) ResultSuccess(data interface{}) {
 	if data == nil {
@@ -67,7 +85,14 @@ func (c *APIContext) ResultSuccess(data interface{}) {
 
 func Handle(handler func(c *APIContext)) func(ctx *gin.Context) {
 	return func(c *gin.Context) {
-		handler(&APIContext{Context: c})
+		ctx := &APIContext{Context: c}
+		if user, ok := c.Get(userKey); ok {
+			ctx.User = user.(*db.User)
+		}
+		if claims, ok := c.Get(claimsKey); ok {
+			ctx.Claims = claims.(*jwtutil.UserClaims)
+		}
+		handler(ctx)
 	}
 }
 
@@ -164,6 +189,15 @@ func AuthMiddleware(ctx *gin.Context) {
 		return
 	}
 
+	revoked, err := cache.IsTokenRevoked(uc.ID)
+	if err != nil || revoked {
+		ctx.AbortWithStatusJSON(
+			http.StatusOK,
+			genResult(ErrorCode, "Unauthorized or revoked", nil),
+		)
+		return
+	}
+
 	user, err := db.Users.GetByID(ctx, uc.Id)
 	if err != nil {
 		ctx.AbortWithStatusJSON(
@@ -173,11 +207,35 @@ func AuthMiddleware(ctx *gin.Context) {
 		return
 	}
 
+	// 修改密码后之前签发的令牌失效
+	if uc.Version != user.TokenVersion {
+		ctx.AbortWithStatusJSON(
+			http.StatusOK,
+			genResult(ErrorCode, "Unauthorized or revoked", nil),
+		)
+		return
+	}
+
 	// 将授权用户的信息添加到上下文件
-	c := &APIContext{
-		Context: ctx,
-		User:    user,
+	ctx.Set(userKey, user)
+	ctx.Set(claimsKey, uc)
+
+	ctx.Next()
+}
+
+// FeedTokenMiddleware 日历订阅令牌认证中间件, 日历客户端无法携带 Authorization 头, 令牌在订阅地址中
+// 只设置授权用户, 不设置令牌声明, 仅用于日历订阅接口
+func FeedTokenMiddleware(ctx *gin.Context) {
+	user, err := db.Users.GetByFeedToken(ctx, ctx.Param("token"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(
+			http.StatusOK,
+			genResult(ErrorCode, "Unauthorized or revoked", nil),
+		)
+		return
 	}
 
-	c.Next()
+	ctx.Set(userKey, user)
+
+	ctx.Next()
 }
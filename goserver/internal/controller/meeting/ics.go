@@ -0,0 +1,116 @@
+package meeting
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/context"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/utils/icalutil"
+)
+
+// Export 导出单个会议的 iCalendar 文件, 仅组织者和受邀用户可导出
+func Export(c *context.APIContext) {
+	meeting, ok := getInvitedMeeting(c)
+	if !ok {
+		return
+	}
+	calendar := &icalutil.Calendar{
+		Name:   meeting.Title,
+		Events: newEvents(c, meeting),
+	}
+	writeCalendar(c, fmt.Sprintf("meeting-%d.ics", meeting.Id), calendar)
+}
+
+// Feed 当前用户组织或受邀的全部会议的 iCalendar 订阅, 已取消的会议标记为 CANCELLED
+func Feed(c *context.APIContext) {
+	meetings, err := db.Meetings.ListByUser(c.Request.Context(), c.User.Id)
+	if err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+	calendar := &icalutil.Calendar{
+		Name:   fmt.Sprintf("%s - %s", conf.App.BrandName, c.User.Name),
+		Events: newEvents(c, meetings...),
+	}
+	writeCalendar(c, "meetings.ics", calendar)
+}
+
+// FeedURL 当前用户的日历订阅地址, 首次获取时生成订阅令牌
+func FeedURL(c *context.APIContext) {
+	token := c.User.FeedToken
+	if token == "" {
+		var err error
+		token, err = db.Users.ResetFeedToken(c.Request.Context(), c.User.Id)
+		if err != nil {
+			c.ResultCode(common.ModelStoreError, err.Error())
+			return
+		}
+	}
+	c.ResultSuccess(gin.H{"url": feedURL(token)})
+}
+
+// ResetFeed 重置日历订阅令牌, 原订阅地址随即失效
+func ResetFeed(c *context.APIContext) {
+	token, err := db.Users.ResetFeedToken(c.Request.Context(), c.User.Id)
+	if err != nil {
+		c.ResultCode(common.ModelStoreError, err.Error())
+		return
+	}
+	c.ResultSuccess(gin.H{"url": feedURL(token)})
+}
+
+func feedURL(token string) string {
+	return fmt.Sprintf("%scalendar/%s/meetings.ics", conf.Server.ExternalURL, token)
+}
+
+func writeCalendar(c *context.APIContext, filename string, calendar *icalutil.Calendar) {
+	c.Header("Content-Type", icalutil.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+	_, _ = calendar.WriteTo(c.Writer)
+}
+
+// newEvents 将会议转换为日历事件, 组织者和受邀用户按用户 ID 查询一次
+func newEvents(c *context.APIContext, meetings ...*db.Meeting) []*icalutil.Event {
+	people := make(map[uint64]*icalutil.Person)
+	person := func(userId uint64) *icalutil.Person {
+		if p, ok := people[userId]; ok {
+			return p
+		}
+		var p *icalutil.Person
+		if user, err := db.Users.GetByID(c.Request.Context(), userId); err == nil {
+			p = &icalutil.Person{Name: user.Name, Email: user.Email}
+		}
+		people[userId] = p
+		return p
+	}
+
+	events := make([]*icalutil.Event, 0, len(meetings))
+	for _, meeting := range meetings {
+		// 重复规则在保存前已校验
+		recurrence, _ := icalutil.ParseRecurrence(meeting.Recurrence)
+		event := &icalutil.Event{
+			UID:         fmt.Sprintf("meeting-%d@%s", meeting.Id, conf.Server.Domain),
+			Sequence:    meeting.Sequence,
+			Summary:     meeting.Title,
+			Description: meeting.Description,
+			Start:       meeting.StartAt,
+			End:         meeting.EndAt,
+			Stamp:       meeting.UpdatedAt,
+			Recurrence:  recurrence,
+			Canceled:    meeting.Canceled,
+			Organizer:   person(meeting.OrganizerId),
+		}
+		for _, userId := range meeting.Invitees {
+			if p := person(userId); p != nil {
+				event.Attendees = append(event.Attendees, p)
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
@@ -0,0 +1,254 @@
+// Package meeting 预约会议接口
+package meeting
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/context"
+	"io.wandao.meeting/internal/controller/types"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/utils/icalutil"
+)
+
+const (
+	maxTitleLength       = 128            // 会议标题最大长度
+	maxDescriptionLength = 1024           // 会议描述最大长度
+	maxInvitees          = 200            // 最多受邀用户数
+	maxDuration          = 24 * time.Hour // 单次会议最长时间
+	maxRecurrenceLength  = 255            // 重复规则最大长度
+)
+
+// Create 预约会议, 仅房间主持人及以上角色可操作, 创建者为组织者
+func Create(c *context.APIContext) {
+	var in types.MeetingSave
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.ResultCode(common.ParameterIllegal, "")
+		return
+	}
+	if in.RoomId == 0 {
+		c.ResultCode(common.InvalidRoomId, "")
+		return
+	}
+	ctx := c.Request.Context()
+	role, err := db.RoomMembers.GetRole(ctx, in.RoomId, c.User.Id)
+	if err != nil {
+		c.ResultCode(common.NotRoom, "")
+		return
+	}
+	if !role.AtLeast(db.RoleHost) {
+		c.ResultCode(common.Unauthorized, "仅主持人可以预约会议")
+		return
+	}
+
+	meeting := &db.Meeting{
+		RoomId:      in.RoomId,
+		OrganizerId: c.User.Id,
+		Title:       in.Title,
+		Description: in.Description,
+		StartAt:     in.StartAt,
+		EndAt:       in.EndAt,
+		Recurrence:  in.Recurrence,
+		Invitees:    in.Invitees,
+	}
+	if !validateMeeting(c, meeting) {
+		return
+	}
+	if err = db.Meetings.Create(ctx, meeting); err != nil {
+		c.ResultCode(common.ModelAddError, err.Error())
+		return
+	}
+	c.ResultSuccess(meeting)
+}
+
+// List 获取当前用户组织或受邀的会议
+func List(c *context.APIContext) {
+	meetings, err := db.Meetings.ListByUser(c.Request.Context(), c.User.Id)
+	if err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+	c.ResultSuccess(meetings)
+}
+
+// Info 查看会议, 仅组织者和受邀用户可查看
+func Info(c *context.APIContext) {
+	meeting, ok := getInvitedMeeting(c)
+	if !ok {
+		return
+	}
+	c.ResultSuccess(meeting)
+}
+
+// Update 修改会议, 仅组织者可操作, 已取消的会议不能修改
+func Update(c *context.APIContext) {
+	meeting, ok := getOrganizedMeeting(c)
+	if !ok {
+		return
+	}
+	var in types.MeetingUpdate
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.ResultCode(common.ParameterIllegal, "")
+		return
+	}
+
+	if in.Title != nil {
+		meeting.Title = *in.Title
+	}
+	if in.Description != nil {
+		meeting.Description = *in.Description
+	}
+	if in.StartAt != nil {
+		meeting.StartAt = *in.StartAt
+	}
+	if in.EndAt != nil {
+		meeting.EndAt = *in.EndAt
+	}
+	if in.Recurrence != nil {
+		meeting.Recurrence = *in.Recurrence
+	}
+	if in.Invitees != nil {
+		meeting.Invitees = *in.Invitees
+	}
+	if !validateMeeting(c, meeting) {
+		return
+	}
+
+	// 使用校验后的值更新
+	opts := db.UpdateMeetingOptions{
+		Title:       &meeting.Title,
+		Description: &meeting.Description,
+		StartAt:     &meeting.StartAt,
+		EndAt:       &meeting.EndAt,
+		Recurrence:  &meeting.Recurrence,
+	}
+	if in.Invitees != nil {
+		opts.Invitees = &meeting.Invitees
+	}
+	ctx := c.Request.Context()
+	if err := db.Meetings.Update(ctx, meeting.Id, opts); err != nil {
+		c.ResultCode(common.ModelStoreError, err.Error())
+		return
+	}
+	meeting, err := db.Meetings.GetByID(ctx, meeting.Id)
+	if err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+	c.ResultSuccess(meeting)
+}
+
+// Cancel 取消会议, 仅组织者可操作
+func Cancel(c *context.APIContext) {
+	meeting, ok := getOrganizedMeeting(c)
+	if !ok {
+		return
+	}
+	if err := db.Meetings.Cancel(c.Request.Context(), meeting.Id); err != nil {
+		c.ResultCode(common.ModelStoreError, err.Error())
+		return
+	}
+	c.ResultSuccess(nil)
+}
+
+// getInvitedMeeting 获取路径中的会议并校验当前用户是否为组织者或受邀用户
+func getInvitedMeeting(c *context.APIContext) (*db.Meeting, bool) {
+	var uri types.MeetingUri
+	if err := c.ShouldBindUri(&uri); err != nil || uri.Id == 0 {
+		c.ResultCode(common.ParameterIllegal, "无效的会议ID")
+		return nil, false
+	}
+	meeting, err := db.Meetings.GetByID(c.Request.Context(), uri.Id)
+	if err != nil {
+		c.ResultCode(common.NotData, "会议不存在")
+		return nil, false
+	}
+	if !meeting.IsInvited(c.User.Id) {
+		c.ResultCode(common.Unauthorized, "仅组织者和受邀用户可以查看")
+		return nil, false
+	}
+	return meeting, true
+}
+
+// getOrganizedMeeting 获取路径中未取消的会议并校验当前用户是否为组织者
+func getOrganizedMeeting(c *context.APIContext) (*db.Meeting, bool) {
+	meeting, ok := getInvitedMeeting(c)
+	if !ok {
+		return nil, false
+	}
+	if meeting.OrganizerId != c.User.Id {
+		c.ResultCode(common.Unauthorized, "仅组织者可以操作")
+		return nil, false
+	}
+	if meeting.Canceled {
+		c.ResultCode(common.OperationFailure, "会议已取消")
+		return nil, false
+	}
+	return meeting, true
+}
+
+// validateMeeting 校验并规范化会议的标题、时间、重复规则和受邀用户
+func validateMeeting(c *context.APIContext, meeting *db.Meeting) bool {
+	meeting.Title = strings.TrimSpace(meeting.Title)
+	if meeting.Title == "" || utf8.RuneCountInString(meeting.Title) > maxTitleLength {
+		c.ResultCode(common.ParameterIllegal, fmt.Sprintf("会议标题须为1-%d个字符", maxTitleLength))
+		return false
+	}
+	if utf8.RuneCountInString(meeting.Description) > maxDescriptionLength {
+		c.ResultCode(common.ParameterIllegal, fmt.Sprintf("会议描述不能超过%d个字符", maxDescriptionLength))
+		return false
+	}
+
+	if meeting.StartAt.IsZero() || !meeting.EndAt.After(meeting.StartAt) {
+		c.ResultCode(common.ParameterIllegal, "结束时间须晚于开始时间")
+		return false
+	}
+	duration := meeting.EndAt.Sub(meeting.StartAt)
+	if duration > maxDuration {
+		c.ResultCode(common.ParameterIllegal, "单次会议不能超过24小时")
+		return false
+	}
+	if len(meeting.Recurrence) > maxRecurrenceLength {
+		c.ResultCode(common.ParameterIllegal, "重复规则过长")
+		return false
+	}
+	recurrence, err := icalutil.ParseRecurrence(meeting.Recurrence)
+	if err != nil {
+		c.ResultCode(common.ParameterIllegal, err.Error())
+		return false
+	}
+	if recurrence != nil && duration > recurrence.Period() {
+		c.ResultCode(common.ParameterIllegal, "会议时长不能超过重复间隔")
+		return false
+	}
+	meeting.Recurrence = recurrence.String()
+	if _, _, ok := meeting.NextWindow(time.Now()); !ok {
+		c.ResultCode(common.ParameterIllegal, "会议已结束")
+		return false
+	}
+
+	invitees := make([]uint64, 0, len(meeting.Invitees))
+	seen := map[uint64]bool{meeting.OrganizerId: true}
+	for _, userId := range meeting.Invitees {
+		if seen[userId] {
+			continue
+		}
+		seen[userId] = true
+		invitees = append(invitees, userId)
+	}
+	if len(invitees) > maxInvitees {
+		c.ResultCode(common.ParameterIllegal, fmt.Sprintf("受邀用户不能超过%d人", maxInvitees))
+		return false
+	}
+	for _, userId := range invitees {
+		if _, err = db.Users.GetByID(c.Request.Context(), userId); err != nil {
+			c.ResultCode(common.NotUser, fmt.Sprintf("受邀用户不存在(%d)", userId))
+			return false
+		}
+	}
+	meeting.Invitees = invitees
+	return true
+}
@@ -0,0 +1,207 @@
+// Package recording 会议录制接口
+package recording
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/context"
+	"io.wandao.meeting/internal/controller/types"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/recording"
+	log "unknwon.dev/clog/v2"
+)
+
+// maxPageSize 每页最大数量
+const maxPageSize = 100
+
+// List 分页查询录制
+// 指定的房间属于当前用户时返回房间内的全部录制, 否则只返回自己的录制
+func List(c *context.APIContext) {
+	var in types.RecordingQuery
+	if err := c.ShouldBindQuery(&in); err != nil {
+		c.ResultCode(common.ParameterIllegal, "")
+		return
+	}
+	if in.PageSize > maxPageSize {
+		in.PageSize = maxPageSize
+	}
+
+	opts := db.ListRecordingsOptions{
+		RoomId:   in.RoomId,
+		UserId:   c.User.Id,
+		Page:     in.Page,
+		PageSize: in.PageSize,
+	}
+	if in.RoomId > 0 {
+		room, err := db.Rooms.GetByID(c.Request.Context(), in.RoomId)
+		if err != nil {
+			c.ResultCode(common.NotRoom, "")
+			return
+		}
+		if room.IsOwner(c.User.Id) {
+			opts.UserId = 0
+		}
+	}
+	list, total, err := db.Recordings.List(c.Request.Context(), opts)
+	if err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+	c.ResultSuccess(gin.H{
+		"list":  list,
+		"total": total,
+	})
+}
+
+// Info 查看录制
+func Info(c *context.APIContext) {
+	r, ok := getRecording(c)
+	if !ok {
+		return
+	}
+	c.ResultSuccess(r)
+}
+
+// Download 下载录制文件
+func Download(c *context.APIContext) {
+	r, ok := getRecording(c)
+	if !ok {
+		return
+	}
+	if r.Size == 0 {
+		c.ResultCode(common.NotData, "录制文件尚未上传")
+		return
+	}
+	c.Header("Content-Type", r.MimeType)
+	c.FileAttachment(recording.FilePath(r), fmt.Sprintf("recording-%d-%d%s", r.RoomId, r.Id, fileExt(r.MimeType)))
+}
+
+// Delete 删除录制及录制文件, 录制中不能删除
+func Delete(c *context.APIContext) {
+	r, ok := getRecording(c)
+	if !ok {
+		return
+	}
+	if r.Status == db.RecordingActive {
+		c.ResultCode(common.OperationFailure, "录制中不能删除")
+		return
+	}
+	if err := recording.Remove(c.Request.Context(), r); err != nil {
+		c.ResultCode(common.ModelDeleteError, err.Error())
+		return
+	}
+	c.ResultSuccess(nil)
+}
+
+// UploadChunk 上传录制分片, 请求体为分片数据, 仅开始录制的主持人可上传
+// 分片按 seq 顺序追加到录制文件, 首个分片的 Content-Type 作为录制文件类型
+func UploadChunk(c *context.APIContext) {
+	if !conf.Recording.Enabled {
+		c.ResultCode(common.OperationFailure, "未启用会议录制")
+		return
+	}
+	var uri types.RecordingUri
+	var in types.RecordingChunkQuery
+	if err := c.ShouldBindUri(&uri); err != nil || uri.Id == 0 {
+		c.ResultCode(common.ParameterIllegal, "无效的录制ID")
+		return
+	}
+	if err := c.ShouldBindQuery(&in); err != nil || in.Seq == nil || *in.Seq < 0 {
+		c.ResultCode(common.ParameterIllegal, "无效的分片序号")
+		return
+	}
+
+	ctx := c.Request.Context()
+	r, err := db.Recordings.GetByID(ctx, uri.Id)
+	if err != nil {
+		c.ResultCode(common.NotData, "录制不存在")
+		return
+	}
+	if r.UserId != c.User.Id {
+		c.ResultCode(common.Unauthorized, "仅开始录制的主持人可以上传")
+		return
+	}
+	var mimeType string
+	if *in.Seq == 0 {
+		mimeType, _, err = mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if err != nil || !isAllowedType(mimeType) {
+			c.ResultCode(common.ParameterIllegal, fmt.Sprintf("不支持的录制文件类型: %s", c.GetHeader("Content-Type")))
+			return
+		}
+	}
+
+	maxSize := conf.Recording.MaxChunkSize << 20
+	if c.Request.ContentLength > maxSize {
+		c.ResultCode(common.ParameterIllegal, fmt.Sprintf("分片不能超过 %dMB", conf.Recording.MaxChunkSize))
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+
+	r, err = recording.AppendChunk(ctx, r.Id, *in.Seq, mimeType, c.Request.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, recording.ErrNotUploadable), errors.Is(err, recording.ErrChunkOrder), errors.Is(err, recording.ErrTooLarge):
+			c.ResultCode(common.OperationFailure, err.Error())
+		case errors.As(err, &maxBytesErr):
+			c.ResultCode(common.ParameterIllegal, fmt.Sprintf("分片不能超过 %dMB", conf.Recording.MaxChunkSize))
+		default:
+			log.Error("[Recording]保存录制分片失败: %d %d %v", uri.Id, *in.Seq, err)
+			c.ResultCode(common.ModelStoreError, err.Error())
+		}
+		return
+	}
+	c.ResultSuccess(r)
+}
+
+// getRecording 获取路径中的录制并校验当前用户是否为录制的所有者或房间所有者
+func getRecording(c *context.APIContext) (*db.Recording, bool) {
+	var uri types.RecordingUri
+	if err := c.ShouldBindUri(&uri); err != nil || uri.Id == 0 {
+		c.ResultCode(common.ParameterIllegal, "无效的录制ID")
+		return nil, false
+	}
+	r, err := db.Recordings.GetByID(c.Request.Context(), uri.Id)
+	if err != nil {
+		c.ResultCode(common.NotData, "录制不存在")
+		return nil, false
+	}
+	if r.UserId == c.User.Id {
+		return r, true
+	}
+	room, err := db.Rooms.GetByID(c.Request.Context(), r.RoomId)
+	if err != nil || !room.IsOwner(c.User.Id) {
+		c.ResultCode(common.Unauthorized, "仅录制者和房间所有者可以访问")
+		return nil, false
+	}
+	return r, true
+}
+
+// isAllowedType 录制文件类型是否在 conf.Recording.AllowedTypes 中, 为空时允许任何类型
+func isAllowedType(mimeType string) bool {
+	if len(conf.Recording.AllowedTypes) == 0 {
+		return true
+	}
+	for _, allowed := range conf.Recording.AllowedTypes {
+		if strings.TrimSpace(allowed) == mimeType {
+			return true
+		}
+	}
+	return false
+}
+
+// fileExt 录制文件类型对应的扩展名
+func fileExt(mimeType string) string {
+	_, subtype, ok := strings.Cut(mimeType, "/")
+	if !ok || subtype == "" {
+		return ""
+	}
+	return "." + subtype
+}
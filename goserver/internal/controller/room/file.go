@@ -0,0 +1,227 @@
+package room
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/context"
+	"io.wandao.meeting/internal/controller/types"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/protocol"
+	"io.wandao.meeting/internal/server/websocket"
+	"io.wandao.meeting/internal/utils/tool"
+	log "unknwon.dev/clog/v2"
+)
+
+// sniffLength 识别文件类型时读取的字节数, 与 http.DetectContentType 一致
+const sniffLength = 512
+
+// uploadFile 校验通过待保存的文件
+type uploadFile struct {
+	header   *multipart.FileHeader
+	mimeType string
+}
+
+// UploadFiles 上传共享文件并通知房间内的成员, 仅房间内的成员可上传
+func UploadFiles(c *context.APIContext) {
+	if !conf.Attachment.Enabled {
+		c.ResultCode(common.OperationFailure, "未启用文件共享")
+		return
+	}
+	var uri types.RoomUri
+	if err := c.ShouldBindUri(&uri); err != nil || uri.Id == 0 {
+		c.ResultCode(common.InvalidRoomId, "")
+		return
+	}
+	room, ok := getMemberRoom(c, uri.Id)
+	if !ok {
+		return
+	}
+
+	maxSize := conf.Attachment.MaxSize << 20
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize*int64(conf.Attachment.MaxFiles)+1<<20)
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.ResultCode(common.ParameterIllegal, "文件过大或格式错误")
+		return
+	}
+	headers := form.File["files"]
+	if len(headers) == 0 || len(headers) > conf.Attachment.MaxFiles {
+		c.ResultCode(common.ParameterIllegal, fmt.Sprintf("每次可上传1-%d个文件", conf.Attachment.MaxFiles))
+		return
+	}
+
+	// 全部文件校验通过后再保存
+	files := make([]*uploadFile, 0, len(headers))
+	for _, header := range headers {
+		f, code, msg := checkFile(header, maxSize)
+		if code != common.OK {
+			c.ResultCode(code, msg)
+			return
+		}
+		files = append(files, f)
+	}
+
+	// 任一文件保存失败时删除已保存的文件, 全部保存后再通知房间内的成员
+	result := make([]*db.RoomFile, 0, len(files))
+	for _, f := range files {
+		file, err := saveFile(c, room.Id, f)
+		if err != nil {
+			log.Error("[Room]保存共享文件失败: %d %s %v", room.Id, f.header.Filename, err)
+			removeFiles(result)
+			c.ResultCode(common.ModelStoreError, err.Error())
+			return
+		}
+		result = append(result, file)
+	}
+	if err = db.RoomFiles.Create(c.Request.Context(), result...); err != nil {
+		log.Error("[Room]保存共享文件信息失败: %d %v", room.Id, err)
+		removeFiles(result)
+		c.ResultCode(common.ModelStoreError, err.Error())
+		return
+	}
+
+	userName := c.User.Name
+	for _, file := range result {
+		websocket.NotifyFileShared(&protocol.FileSharedEvent{
+			Id:       file.Id,
+			RoomId:   file.RoomId,
+			UserId:   file.UserId,
+			UserName: userName,
+			Name:     file.Name,
+			Size:     file.Size,
+			MimeType: file.MimeType,
+			Url:      fileURL(file),
+		})
+	}
+	c.ResultSuccess(result)
+}
+
+// Files 获取房间内的共享文件
+func Files(c *context.APIContext) {
+	var uri types.RoomUri
+	if err := c.ShouldBindUri(&uri); err != nil || uri.Id == 0 {
+		c.ResultCode(common.InvalidRoomId, "")
+		return
+	}
+	room, ok := getMemberRoom(c, uri.Id)
+	if !ok {
+		return
+	}
+	files, err := db.RoomFiles.List(c.Request.Context(), room.Id)
+	if err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+	c.ResultSuccess(files)
+}
+
+// DownloadFile 下载共享文件, 仅房间内的成员可下载
+func DownloadFile(c *context.APIContext) {
+	var uri types.RoomFileUri
+	if err := c.ShouldBindUri(&uri); err != nil || uri.Id == 0 {
+		c.ResultCode(common.InvalidRoomId, "")
+		return
+	}
+	room, ok := getMemberRoom(c, uri.Id)
+	if !ok {
+		return
+	}
+	file, err := db.RoomFiles.GetByID(c.Request.Context(), room.Id, uri.FileId)
+	if err != nil {
+		c.ResultCode(common.NotData, "文件不存在")
+		return
+	}
+	c.Header("Content-Type", file.MimeType)
+	c.FileAttachment(filepath.Join(conf.Attachment.Path, file.Path), file.Name)
+}
+
+// checkFile 校验文件大小和类型, 类型按文件内容识别
+func checkFile(header *multipart.FileHeader, maxSize int64) (f *uploadFile, code uint64, msg string) {
+	if header.Size > maxSize {
+		return nil, common.ParameterIllegal, fmt.Sprintf("%s 超过 %s", header.Filename, tool.FileSize(maxSize))
+	}
+	mimeType, err := sniffMimeType(header)
+	if err != nil {
+		return nil, context.ErrorCode, err.Error()
+	}
+	if !isAllowedType(mimeType) {
+		return nil, common.ParameterIllegal, fmt.Sprintf("不支持的文件类型: %s", mimeType)
+	}
+	return &uploadFile{header: header, mimeType: mimeType}, common.OK, ""
+}
+
+// sniffMimeType 按文件内容识别类型, 不信任客户端提供的 Content-Type
+func sniffMimeType(header *multipart.FileHeader) (string, error) {
+	src, err := header.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	data := make([]byte, sniffLength)
+	n, err := io.ReadFull(src, data)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return tool.MimeType(data[:n]), nil
+}
+
+// isAllowedType 文件类型是否在 conf.Attachment.AllowedTypes 中, 为空时允许任何类型
+// 支持 image/* 形式的通配
+func isAllowedType(mimeType string) bool {
+	if len(conf.Attachment.AllowedTypes) == 0 {
+		return true
+	}
+	for _, allowed := range conf.Attachment.AllowedTypes {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == mimeType || allowed == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mimeType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// saveFile 将文件保存到附件目录下的房间目录中, 文件信息由调用方统一记录
+func saveFile(c *context.APIContext, roomId uint64, f *uploadFile) (*db.RoomFile, error) {
+	name := filepath.Join(strconv.FormatUint(roomId, 10), uuid.NewString())
+	dst := filepath.Join(conf.Attachment.Path, name)
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := c.SaveUploadedFile(f.header, dst); err != nil {
+		return nil, err
+	}
+
+	return &db.RoomFile{
+		RoomId:   roomId,
+		UserId:   c.User.Id,
+		Name:     filepath.Base(f.header.Filename),
+		Size:     f.header.Size,
+		MimeType: f.mimeType,
+		Path:     filepath.ToSlash(name),
+	}, nil
+}
+
+// removeFiles 删除已保存到附件目录的文件
+func removeFiles(files []*db.RoomFile) {
+	for _, file := range files {
+		_ = os.Remove(filepath.Join(conf.Attachment.Path, filepath.FromSlash(file.Path)))
+	}
+}
+
+// fileURL 共享文件的下载地址
+func fileURL(file *db.RoomFile) string {
+	return fmt.Sprintf("%sroom/%d/files/%d", conf.Server.ExternalURL, file.RoomId, file.Id)
+}
@@ -0,0 +1,80 @@
+package room
+
+import (
+	"bytes"
+	"mime/multipart"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/conf"
+)
+
+func Test_isAllowedType(t *testing.T) {
+	tests := []struct {
+		name     string
+		allowed  []string
+		mimeType string
+		expVal   bool
+	}{
+		{name: "not configured", allowed: nil, mimeType: "application/zip", expVal: true},
+		{name: "exact", allowed: []string{"application/pdf"}, mimeType: "application/pdf", expVal: true},
+		{name: "not listed", allowed: []string{"application/pdf"}, mimeType: "application/zip", expVal: false},
+		{name: "any", allowed: []string{"*/*"}, mimeType: "application/zip", expVal: true},
+		{name: "type wildcard", allowed: []string{"image/*"}, mimeType: "image/png", expVal: true},
+		{name: "other type", allowed: []string{"image/*"}, mimeType: "text/plain", expVal: false},
+		{name: "type prefix only", allowed: []string{"image/*"}, mimeType: "imagex/png", expVal: false},
+		{name: "spaces", allowed: []string{" text/plain ", "image/*"}, mimeType: "text/plain", expVal: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf.SetMockAttachment(t, conf.AttachmentOpts{AllowedTypes: test.allowed})
+			assert.Equal(t, test.expVal, isAllowedType(test.mimeType))
+		})
+	}
+}
+
+func Test_checkFile(t *testing.T) {
+	conf.SetMockAttachment(t, conf.AttachmentOpts{AllowedTypes: []string{"image/*", "application/pdf"}})
+	header := func(data []byte) *multipart.FileHeader {
+		body := &bytes.Buffer{}
+		w := multipart.NewWriter(body)
+		part, err := w.CreateFormFile("files", "upload")
+		require.NoError(t, err)
+		_, err = part.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		form, err := multipart.NewReader(body, w.Boundary()).ReadForm(1 << 20)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = form.RemoveAll() })
+		return form.File["files"][0]
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		maxSize int64
+		expCode uint64
+		expType string
+	}{
+		{name: "png", data: []byte("\x89PNG\x0D\x0A\x1A\x0A"), maxSize: 16, expCode: common.OK, expType: "image/png"},
+		{name: "pdf", data: []byte("%PDF-1.7"), maxSize: 16, expCode: common.OK, expType: "application/pdf"},
+		{name: "not allowed", data: []byte("hello"), maxSize: 16, expCode: common.ParameterIllegal},
+		{name: "max size", data: []byte("%PDF-1.7"), maxSize: 8, expCode: common.OK, expType: "application/pdf"},
+		{name: "too large", data: []byte("%PDF-1.7"), maxSize: 7, expCode: common.ParameterIllegal},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, code, _ := checkFile(header(test.data), test.maxSize)
+			assert.Equal(t, test.expCode, code)
+			if test.expCode == common.OK {
+				require.NotNil(t, f)
+				assert.Equal(t, test.expType, f.mimeType)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package room
+
+import (
+	"github.com/gin-gonic/gin"
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/context"
+	"io.wandao.meeting/internal/controller/types"
+	"io.wandao.meeting/internal/db"
+)
+
+// Messages 分页查询房间聊天记录, 仅房间所有者和房间内的成员可查看
+func Messages(c *context.APIContext) {
+	var uri types.RoomUri
+	if err := c.ShouldBindUri(&uri); err != nil || uri.Id == 0 {
+		c.ResultCode(common.InvalidRoomId, "")
+		return
+	}
+	var in types.RoomMessageQuery
+	if err := c.ShouldBindQuery(&in); err != nil {
+		c.ResultCode(common.ParameterIllegal, "")
+		return
+	}
+	if in.PageSize > maxPageSize {
+		in.PageSize = maxPageSize
+	}
+
+	room, ok := getMemberRoom(c, uri.Id)
+	if !ok {
+		return
+	}
+
+	messages, total, err := db.RoomMessages.List(c.Request.Context(), db.ListRoomMessagesOptions{
+		RoomId:   room.Id,
+		UserId:   c.User.Id,
+		Page:     in.Page,
+		PageSize: in.PageSize,
+	})
+	if err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+	c.ResultSuccess(gin.H{
+		"list":  messages,
+		"total": total,
+	})
+}
@@ -0,0 +1,255 @@
+// Package room 房间管理接口
+package room
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/context"
+	"io.wandao.meeting/internal/controller/types"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/recording"
+	"io.wandao.meeting/internal/server/websocket"
+	log "unknwon.dev/clog/v2"
+)
+
+const (
+	maxNameLength        = 64   // 房间名称最大长度
+	maxPageSize          = 100  // 每页最大数量
+	maxDescriptionLength = 1024 // 房间描述最大长度
+	maxPasswdLength      = 64   // 入会密码最大长度
+)
+
+// Create 创建房间, 创建者为房间所有者
+func Create(c *context.APIContext) {
+	var in types.RoomSave
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.ResultCode(common.ParameterIllegal, "")
+		return
+	}
+	name, ok := validateName(c, in.Name)
+	if !ok {
+		return
+	}
+
+	room, err := db.Rooms.Create(c.Request.Context(), &db.Room{
+		UserId: c.User.Id,
+		Name:   name,
+	})
+	if err != nil {
+		resultRoomError(c, err)
+		return
+	}
+	c.ResultSuccess(room)
+}
+
+// List 分页查询房间
+func List(c *context.APIContext) {
+	var in types.RoomQuery
+	if err := c.ShouldBindQuery(&in); err != nil {
+		c.ResultCode(common.ParameterIllegal, "")
+		return
+	}
+	if in.PageSize > maxPageSize {
+		in.PageSize = maxPageSize
+	}
+
+	opts := db.ListRoomsOptions{
+		Page:     in.Page,
+		PageSize: in.PageSize,
+	}
+	if in.Mine {
+		opts.UserId = c.User.Id
+	}
+	rooms, total, err := db.Rooms.List(c.Request.Context(), opts)
+	if err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+	c.ResultSuccess(gin.H{
+		"list":  rooms,
+		"total": total,
+	})
+}
+
+// Info 查看房间信息
+func Info(c *context.APIContext) {
+	var uri types.RoomUri
+	if err := c.ShouldBindUri(&uri); err != nil || uri.Id == 0 {
+		c.ResultCode(common.InvalidRoomId, "")
+		return
+	}
+	room, err := db.Rooms.GetByID(c.Request.Context(), uri.Id)
+	if err != nil {
+		c.ResultCode(common.NotRoom, "")
+		return
+	}
+	c.ResultSuccess(room)
+}
+
+// UpdateSettings 修改房间设置, 仅房间所有者可操作
+func UpdateSettings(c *context.APIContext) {
+	room, ok := getOwnedRoom(c)
+	if !ok {
+		return
+	}
+	var in types.RoomSettings
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.ResultCode(common.ParameterIllegal, "")
+		return
+	}
+	if in.MaxPeers != nil && *in.MaxPeers < 0 {
+		c.ResultCode(common.ParameterIllegal, "最大人数不能为负数")
+		return
+	}
+	if in.Passwd != nil && utf8.RuneCountInString(*in.Passwd) > maxPasswdLength {
+		c.ResultCode(common.ParameterIllegal, "入会密码不能超过64个字符")
+		return
+	}
+	if in.Description != nil && utf8.RuneCountInString(*in.Description) > maxDescriptionLength {
+		c.ResultCode(common.ParameterIllegal, "房间描述不能超过1024个字符")
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := db.Rooms.UpdateSettings(ctx, room.Id, db.UpdateRoomSettingsOptions{
+		Locked:      in.Locked,
+		Passwd:      in.Passwd,
+		MaxPeers:    in.MaxPeers,
+		Lobby:       in.Lobby,
+		Description: in.Description,
+	})
+	if err != nil {
+		c.ResultCode(common.ModelStoreError, err.Error())
+		return
+	}
+
+	room, err = db.Rooms.GetByID(ctx, room.Id)
+	if err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+	if in.Locked != nil || in.Passwd != nil {
+		websocket.NotifyRoomLock(room.Id, c.User.Id, c.User.Name, room.Locked || room.HasPassword())
+	}
+	c.ResultSuccess(room)
+}
+
+// Rename 重命名房间, 仅房间所有者可操作
+func Rename(c *context.APIContext) {
+	room, ok := getOwnedRoom(c)
+	if !ok {
+		return
+	}
+	var in types.RoomSave
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.ResultCode(common.ParameterIllegal, "")
+		return
+	}
+	name, ok := validateName(c, in.Name)
+	if !ok {
+		return
+	}
+
+	err := db.Rooms.Rename(c.Request.Context(), room.Id, name)
+	if err != nil {
+		resultRoomError(c, err)
+		return
+	}
+	room.Name = name
+	c.ResultSuccess(room)
+}
+
+// Delete 删除房间及其分组讨论房间和全部数据, 仅房间所有者可操作
+func Delete(c *context.APIContext) {
+	room, ok := getOwnedRoom(c)
+	if !ok {
+		return
+	}
+	children, err := db.Rooms.ListByParent(c.Request.Context(), room.Id)
+	if err != nil {
+		c.ResultCode(common.ServerError, err.Error())
+		return
+	}
+	for _, r := range append(children, room) {
+		if len(websocket.UserList(r.Id)) > 0 {
+			c.ResultCode(common.OperationFailure, "房间内仍有成员")
+			return
+		}
+	}
+
+	files, recordings, err := db.Rooms.DeleteWithData(c.Request.Context(), room.Id)
+	if err != nil {
+		c.ResultCode(common.ModelDeleteError, err.Error())
+		return
+	}
+	// 记录已删除, 文件删除失败只记录日志
+	for _, f := range files {
+		if err = os.Remove(filepath.Join(conf.Attachment.Path, f.Path)); err != nil && !os.IsNotExist(err) {
+			log.Error("[Room]删除共享文件失败: %d %s %v", room.Id, f.Path, err)
+		}
+	}
+	for _, r := range recordings {
+		if err = os.Remove(recording.FilePath(r)); err != nil && !os.IsNotExist(err) {
+			log.Error("[Room]删除录制文件失败: %d %s %v", room.Id, r.Path, err)
+		}
+	}
+	c.ResultSuccess(nil)
+}
+
+// getOwnedRoom 获取路径中的房间并校验当前用户是否为所有者
+func getOwnedRoom(c *context.APIContext) (*db.Room, bool) {
+	var uri types.RoomUri
+	if err := c.ShouldBindUri(&uri); err != nil || uri.Id == 0 {
+		c.ResultCode(common.InvalidRoomId, "")
+		return nil, false
+	}
+	room, err := db.Rooms.GetByID(c.Request.Context(), uri.Id)
+	if err != nil {
+		c.ResultCode(common.NotRoom, "")
+		return nil, false
+	}
+	if room.UserId != c.User.Id {
+		c.ResultCode(common.Unauthorized, "仅房间所有者可以操作")
+		return nil, false
+	}
+	return room, true
+}
+
+// getMemberRoom 获取房间并校验当前用户是否为所有者或房间内的成员
+func getMemberRoom(c *context.APIContext, roomId uint64) (*db.Room, bool) {
+	room, err := db.Rooms.GetByID(c.Request.Context(), roomId)
+	if err != nil {
+		c.ResultCode(common.NotRoom, "")
+		return nil, false
+	}
+	if room.UserId != c.User.Id && !websocket.InRoom(room.Id, c.User.Id) {
+		c.ResultCode(common.Unauthorized, "仅房间内的成员可以访问")
+		return nil, false
+	}
+	return room, true
+}
+
+// validateName 校验房间名称
+func validateName(c *context.APIContext, name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		c.ResultCode(common.ParameterIllegal, "房间名称须为1-64个字符")
+		return "", false
+	}
+	return name, true
+}
+
+// resultRoomError 将房间存储错误映射为 common 错误码
+func resultRoomError(c *context.APIContext, err error) {
+	if db.IsErrRoomAlreadyExist(err) {
+		c.ResultCode(common.RoomExists, "")
+		return
+	}
+	c.ResultCode(common.ModelStoreError, err.Error())
+}
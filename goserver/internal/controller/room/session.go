@@ -0,0 +1,122 @@
+package room
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/context"
+	"io.wandao.meeting/internal/controller/types"
+	"io.wandao.meeting/internal/db"
+)
+
+// Sessions 分页查询房间的会议记录, 仅房间所有者可查看
+func Sessions(c *context.APIContext) {
+	room, ok := getOwnedRoom(c)
+	if !ok {
+		return
+	}
+	var in types.RoomSessionQuery
+	if err := c.ShouldBindQuery(&in); err != nil {
+		c.ResultCode(common.ParameterIllegal, "")
+		return
+	}
+	if in.PageSize > maxPageSize {
+		in.PageSize = maxPageSize
+	}
+
+	sessions, total, err := db.MeetingSessions.List(c.Request.Context(), db.ListMeetingSessionsOptions{
+		RoomId:   room.Id,
+		Page:     in.Page,
+		PageSize: in.PageSize,
+	})
+	if err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+	c.ResultSuccess(gin.H{
+		"list":  sessions,
+		"total": total,
+	})
+}
+
+// Attendance 会议的参会报告, 按成员汇总参会次数和累计时长, 仅房间所有者可查看
+// format=csv 时下载 CSV 文件
+func Attendance(c *context.APIContext) {
+	room, ok := getOwnedRoom(c)
+	if !ok {
+		return
+	}
+	var uri types.RoomSessionUri
+	var in types.AttendanceQuery
+	if err := c.ShouldBindUri(&uri); err != nil || uri.SessionId == 0 {
+		c.ResultCode(common.ParameterIllegal, "无效的会议记录ID")
+		return
+	}
+	if err := c.ShouldBindQuery(&in); err != nil || (in.Format != "" && in.Format != "json" && in.Format != "csv") {
+		c.ResultCode(common.ParameterIllegal, "")
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := db.MeetingSessions.GetByID(ctx, room.Id, uri.SessionId)
+	if err != nil {
+		c.ResultCode(common.NotData, "会议记录不存在")
+		return
+	}
+	attendances, err := db.MeetingSessions.ListAttendances(ctx, session.Id)
+	if err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+	summaries := db.SummarizeAttendance(attendances, time.Now())
+
+	if in.Format == "csv" {
+		writeAttendanceCSV(c, session, summaries)
+		return
+	}
+	c.ResultSuccess(gin.H{
+		"session":     session,
+		"summaries":   summaries,
+		"attendances": attendances,
+	})
+}
+
+// writeAttendanceCSV 输出参会报告 CSV, 带 UTF-8 BOM 以便表格软件识别中文
+func writeAttendanceCSV(c *context.APIContext, session *db.MeetingSession, summaries []*db.AttendanceSummary) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"attendance-%d-%d.csv\"", session.RoomId, session.Id))
+	c.Status(http.StatusOK)
+	_, _ = c.Writer.WriteString("\ufeff")
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"userId", "userName", "joins", "firstJoinedAt", "lastLeftAt", "durationSeconds"})
+	for _, s := range summaries {
+		lastLeftAt := ""
+		if s.LastLeftAt != nil {
+			lastLeftAt = s.LastLeftAt.Format(time.RFC3339)
+		}
+		_ = w.Write([]string{
+			strconv.FormatUint(s.UserId, 10),
+			csvCell(s.UserName),
+			strconv.Itoa(s.Joins),
+			s.FirstJoinedAt.Format(time.RFC3339),
+			lastLeftAt,
+			strconv.FormatInt(s.Duration, 10),
+		})
+	}
+	w.Flush()
+}
+
+// csvCell 转义用户输入的单元格, 以 = + - @ 开头时加 ' 前缀, 避免表格软件将其作为公式执行
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
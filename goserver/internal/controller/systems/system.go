@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"io.wandao.meeting/internal/context"
+	"net/http"
 	"runtime"
 
+	"io.wandao.meeting/internal/server/protocol"
 	"io.wandao.meeting/internal/server/websocket"
 )
 
@@ -24,3 +26,8 @@ func Status(c *context.APIContext) {
 		"managerInfo":  websocket.GetManagerInfo(isDebug), // ClientManager 信息
 	})
 }
+
+// ProtocolSchema 信令协议的 JSON Schema 文档, 直接输出便于客户端校验工具使用
+func ProtocolSchema(c *context.APIContext) {
+	c.JSON(http.StatusOK, protocol.Schema())
+}
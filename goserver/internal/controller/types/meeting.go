@@ -0,0 +1,26 @@
+package types
+
+import "time"
+
+type MeetingSave struct {
+	RoomId      uint64    `json:"roomId"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	StartAt     time.Time `json:"startAt"`
+	EndAt       time.Time `json:"endAt"`
+	Recurrence  string    `json:"recurrence"`
+	Invitees    []uint64  `json:"invitees"`
+}
+
+type MeetingUpdate struct {
+	Title       *string    `json:"title"`
+	Description *string    `json:"description"`
+	StartAt     *time.Time `json:"startAt"`
+	EndAt       *time.Time `json:"endAt"`
+	Recurrence  *string    `json:"recurrence"`
+	Invitees    *[]uint64  `json:"invitees"`
+}
+
+type MeetingUri struct {
+	Id uint64 `uri:"id"`
+}
@@ -0,0 +1,15 @@
+package types
+
+type RecordingUri struct {
+	Id uint64 `uri:"id"`
+}
+
+type RecordingQuery struct {
+	RoomId   uint64 `form:"roomId"`
+	Page     int    `form:"page"`
+	PageSize int    `form:"pageSize"`
+}
+
+type RecordingChunkQuery struct {
+	Seq *int `form:"seq"` // 分片序号, 从 0 开始
+}
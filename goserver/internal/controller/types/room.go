@@ -0,0 +1,47 @@
+package types
+
+type RoomSave struct {
+	Name string `json:"name"`
+}
+
+type RoomSettings struct {
+	Locked      *bool   `json:"locked"`
+	Passwd      *string `json:"passwd"`
+	MaxPeers    *int    `json:"maxPeers"`
+	Lobby       *bool   `json:"lobby"`
+	Description *string `json:"description"`
+}
+
+type RoomUri struct {
+	Id uint64 `uri:"id"`
+}
+
+type RoomQuery struct {
+	Page     int  `form:"page"`
+	PageSize int  `form:"pageSize"`
+	Mine     bool `form:"mine"`
+}
+
+type RoomMessageQuery struct {
+	Page     int `form:"page"`
+	PageSize int `form:"pageSize"`
+}
+
+type RoomFileUri struct {
+	Id     uint64 `uri:"id"`
+	FileId uint64 `uri:"fileId"`
+}
+
+type RoomSessionUri struct {
+	Id        uint64 `uri:"id"`
+	SessionId uint64 `uri:"sessionId"`
+}
+
+type RoomSessionQuery struct {
+	Page     int `form:"page"`
+	PageSize int `form:"pageSize"`
+}
+
+type AttendanceQuery struct {
+	Format string `form:"format"` // json 或 csv, 默认 json
+}
@@ -5,6 +5,31 @@ type UserLogin struct {
 	Passwd string `json:"passwd"`
 }
 
+type UserRegister struct {
+	Name   string `json:"name"`
+	Passwd string `json:"passwd"`
+	Alias  string `json:"alias"`
+	Email  string `json:"email"`
+}
+
+type UserProfile struct {
+	Alias *string `json:"alias"`
+	Email *string `json:"email"`
+}
+
+type UserPassword struct {
+	OldPasswd string `json:"oldPasswd"`
+	NewPasswd string `json:"newPasswd"`
+}
+
+type UserDelete struct {
+	Passwd string `json:"passwd"`
+}
+
+type UserRefresh struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
 type UserMessage struct {
 	RoomId  uint64 `json:"roomId"`
 	UserId  uint64 `json:"userId"`
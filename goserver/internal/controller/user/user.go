@@ -3,13 +3,18 @@ package user
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/conf"
 	"io.wandao.meeting/internal/context"
 	"io.wandao.meeting/internal/controller/types"
 	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/libs/cache"
 	"io.wandao.meeting/internal/server/websocket"
 	"io.wandao.meeting/internal/utils/jwtutil"
+	"io.wandao.meeting/internal/utils/userutil"
 )
 
 // Login 登录
@@ -25,17 +30,247 @@ func Login(c *context.APIContext) {
 		return
 	}
 
-	token, err := jwtutil.GenerateToken(user.Id, user.Name)
+	tokens, err := generateTokens(user)
+	if err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+
+	tokens["user"] = user
+	c.ResultSuccess(tokens)
+}
+
+// Register 注册
+func Register(c *context.APIContext) {
+	var in types.UserRegister
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.ResultCode(common.ParameterIllegal, "")
+		return
+	}
+	in.Name = strings.ToLower(strings.TrimSpace(in.Name))
+	in.Email = strings.ToLower(strings.TrimSpace(in.Email))
+	for _, err := range []error{
+		validateName(in.Name),
+		validatePasswd(in.Passwd),
+		validateEmail(in.Email),
+		validateAlias(in.Alias),
+	} {
+		if err != nil {
+			c.ResultCode(common.ParameterIllegal, err.Error())
+			return
+		}
+	}
+
+	user, err := db.Users.Create(c.Request.Context(), &db.User{
+		Name:   in.Name,
+		Passwd: in.Passwd,
+		Alias:  in.Alias,
+		Email:  in.Email,
+	})
+	if err != nil {
+		resultUserError(c, err)
+		return
+	}
 
+	tokens, err := generateTokens(user)
 	if err != nil {
 		c.ResultError(err.Error())
 		return
 	}
 
-	c.ResultSuccess(gin.H{
-		"token": token,
-		"user":  user,
+	tokens["user"] = user
+	c.ResultSuccess(tokens)
+}
+
+// Me 查看当前用户资料
+func Me(c *context.APIContext) {
+	c.ResultSuccess(c.User)
+}
+
+// UpdateProfile 修改当前用户资料
+func UpdateProfile(c *context.APIContext) {
+	var in types.UserProfile
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.ResultCode(common.ParameterIllegal, "")
+		return
+	}
+	if in.Alias != nil {
+		if err := validateAlias(*in.Alias); err != nil {
+			c.ResultCode(common.ParameterIllegal, err.Error())
+			return
+		}
+	}
+	if in.Email != nil {
+		email := strings.ToLower(strings.TrimSpace(*in.Email))
+		if err := validateEmail(email); err != nil {
+			c.ResultCode(common.ParameterIllegal, err.Error())
+			return
+		}
+		in.Email = &email
+	}
+
+	ctx := c.Request.Context()
+	err := db.Users.Update(ctx, c.User.Id, db.UpdateUserOptions{
+		Alias: in.Alias,
+		Email: in.Email,
 	})
+	if err != nil {
+		resultUserError(c, err)
+		return
+	}
+
+	user, err := db.Users.GetByID(ctx, c.User.Id)
+	if err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+	c.ResultSuccess(user)
+}
+
+// ChangePassword 修改密码, 须验证原密码, 成功后返回新的令牌
+func ChangePassword(c *context.APIContext) {
+	var in types.UserPassword
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.ResultCode(common.ParameterIllegal, "")
+		return
+	}
+	if !userutil.ValidatePassword(c.User.Passwd, c.User.Salt, in.OldPasswd) {
+		c.ResultCode(common.PasswordError, "原密码错误")
+		return
+	}
+	if err := validatePasswd(in.NewPasswd); err != nil {
+		c.ResultCode(common.ParameterIllegal, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := db.Users.ChangePassword(ctx, c.User.Id, in.NewPasswd)
+	if err != nil {
+		c.ResultCode(common.ModelStoreError, err.Error())
+		return
+	}
+
+	// 修改密码后之前签发的令牌全部失效, 为当前客户端签发新的令牌
+	user, err := db.Users.GetByID(ctx, c.User.Id)
+	if err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+	tokens, err := generateTokens(user)
+	if err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+	c.ResultSuccess(tokens)
+}
+
+// Delete 注销当前账号, 须验证密码
+func Delete(c *context.APIContext) {
+	var in types.UserDelete
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.ResultCode(common.ParameterIllegal, "")
+		return
+	}
+	if !userutil.ValidatePassword(c.User.Passwd, c.User.Salt, in.Passwd) {
+		c.ResultCode(common.PasswordError, "")
+		return
+	}
+
+	err := db.Users.DeleteByID(c.Request.Context(), c.User.Id)
+	if err != nil {
+		c.ResultCode(common.ModelDeleteError, err.Error())
+		return
+	}
+	_ = cache.RevokeToken(c.Claims.ID, c.Claims.ExpiresAt.Time)
+	c.ResultSuccess(nil)
+}
+
+// resultUserError 将用户存储错误映射为 common 错误码
+func resultUserError(c *context.APIContext, err error) {
+	switch {
+	case db.IsErrUserAlreadyExist(err):
+		c.ResultCode(common.UserExists, "")
+	case db.IsErrEmailAlreadyUsed(err):
+		c.ResultCode(common.EmailExists, "")
+	default:
+		c.ResultCode(common.ModelStoreError, err.Error())
+	}
+}
+
+// Refresh 使用刷新令牌换取新的令牌
+func Refresh(c *context.APIContext) {
+	var in types.UserRefresh
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.ResultError("刷新令牌参数无效")
+		return
+	}
+	claims, err := jwtutil.AnalyseRefreshToken(in.RefreshToken)
+	if err != nil {
+		c.ResultError("刷新令牌无效或已过期")
+		return
+	}
+	user, err := db.Users.GetByID(c.Request.Context(), claims.Id)
+	if err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+	if claims.Version != user.TokenVersion {
+		c.ResultError("刷新令牌已失效")
+		return
+	}
+
+	// 刷新令牌只能使用一次, 检查与吊销须为原子操作
+	consumed, err := cache.ConsumeToken(claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+	if !consumed {
+		c.ResultError("刷新令牌已失效")
+		return
+	}
+
+	tokens, err := generateTokens(user)
+	if err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+	c.ResultSuccess(tokens)
+}
+
+// Logout 退出登录, 吊销当前访问令牌及可选的刷新令牌
+func Logout(c *context.APIContext) {
+	var in types.UserRefresh
+	_ = c.ShouldBindJSON(&in)
+
+	if err := cache.RevokeToken(c.Claims.ID, c.Claims.ExpiresAt.Time); err != nil {
+		c.ResultError(err.Error())
+		return
+	}
+	if in.RefreshToken != "" {
+		claims, err := jwtutil.AnalyseRefreshToken(in.RefreshToken)
+		if err == nil && claims.Id == c.User.Id {
+			_ = cache.RevokeToken(claims.ID, claims.ExpiresAt.Time)
+		}
+	}
+	c.ResultSuccess(nil)
+}
+
+// generateTokens 生成访问令牌和刷新令牌
+func generateTokens(user *db.User) (gin.H, error) {
+	token, err := jwtutil.GenerateToken(user.Id, user.Name, user.TokenVersion)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := jwtutil.GenerateRefreshToken(user.Id, user.Name, user.TokenVersion)
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"token":        token,
+		"refreshToken": refreshToken,
+		"expiresIn":    int64(conf.Security.AccessTokenTTL.Seconds()),
+	}, nil
 }
 
 // List 查看全部在线用户
@@ -0,0 +1,54 @@
+package user
+
+import (
+	"net/mail"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	minPasswdLength = 6
+	maxPasswdLength = 64
+	maxAliasLength  = 255
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{2,31}$`)
+
+// validateName 用户名: 3-32 位小写字母、数字、下划线、点或中划线
+func validateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return errors.New("用户名须为3-32位小写字母、数字、下划线、点或中划线")
+	}
+	return nil
+}
+
+// validatePasswd 密码长度校验
+func validatePasswd(passwd string) error {
+	n := utf8.RuneCountInString(passwd)
+	if n < minPasswdLength || n > maxPasswdLength {
+		return errors.Errorf("密码长度须为%d-%d位", minPasswdLength, maxPasswdLength)
+	}
+	return nil
+}
+
+// validateEmail 邮箱格式校验, 允许为空
+func validateEmail(email string) error {
+	if email == "" {
+		return nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return errors.New("邮箱格式不正确")
+	}
+	return nil
+}
+
+// validateAlias 昵称长度校验
+func validateAlias(alias string) error {
+	if utf8.RuneCountInString(alias) > maxAliasLength {
+		return errors.Errorf("昵称不能超过%d个字符", maxAliasLength)
+	}
+	return nil
+}
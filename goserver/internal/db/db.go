@@ -37,7 +37,17 @@ func newLogWriter() (logger.Writer, error) {
 // Tables 表列表
 // NOTE: 行按字母顺序排序，每个字母都在自己的行中.
 var Tables = []any{
+	new(Meeting),
+	new(MeetingAttendance),
+	new(MeetingInvitee),
+	new(MeetingSession),
+	new(Recording),
 	new(Room),
+	new(RoomBan),
+	new(RoomFile),
+	new(RoomMember),
+	new(RoomMessage),
+	new(RoomWhiteboard),
 	new(User),
 }
 
@@ -89,20 +99,29 @@ func InitDatabase(w logger.Writer) (*gorm.DB, error) {
 	}
 
 	for _, table := range Tables {
-		if db.Migrator().HasTable(table) {
-			continue
-		}
+		// 已存在的表也需要迁移, 以补齐新增的字段
+		hasTable := db.Migrator().HasTable(table)
 
 		name := strings.TrimPrefix(fmt.Sprintf("%T", table), "*db.")
 		err = db.Migrator().AutoMigrate(table)
 		if err != nil {
 			return nil, errors.Wrapf(err, "auto migrate %q", name)
 		}
-		log.Trace("Auto migrated %q", name)
+		if !hasTable {
+			log.Trace("Auto migrated %q", name)
+		}
 	}
 
 	Users = useUsersStore(db)
 	Rooms = useRoomsStore(db)
+	RoomMembers = useRoomMembersStore(db)
+	RoomBans = useRoomBansStore(db)
+	RoomFiles = useRoomFilesStore(db)
+	RoomMessages = useRoomMessagesStore(db)
+	RoomWhiteboards = useRoomWhiteboardsStore(db)
+	Meetings = useMeetingsStore(db)
+	MeetingSessions = useMeetingSessionsStore(db)
+	Recordings = useRecordingsStore(db)
 
 	Conn = db
 
@@ -115,6 +115,23 @@ func Ping() error {
 	return x.Ping()
 }
 
+// Close 关闭数据库连接
+func Close() (err error) {
+	if x != nil {
+		err = x.Close()
+	}
+	if Conn != nil {
+		sqlDB, e := Conn.DB()
+		if e == nil {
+			e = sqlDB.Close()
+		}
+		if err == nil {
+			err = e
+		}
+	}
+	return
+}
+
 func Init() (*gorm.DB, error) {
 	var err error
 	x, err = getEngine()
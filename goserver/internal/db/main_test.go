@@ -49,7 +49,7 @@ func clearTables(t *testing.T, db *gorm.DB, tables ...any) error {
 	}
 
 	for _, t := range tables {
-		err := db.Where("TRUE").Delete(t).Error
+		err := db.Unscoped().Where("TRUE").Delete(t).Error
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,263 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MeetingSession 房间会议记录, 第一名成员进入房间时开始, 最后一名成员离开时结束
+type MeetingSession struct {
+	Id        uint64     `gorm:"primaryKey" json:"id"`
+	RoomId    uint64     `gorm:"index:idx_meeting_session_room;not null" json:"roomId"`
+	StartedAt time.Time  `gorm:"not null" json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt"` // 进行中为 nil
+	// OpenRoomId 进行中时为 RoomId, 结束后为 NULL, 通过唯一索引保证每个房间只有一个进行中的会议
+	OpenRoomId *uint64 `gorm:"uniqueIndex:idx_meeting_session_open" json:"-"`
+}
+
+// MeetingAttendance 成员参会记录, 每次进入和离开房间为一条记录
+type MeetingAttendance struct {
+	Id        uint64     `gorm:"primaryKey" json:"id"`
+	SessionId uint64     `gorm:"index:idx_meeting_attendance_session;not null" json:"sessionId"`
+	UserId    uint64     `gorm:"not null" json:"userId"`
+	UserName  string     `gorm:"type:varchar(255);not null" json:"userName"`                  // 进入时的用户名
+	Node      string     `gorm:"type:varchar(64);index:idx_meeting_attendance_node" json:"-"` // 成员连接的节点
+	JoinedAt  time.Time  `gorm:"not null" json:"joinedAt"`
+	LeftAt    *time.Time `json:"leftAt"` // 仍在房间内为 nil
+}
+
+// AttendanceSummary 成员在一次会议中的参会统计
+type AttendanceSummary struct {
+	UserId        uint64     `json:"userId"`
+	UserName      string     `json:"userName"`
+	Joins         int        `json:"joins"` // 进入次数
+	FirstJoinedAt time.Time  `json:"firstJoinedAt"`
+	LastLeftAt    *time.Time `json:"lastLeftAt"` // 仍在房间内为 nil
+	Duration      int64      `json:"duration"`   // 累计参会时长(秒)
+}
+
+type MeetingSessionsStore interface {
+	// Join 记录成员在节点 node 上进入房间, 房间没有进行中的会议时开始新的会议
+	// 成员已有未结束的参会记录时不重复记录, 只更新所在节点
+	Join(ctx context.Context, roomId uint64, userId uint64, userName string, node string, at time.Time) error
+	// Leave 记录成员离开节点 node, 会议中没有成员时结束会议
+	// 成员已在其它节点上重新进入时不结束其参会记录
+	Leave(ctx context.Context, roomId uint64, userId uint64, node string, at time.Time) error
+	// CloseNode 结束节点 node 上未结束的参会记录, 并结束已没有成员的会议, 用于节点异常退出后的恢复
+	CloseNode(ctx context.Context, node string, at time.Time) error
+	// GetByID 获取房间内的会议
+	GetByID(ctx context.Context, roomId uint64, id uint64) (*MeetingSession, error)
+	// List 按开始时间倒序分页获取房间内的会议
+	List(ctx context.Context, opts ListMeetingSessionsOptions) ([]*MeetingSession, int64, error)
+	// ListAttendances 按进入时间获取会议的参会记录
+	ListAttendances(ctx context.Context, sessionId uint64) ([]*MeetingAttendance, error)
+}
+
+// ListMeetingSessionsOptions 会议记录查询选项
+type ListMeetingSessionsOptions struct {
+	RoomId   uint64
+	Page     int
+	PageSize int
+}
+
+type meetingSessions struct {
+	*gorm.DB
+}
+
+var MeetingSessions MeetingSessionsStore
+var _ MeetingSessionsStore = (*meetingSessions)(nil)
+
+// openSession 锁定房间进行中的会议, 与同时进行的 Join、Leave 串行执行
+func openSession(tx *gorm.DB, roomId uint64) (*MeetingSession, error) {
+	session := new(MeetingSession)
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("room_id = ? AND ended_at IS NULL", roomId).Order("id DESC").First(session).Error
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// endSession 结束会议并释放房间进行中会议的唯一索引
+func endSession(tx *gorm.DB, at time.Time) *gorm.DB {
+	return tx.Updates(map[string]any{"ended_at": at, "open_room_id": nil})
+}
+
+func (db *meetingSessions) Join(ctx context.Context, roomId uint64, userId uint64, userName string, node string, at time.Time) error {
+	err := db.join(ctx, roomId, userId, userName, node, at)
+	if err != nil {
+		// 多个节点同时开始会议时由唯一索引拒绝, 加入已开始的会议
+		if e := db.WithContext(ctx).Where("open_room_id = ?", roomId).First(new(MeetingSession)).Error; e == nil {
+			return db.join(ctx, roomId, userId, userName, node, at)
+		}
+	}
+	return err
+}
+
+func (db *meetingSessions) join(ctx context.Context, roomId uint64, userId uint64, userName string, node string, at time.Time) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		session, err := openSession(tx, roomId)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			session = &MeetingSession{RoomId: roomId, StartedAt: at, OpenRoomId: &roomId}
+			err = tx.Create(session).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		present := "session_id = ? AND user_id = ? AND left_at IS NULL"
+		var count int64
+		err = tx.Model(&MeetingAttendance{}).Where(present, session.Id, userId).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return tx.Model(&MeetingAttendance{}).Where(present, session.Id, userId).Update("node", node).Error
+		}
+		return tx.Create(&MeetingAttendance{
+			SessionId: session.Id,
+			UserId:    userId,
+			UserName:  userName,
+			Node:      node,
+			JoinedAt:  at,
+		}).Error
+	})
+}
+
+func (db *meetingSessions) Leave(ctx context.Context, roomId uint64, userId uint64, node string, at time.Time) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		session, err := openSession(tx, roomId)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		err = tx.Model(&MeetingAttendance{}).
+			Where("session_id = ? AND user_id = ? AND node = ? AND left_at IS NULL", session.Id, userId, node).
+			Update("left_at", at).Error
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		err = tx.Model(&MeetingAttendance{}).
+			Where("session_id = ? AND left_at IS NULL", session.Id).
+			Count(&count).Error
+		if err != nil || count > 0 {
+			return err
+		}
+		return endSession(tx.Model(session), at).Error
+	})
+}
+
+func (db *meetingSessions) CloseNode(ctx context.Context, node string, at time.Time) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&MeetingAttendance{}).Where("node = ? AND left_at IS NULL", node).Update("left_at", at).Error
+		if err != nil {
+			return err
+		}
+		present := tx.Session(&gorm.Session{NewDB: true}).Model(&MeetingAttendance{}).Select("1").
+			Where("meeting_attendance.session_id = meeting_session.id AND meeting_attendance.left_at IS NULL")
+		return endSession(tx.Model(&MeetingSession{}).Where("ended_at IS NULL AND NOT EXISTS (?)", present), at).Error
+	})
+}
+
+func (db *meetingSessions) GetByID(ctx context.Context, roomId uint64, id uint64) (*MeetingSession, error) {
+	session := new(MeetingSession)
+	err := db.WithContext(ctx).Where("room_id = ? AND id = ?", roomId, id).First(session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Wrapf(err, "会议记录不存在(%d)", id)
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+func (db *meetingSessions) List(ctx context.Context, opts ListMeetingSessionsOptions) ([]*MeetingSession, int64, error) {
+	query := db.WithContext(ctx).Model(&MeetingSession{}).Where("room_id = ?", opts.RoomId)
+
+	var count int64
+	err := query.Count(&count).Error
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "count meeting sessions")
+	}
+
+	if opts.Page <= 0 {
+		opts.Page = 1
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = 20
+	}
+
+	sessions := make([]*MeetingSession, 0, opts.PageSize)
+	err = query.Order("id DESC").
+		Limit(opts.PageSize).
+		Offset((opts.Page - 1) * opts.PageSize).
+		Find(&sessions).Error
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "list meeting sessions")
+	}
+	return sessions, count, nil
+}
+
+func (db *meetingSessions) ListAttendances(ctx context.Context, sessionId uint64) ([]*MeetingAttendance, error) {
+	attendances := make([]*MeetingAttendance, 0)
+	err := db.WithContext(ctx).Where("session_id = ?", sessionId).Order("joined_at, id").Find(&attendances).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "list meeting attendances")
+	}
+	return attendances, nil
+}
+
+// SummarizeAttendance 按成员汇总参会记录, 未离开的记录按 now 计算时长
+// 结果按首次进入时间排序
+func SummarizeAttendance(attendances []*MeetingAttendance, now time.Time) []*AttendanceSummary {
+	byUser := make(map[uint64]*AttendanceSummary)
+	present := make(map[uint64]bool)
+	summaries := make([]*AttendanceSummary, 0)
+	for _, a := range attendances {
+		summary, ok := byUser[a.UserId]
+		if !ok {
+			summary = &AttendanceSummary{UserId: a.UserId, UserName: a.UserName, FirstJoinedAt: a.JoinedAt}
+			byUser[a.UserId] = summary
+			summaries = append(summaries, summary)
+		}
+		summary.Joins++
+		if a.JoinedAt.Before(summary.FirstJoinedAt) {
+			summary.FirstJoinedAt = a.JoinedAt
+		}
+
+		end := now
+		if a.LeftAt != nil {
+			end = *a.LeftAt
+		}
+		if end.After(a.JoinedAt) {
+			summary.Duration += int64(end.Sub(a.JoinedAt) / time.Second)
+		}
+		if a.LeftAt == nil {
+			present[a.UserId] = true
+		} else if summary.LastLeftAt == nil || a.LeftAt.After(*summary.LastLeftAt) {
+			summary.LastLeftAt = a.LeftAt
+		}
+	}
+	// 仍在房间内的成员没有离开时间
+	for userId := range present {
+		byUser[userId].LastLeftAt = nil
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].FirstJoinedAt.Before(summaries[j].FirstJoinedAt)
+	})
+	return summaries
+}
+
+func useMeetingSessionsStore(db *gorm.DB) MeetingSessionsStore {
+	return &meetingSessions{DB: db}
+}
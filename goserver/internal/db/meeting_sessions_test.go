@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/db/dbtest"
+)
+
+func TestMeetingSessions(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+
+	ctx := context.Background()
+	tables := []any{
+		new(MeetingSession),
+		new(MeetingAttendance),
+	}
+	db := &meetingSessions{
+		DB: dbtest.NewDB(t, "meetingSessions", tables...),
+	}
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, ctx context.Context, db *meetingSessions)
+	}{
+		{"JoinAndLeave", meetingSessionsJoinAndLeave},
+		{"OpenSession", meetingSessionsOpenSession},
+		{"CloseNode", meetingSessionsCloseNode},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := clearTables(t, db.DB, tables...)
+				require.NoError(t, err)
+			})
+			tc.test(t, ctx, db)
+		})
+		if t.Failed() {
+			break
+		}
+	}
+}
+
+func meetingSessionsJoinAndLeave(t *testing.T, ctx context.Context, db *meetingSessions) {
+	start := time.Now().Truncate(time.Second)
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	require.NoError(t, db.Join(ctx, 1, 2, "alice", "node1", at(0)))
+	require.NoError(t, db.Join(ctx, 1, 3, "bob", "node1", at(1)))
+	// 重复进入不重复记录
+	require.NoError(t, db.Join(ctx, 1, 2, "alice", "node1", at(2)))
+	require.NoError(t, db.Leave(ctx, 1, 3, "node1", at(5)))
+	require.NoError(t, db.Join(ctx, 1, 3, "bob", "node1", at(6)))
+
+	sessions, total, err := db.List(ctx, ListMeetingSessionsOptions{RoomId: 1})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	session := sessions[0]
+	assert.Nil(t, session.EndedAt)
+
+	// 最后一名成员离开后结束会议
+	require.NoError(t, db.Leave(ctx, 1, 2, "node1", at(10)))
+	require.NoError(t, db.Leave(ctx, 1, 3, "node1", at(12)))
+	session, err = db.GetByID(ctx, 1, session.Id)
+	require.NoError(t, err)
+	require.NotNil(t, session.EndedAt)
+	assert.True(t, at(12).Equal(*session.EndedAt))
+
+	attendances, err := db.ListAttendances(ctx, session.Id)
+	require.NoError(t, err)
+	assert.Len(t, attendances, 3)
+
+	// 之后进入房间开始新的会议
+	require.NoError(t, db.Join(ctx, 1, 2, "alice", "node1", at(20)))
+	_, total, err = db.List(ctx, ListMeetingSessionsOptions{RoomId: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+
+	_, err = db.GetByID(ctx, 2, session.Id)
+	assert.Error(t, err)
+}
+
+func meetingSessionsCloseNode(t *testing.T, ctx context.Context, db *meetingSessions) {
+	now := time.Now().Truncate(time.Second)
+	require.NoError(t, db.Join(ctx, 1, 2, "alice", "node1", now))
+	require.NoError(t, db.Join(ctx, 1, 3, "bob", "node1", now))
+	// 重新连接到其它节点
+	require.NoError(t, db.Join(ctx, 1, 3, "bob", "node2", now.Add(time.Second)))
+	require.NoError(t, db.CloseNode(ctx, "node1", now.Add(time.Minute)))
+
+	sessions, _, err := db.List(ctx, ListMeetingSessionsOptions{RoomId: 1})
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Nil(t, sessions[0].EndedAt, "其它节点上仍有成员")
+
+	attendances, err := db.ListAttendances(ctx, sessions[0].Id)
+	require.NoError(t, err)
+	require.Len(t, attendances, 2)
+	assert.NotNil(t, attendances[0].LeftAt)
+	assert.Nil(t, attendances[1].LeftAt)
+
+	require.NoError(t, db.CloseNode(ctx, "node2", now.Add(2*time.Minute)))
+	session, err := db.GetByID(ctx, 1, sessions[0].Id)
+	require.NoError(t, err)
+	assert.NotNil(t, session.EndedAt)
+}
+
+func meetingSessionsOpenSession(t *testing.T, ctx context.Context, db *meetingSessions) {
+	now := time.Now().Truncate(time.Second)
+	require.NoError(t, db.Join(ctx, 1, 2, "alice", "node1", now))
+
+	// 每个房间只能有一个进行中的会议
+	roomId := uint64(1)
+	err := db.DB.Create(&MeetingSession{RoomId: 1, StartedAt: now, OpenRoomId: &roomId}).Error
+	assert.Error(t, err)
+
+	// 成员重新连接到其它节点后, 原节点上的离开不结束其参会记录
+	require.NoError(t, db.Join(ctx, 1, 2, "alice", "node2", now.Add(time.Second)))
+	require.NoError(t, db.Leave(ctx, 1, 2, "node1", now.Add(2*time.Second)))
+	sessions, _, err := db.List(ctx, ListMeetingSessionsOptions{RoomId: 1})
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Nil(t, sessions[0].EndedAt)
+
+	require.NoError(t, db.Leave(ctx, 1, 2, "node2", now.Add(time.Minute)))
+	session, err := db.GetByID(ctx, 1, sessions[0].Id)
+	require.NoError(t, err)
+	assert.NotNil(t, session.EndedAt)
+	assert.Nil(t, session.OpenRoomId)
+
+	// 结束后可以开始新的会议
+	require.NoError(t, db.Join(ctx, 1, 2, "alice", "node1", now.Add(2*time.Minute)))
+	_, total, err := db.List(ctx, ListMeetingSessionsOptions{RoomId: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+}
+
+func TestSummarizeAttendance(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	at := func(minutes int) *time.Time {
+		v := start.Add(time.Duration(minutes) * time.Minute)
+		return &v
+	}
+	attendances := []*MeetingAttendance{
+		{UserId: 2, UserName: "alice", JoinedAt: *at(0), LeftAt: at(30)},
+		{UserId: 3, UserName: "bob", JoinedAt: *at(5), LeftAt: at(10)},
+		{UserId: 3, UserName: "bob", JoinedAt: *at(20)},
+		{UserId: 2, UserName: "alice", JoinedAt: *at(40), LeftAt: at(45)},
+	}
+
+	summaries := SummarizeAttendance(attendances, *at(60))
+	require.Len(t, summaries, 2)
+
+	alice := summaries[0]
+	assert.Equal(t, uint64(2), alice.UserId)
+	assert.Equal(t, 2, alice.Joins)
+	assert.Equal(t, int64(35*60), alice.Duration)
+	assert.Equal(t, at(45), alice.LastLeftAt)
+
+	// 仍在房间内的时长计算到 now
+	bob := summaries[1]
+	assert.Equal(t, *at(5), bob.FirstJoinedAt)
+	assert.Equal(t, int64(45*60), bob.Duration)
+	assert.Nil(t, bob.LastLeftAt)
+}
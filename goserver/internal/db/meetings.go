@@ -0,0 +1,221 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"io.wandao.meeting/internal/utils/icalutil"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Meeting 预约会议表结构体, 重复会议只保存首次的时间和重复规则
+type Meeting struct {
+	Id          uint64    `gorm:"primaryKey" json:"id"`
+	RoomId      uint64    `gorm:"index:idx_meeting_room;not null" json:"roomId"`
+	OrganizerId uint64    `gorm:"index:idx_meeting_organizer;not null" json:"organizerId"`
+	Title       string    `gorm:"type:varchar(255);not null" json:"title"`
+	Description string    `gorm:"type:text" json:"description"`
+	StartAt     time.Time `gorm:"not null" json:"startAt"`                // 首次开始时间
+	EndAt       time.Time `gorm:"not null" json:"endAt"`                  // 首次结束时间
+	Recurrence  string    `gorm:"type:varchar(255)" json:"recurrence"`    // RRULE, 为空表示不重复
+	Canceled    bool      `gorm:"not null;default:false" json:"canceled"` // 取消后保留, 供日历订阅同步取消状态
+	Sequence    int       `gorm:"not null;default:0" json:"-"`            // 每次修改递增, 对应 iCalendar 的 SEQUENCE
+	Invitees    []uint64  `gorm:"-" json:"invitees"`                      // 受邀用户
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// MeetingInvitee 会议受邀用户表结构体
+type MeetingInvitee struct {
+	Id        uint64 `gorm:"primaryKey"`
+	MeetingId uint64 `gorm:"uniqueIndex:idx_meeting_invitee;not null"`
+	UserId    uint64 `gorm:"uniqueIndex:idx_meeting_invitee;index:idx_meeting_invitee_user;not null"`
+}
+
+// UpdateMeetingOptions 会议更新选项, 为 nil 的字段不更新
+type UpdateMeetingOptions struct {
+	Title       *string
+	Description *string
+	StartAt     *time.Time
+	EndAt       *time.Time
+	Recurrence  *string
+	Invitees    *[]uint64
+}
+
+type MeetingsStore interface {
+	// Create 创建会议并保存受邀用户
+	Create(ctx context.Context, meeting *Meeting) error
+	// GetByID 获取会议及受邀用户
+	GetByID(ctx context.Context, id uint64) (*Meeting, error)
+	// Update 更新会议, 受邀用户整体替换
+	Update(ctx context.Context, id uint64, opts UpdateMeetingOptions) error
+	// Cancel 取消会议
+	Cancel(ctx context.Context, id uint64) error
+	// ListByRoom 按开始时间获取房间内未取消的会议
+	ListByRoom(ctx context.Context, roomId uint64) ([]*Meeting, error)
+	// ListByUser 按开始时间获取用户组织或受邀的全部会议, 包括已取消的会议
+	ListByUser(ctx context.Context, userId uint64) ([]*Meeting, error)
+}
+
+type meetings struct {
+	*gorm.DB
+}
+
+var Meetings MeetingsStore
+var _ MeetingsStore = (*meetings)(nil)
+
+func (db *meetings) Create(ctx context.Context, meeting *Meeting) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(meeting).Error; err != nil {
+			return err
+		}
+		return saveInvitees(tx, meeting.Id, meeting.Invitees)
+	})
+}
+
+// saveInvitees 替换会议的受邀用户
+func saveInvitees(tx *gorm.DB, meetingId uint64, userIds []uint64) error {
+	err := tx.Where("meeting_id = ?", meetingId).Delete(&MeetingInvitee{}).Error
+	if err != nil {
+		return err
+	}
+	if len(userIds) == 0 {
+		return nil
+	}
+	invitees := make([]*MeetingInvitee, 0, len(userIds))
+	seen := make(map[uint64]bool, len(userIds))
+	for _, userId := range userIds {
+		if seen[userId] {
+			continue
+		}
+		seen[userId] = true
+		invitees = append(invitees, &MeetingInvitee{MeetingId: meetingId, UserId: userId})
+	}
+	return tx.Create(&invitees).Error
+}
+
+func (db *meetings) GetByID(ctx context.Context, id uint64) (*Meeting, error) {
+	meeting := new(Meeting)
+	err := db.WithContext(ctx).Where("id = ?", id).First(meeting).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Wrapf(err, "会议不存在(%d)", id)
+		}
+		return nil, err
+	}
+	if err = db.loadInvitees(ctx, meeting); err != nil {
+		return nil, err
+	}
+	return meeting, nil
+}
+
+func (db *meetings) Update(ctx context.Context, id uint64, opts UpdateMeetingOptions) error {
+	updates := map[string]any{
+		"sequence": gorm.Expr("sequence + 1"),
+	}
+	if opts.Title != nil {
+		updates["title"] = *opts.Title
+	}
+	if opts.Description != nil {
+		updates["description"] = *opts.Description
+	}
+	if opts.StartAt != nil {
+		updates["start_at"] = *opts.StartAt
+	}
+	if opts.EndAt != nil {
+		updates["end_at"] = *opts.EndAt
+	}
+	if opts.Recurrence != nil {
+		updates["recurrence"] = *opts.Recurrence
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Meeting{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+		if opts.Invitees == nil {
+			return nil
+		}
+		return saveInvitees(tx, id, *opts.Invitees)
+	})
+}
+
+func (db *meetings) Cancel(ctx context.Context, id uint64) error {
+	return db.WithContext(ctx).Model(&Meeting{}).Where("id = ?", id).Updates(map[string]any{
+		"canceled": true,
+		"sequence": gorm.Expr("sequence + 1"),
+	}).Error
+}
+
+func (db *meetings) ListByRoom(ctx context.Context, roomId uint64) ([]*Meeting, error) {
+	list := make([]*Meeting, 0)
+	err := db.WithContext(ctx).Where("room_id = ? AND canceled = ?", roomId, false).Order("start_at, id").Find(&list).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "list room meetings")
+	}
+	return list, db.loadInvitees(ctx, list...)
+}
+
+func (db *meetings) ListByUser(ctx context.Context, userId uint64) ([]*Meeting, error) {
+	list := make([]*Meeting, 0)
+	invited := db.WithContext(ctx).Model(&MeetingInvitee{}).Select("meeting_id").Where("user_id = ?", userId)
+	err := db.WithContext(ctx).
+		Where("organizer_id = ? OR id IN (?)", userId, invited).
+		Order("start_at, id").
+		Find(&list).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "list user meetings")
+	}
+	return list, db.loadInvitees(ctx, list...)
+}
+
+// loadInvitees 批量加载会议的受邀用户
+func (db *meetings) loadInvitees(ctx context.Context, list ...*Meeting) error {
+	if len(list) == 0 {
+		return nil
+	}
+	byId := make(map[uint64]*Meeting, len(list))
+	ids := make([]uint64, 0, len(list))
+	for _, meeting := range list {
+		meeting.Invitees = make([]uint64, 0)
+		byId[meeting.Id] = meeting
+		ids = append(ids, meeting.Id)
+	}
+
+	invitees := make([]*MeetingInvitee, 0)
+	err := db.WithContext(ctx).Where("meeting_id IN ?", ids).Order("id").Find(&invitees).Error
+	if err != nil {
+		return errors.Wrap(err, "list meeting invitees")
+	}
+	for _, invitee := range invitees {
+		meeting := byId[invitee.MeetingId]
+		meeting.Invitees = append(meeting.Invitees, invitee.UserId)
+	}
+	return nil
+}
+
+// IsInvited 用户是否为会议的组织者或受邀用户
+func (m *Meeting) IsInvited(userId uint64) bool {
+	if m.OrganizerId == userId {
+		return true
+	}
+	for _, invitee := range m.Invitees {
+		if invitee == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// NextWindow 正在进行或下一次会议的开始和结束时间, 全部结束后 ok 为 false
+func (m *Meeting) NextWindow(t time.Time) (from, to time.Time, ok bool) {
+	// 重复规则在保存前已校验, 解析失败时按不重复处理
+	recurrence, _ := icalutil.ParseRecurrence(m.Recurrence)
+	return recurrence.Next(m.StartAt, m.EndAt, t)
+}
+
+func useMeetingsStore(db *gorm.DB) MeetingsStore {
+	return &meetings{DB: db}
+}
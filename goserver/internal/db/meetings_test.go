@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/db/dbtest"
+)
+
+func TestMeetings(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+
+	ctx := context.Background()
+	tables := []any{
+		new(Meeting),
+		new(MeetingInvitee),
+	}
+	db := &meetings{
+		DB: dbtest.NewDB(t, "meetings", tables...),
+	}
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, ctx context.Context, db *meetings)
+	}{
+		{"Create", meetingsCreate},
+		{"Update", meetingsUpdate},
+		{"List", meetingsList},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := clearTables(t, db.DB, tables...)
+				require.NoError(t, err)
+			})
+			tc.test(t, ctx, db)
+		})
+		if t.Failed() {
+			break
+		}
+	}
+}
+
+func newTestMeeting(roomId, organizerId uint64, start time.Time, invitees ...uint64) *Meeting {
+	return &Meeting{
+		RoomId:      roomId,
+		OrganizerId: organizerId,
+		Title:       "周会",
+		StartAt:     start,
+		EndAt:       start.Add(time.Hour),
+		Invitees:    invitees,
+	}
+}
+
+func meetingsCreate(t *testing.T, ctx context.Context, db *meetings) {
+	start := time.Now().Add(time.Hour).Truncate(time.Second)
+	meeting := newTestMeeting(1, 2, start, 3, 4, 3)
+	require.NoError(t, db.Create(ctx, meeting))
+
+	got, err := db.GetByID(ctx, meeting.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "周会", got.Title)
+	assert.Equal(t, []uint64{3, 4}, got.Invitees)
+	assert.True(t, got.IsInvited(2))
+	assert.True(t, got.IsInvited(4))
+	assert.False(t, got.IsInvited(5))
+
+	_, err = db.GetByID(ctx, meeting.Id+1)
+	assert.Error(t, err)
+}
+
+func meetingsUpdate(t *testing.T, ctx context.Context, db *meetings) {
+	start := time.Now().Add(time.Hour).Truncate(time.Second)
+	meeting := newTestMeeting(1, 2, start, 3)
+	require.NoError(t, db.Create(ctx, meeting))
+
+	title := "月会"
+	recurrence := "FREQ=MONTHLY"
+	invitees := []uint64{5}
+	err := db.Update(ctx, meeting.Id, UpdateMeetingOptions{
+		Title:      &title,
+		Recurrence: &recurrence,
+		Invitees:   &invitees,
+	})
+	require.NoError(t, err)
+
+	got, err := db.GetByID(ctx, meeting.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "月会", got.Title)
+	assert.Equal(t, "FREQ=MONTHLY", got.Recurrence)
+	assert.Equal(t, []uint64{5}, got.Invitees)
+	assert.Equal(t, 1, got.Sequence)
+
+	// 只修改时间时保留受邀用户
+	end := start.Add(2 * time.Hour)
+	require.NoError(t, db.Update(ctx, meeting.Id, UpdateMeetingOptions{EndAt: &end}))
+	require.NoError(t, db.Cancel(ctx, meeting.Id))
+	got, err = db.GetByID(ctx, meeting.Id)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{5}, got.Invitees)
+	assert.True(t, got.Canceled)
+	assert.Equal(t, 3, got.Sequence)
+}
+
+func meetingsList(t *testing.T, ctx context.Context, db *meetings) {
+	start := time.Now().Add(time.Hour).Truncate(time.Second)
+	later := newTestMeeting(1, 2, start.Add(time.Hour), 3)
+	earlier := newTestMeeting(1, 3, start)
+	canceled := newTestMeeting(1, 2, start)
+	other := newTestMeeting(2, 4, start, 5)
+	for _, meeting := range []*Meeting{later, earlier, canceled, other} {
+		require.NoError(t, db.Create(ctx, meeting))
+	}
+	require.NoError(t, db.Cancel(ctx, canceled.Id))
+
+	list, err := db.ListByRoom(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, earlier.Id, list[0].Id)
+	assert.Equal(t, []uint64{3}, list[1].Invitees)
+
+	// 组织和受邀的会议, 包括已取消的会议
+	list, err = db.ListByUser(ctx, 3)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, earlier.Id, list[0].Id)
+	assert.Equal(t, later.Id, list[1].Id)
+
+	list, err = db.ListByUser(ctx, 2)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	list, err = db.ListByUser(ctx, 9)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
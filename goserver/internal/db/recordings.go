@@ -0,0 +1,231 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"io.wandao.meeting/internal/utils/errutil"
+)
+
+// 录制状态
+const (
+	RecordingActive  = "recording" // 录制中
+	RecordingStopped = "stopped"   // 已停止
+)
+
+// Recording 会议录制表结构体, 录制文件由开始录制的主持人分片上传
+type Recording struct {
+	Id     uint64 `gorm:"primaryKey" json:"id"`
+	RoomId uint64 `gorm:"index:idx_recording_room;not null" json:"roomId"`
+	UserId uint64 `gorm:"index:idx_recording_user;not null" json:"userId"` // 开始录制的主持人, 即录制文件的所有者
+	Status string `gorm:"type:varchar(16);not null" json:"status"`
+	// ActiveRoomId 录制中时为 RoomId, 停止后为 NULL, 通过唯一索引保证每个房间只有一个录制中的记录
+	ActiveRoomId *uint64    `gorm:"uniqueIndex:idx_recording_active" json:"-"`
+	MimeType     string     `gorm:"type:varchar(255)" json:"mimeType"`   // 首个分片上传时确定
+	Chunks       int        `gorm:"not null;default:0" json:"chunks"`    // 已上传的分片数
+	Size         int64      `gorm:"not null;default:0" json:"size"`      // 已上传的大小(字节)
+	Path         string     `gorm:"type:varchar(255);not null" json:"-"` // 相对录制目录的存储路径
+	StartedAt    time.Time  `gorm:"not null" json:"startedAt"`
+	EndedAt      *time.Time `json:"endedAt"`  // 录制中为 nil
+	Duration     int64      `json:"duration"` // 录制时长(秒)
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ListRecordingsOptions 录制查询选项, 为 0 的条件不限制
+type ListRecordingsOptions struct {
+	RoomId   uint64
+	UserId   uint64
+	Page     int
+	PageSize int
+}
+
+type RecordingsStore interface {
+	// Create 开始录制, 房间已在录制时返回 ErrRecordingActive
+	Create(ctx context.Context, recording *Recording) error
+	// GetByID 获取录制
+	GetByID(ctx context.Context, id uint64) (*Recording, error)
+	// GetActive 获取房间内正在进行的录制, 没有时返回 nil
+	GetActive(ctx context.Context, roomId uint64) (*Recording, error)
+	// Stop 停止录制并记录时长, 录制已停止时返回 gorm.ErrRecordNotFound
+	Stop(ctx context.Context, id uint64, at time.Time) (*Recording, error)
+	// AddChunk 记录上传的第 seq 个分片, seq 须等于已上传的分片数
+	// 首个分片同时记录文件类型, 并发上传同一分片时只有一个成功
+	AddChunk(ctx context.Context, id uint64, seq int, size int64, mimeType string) error
+	// List 按开始时间倒序分页获取录制
+	List(ctx context.Context, opts ListRecordingsOptions) ([]*Recording, int64, error)
+	// ListActive 获取全部录制中的录制
+	ListActive(ctx context.Context) ([]*Recording, error)
+	// ListEndedBefore 获取在 before 之前停止的录制, 不包括录制中的录制
+	ListEndedBefore(ctx context.Context, before time.Time) ([]*Recording, error)
+	// DeleteByID 删除录制
+	DeleteByID(ctx context.Context, id uint64) error
+}
+
+// ErrRecordingActive 房间正在录制
+type ErrRecordingActive struct {
+	args errutil.Args
+}
+
+// IsErrRecordingActive 是否为房间正在录制错误
+func IsErrRecordingActive(err error) bool {
+	return errors.As(err, &ErrRecordingActive{})
+}
+
+func (err ErrRecordingActive) Error() string {
+	return fmt.Sprintf("房间正在录制: %v", err.args)
+}
+
+type recordings struct {
+	*gorm.DB
+}
+
+var Recordings RecordingsStore
+var _ RecordingsStore = (*recordings)(nil)
+
+func (db *recordings) Create(ctx context.Context, recording *Recording) error {
+	recording.Status = RecordingActive
+	recording.ActiveRoomId = &recording.RoomId
+	err := db.WithContext(ctx).Create(recording).Error
+	if err != nil {
+		// 并发开始录制时由唯一索引拒绝
+		if active, e := db.GetActive(ctx, recording.RoomId); e == nil && active != nil {
+			return ErrRecordingActive{args: errutil.Args{"roomId": recording.RoomId}}
+		}
+		return err
+	}
+	return nil
+}
+
+func (db *recordings) GetByID(ctx context.Context, id uint64) (*Recording, error) {
+	recording := new(Recording)
+	err := db.WithContext(ctx).Where("id = ?", id).First(recording).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Wrapf(err, "录制不存在(%d)", id)
+		}
+		return nil, err
+	}
+	return recording, nil
+}
+
+func (db *recordings) GetActive(ctx context.Context, roomId uint64) (*Recording, error) {
+	recording := new(Recording)
+	err := db.WithContext(ctx).Where("room_id = ? AND status = ?", roomId, RecordingActive).Order("id DESC").First(recording).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return recording, nil
+}
+
+func (db *recordings) Stop(ctx context.Context, id uint64, at time.Time) (*Recording, error) {
+	recording := new(Recording)
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("id = ? AND status = ?", id, RecordingActive).First(recording).Error
+		if err != nil {
+			return err
+		}
+		recording.Status = RecordingStopped
+		recording.EndedAt = &at
+		recording.Duration = int64(at.Sub(recording.StartedAt) / time.Second)
+		recording.ActiveRoomId = nil
+		result := tx.Model(&Recording{}).Where("id = ? AND status = ?", id, RecordingActive).Updates(map[string]any{
+			"status":         recording.Status,
+			"active_room_id": gorm.Expr("NULL"),
+			"ended_at":       at,
+			"duration":       recording.Duration,
+		})
+		if result.Error == nil && result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return result.Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recording, nil
+}
+
+func (db *recordings) AddChunk(ctx context.Context, id uint64, seq int, size int64, mimeType string) error {
+	updates := map[string]any{
+		"chunks": gorm.Expr("chunks + 1"),
+		"size":   gorm.Expr("size + ?", size),
+	}
+	if seq == 0 {
+		updates["mime_type"] = mimeType
+	}
+	result := db.WithContext(ctx).Model(&Recording{}).Where("id = ? AND chunks = ?", id, seq).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.Errorf("分片序号不连续(%d)", seq)
+	}
+	return nil
+}
+
+func (db *recordings) List(ctx context.Context, opts ListRecordingsOptions) ([]*Recording, int64, error) {
+	query := db.WithContext(ctx).Model(&Recording{})
+	if opts.RoomId > 0 {
+		query = query.Where("room_id = ?", opts.RoomId)
+	}
+	if opts.UserId > 0 {
+		query = query.Where("user_id = ?", opts.UserId)
+	}
+
+	var count int64
+	err := query.Count(&count).Error
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "count recordings")
+	}
+
+	if opts.Page <= 0 {
+		opts.Page = 1
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = 20
+	}
+
+	list := make([]*Recording, 0, opts.PageSize)
+	err = query.Order("id DESC").
+		Limit(opts.PageSize).
+		Offset((opts.Page - 1) * opts.PageSize).
+		Find(&list).Error
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "list recordings")
+	}
+	return list, count, nil
+}
+
+func (db *recordings) ListActive(ctx context.Context) ([]*Recording, error) {
+	list := make([]*Recording, 0)
+	err := db.WithContext(ctx).Where("status = ?", RecordingActive).Order("id").Find(&list).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "list active recordings")
+	}
+	return list, nil
+}
+
+func (db *recordings) ListEndedBefore(ctx context.Context, before time.Time) ([]*Recording, error) {
+	list := make([]*Recording, 0)
+	err := db.WithContext(ctx).Where("status <> ? AND ended_at < ?", RecordingActive, before).Order("id").Find(&list).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "list expired recordings")
+	}
+	return list, nil
+}
+
+func (db *recordings) DeleteByID(ctx context.Context, id uint64) error {
+	return db.WithContext(ctx).Where("id = ?", id).Delete(&Recording{}).Error
+}
+
+func useRecordingsStore(db *gorm.DB) RecordingsStore {
+	return &recordings{DB: db}
+}
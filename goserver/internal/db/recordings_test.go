@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"io.wandao.meeting/internal/db/dbtest"
+)
+
+func TestRecordings(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+
+	ctx := context.Background()
+	tables := []any{
+		new(Recording),
+	}
+	db := &recordings{
+		DB: dbtest.NewDB(t, "recordings", tables...),
+	}
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, ctx context.Context, db *recordings)
+	}{
+		{"StartAndStop", recordingsStartAndStop},
+		{"AddChunk", recordingsAddChunk},
+		{"List", recordingsList},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := clearTables(t, db.DB, tables...)
+				require.NoError(t, err)
+			})
+			tc.test(t, ctx, db)
+		})
+		if t.Failed() {
+			break
+		}
+	}
+}
+
+func recordingsStartAndStop(t *testing.T, ctx context.Context, db *recordings) {
+	start := time.Now().Truncate(time.Second)
+	recording := &Recording{RoomId: 1, UserId: 2, Path: "1/a", StartedAt: start}
+	require.NoError(t, db.Create(ctx, recording))
+
+	active, err := db.GetActive(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, recording.Id, active.Id)
+	assert.Equal(t, RecordingActive, active.Status)
+
+	stopped, err := db.Stop(ctx, recording.Id, start.Add(90*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, RecordingStopped, stopped.Status)
+	assert.Equal(t, int64(90), stopped.Duration)
+
+	active, err = db.GetActive(ctx, 1)
+	require.NoError(t, err)
+	assert.Nil(t, active)
+	assert.Nil(t, stopped.ActiveRoomId)
+	_, err = db.Stop(ctx, recording.Id, start.Add(time.Hour))
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	got, err := db.GetByID(ctx, recording.Id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(90), got.Duration)
+
+	// 停止后可以再次开始, 房间内同时只有一个录制中的录制
+	next := &Recording{RoomId: 1, UserId: 2, Path: "1/b", StartedAt: start}
+	require.NoError(t, db.Create(ctx, next))
+	err = db.Create(ctx, &Recording{RoomId: 1, UserId: 3, Path: "1/c", StartedAt: start})
+	assert.True(t, IsErrRecordingActive(err))
+
+	list, err := db.ListActive(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, next.Id, list[0].Id)
+}
+
+func recordingsAddChunk(t *testing.T, ctx context.Context, db *recordings) {
+	recording := &Recording{RoomId: 1, UserId: 2, Path: "1/a", StartedAt: time.Now()}
+	require.NoError(t, db.Create(ctx, recording))
+
+	require.NoError(t, db.AddChunk(ctx, recording.Id, 0, 100, "video/webm"))
+	require.NoError(t, db.AddChunk(ctx, recording.Id, 1, 50, "audio/webm"))
+	// 重复或跳过的分片
+	assert.Error(t, db.AddChunk(ctx, recording.Id, 1, 50, ""))
+	assert.Error(t, db.AddChunk(ctx, recording.Id, 3, 50, ""))
+
+	got, err := db.GetByID(ctx, recording.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.Chunks)
+	assert.Equal(t, int64(150), got.Size)
+	assert.Equal(t, "video/webm", got.MimeType)
+}
+
+func recordingsList(t *testing.T, ctx context.Context, db *recordings) {
+	now := time.Now().Truncate(time.Second)
+	old := &Recording{RoomId: 1, UserId: 2, Path: "1/a", StartedAt: now.Add(-48 * time.Hour)}
+	require.NoError(t, db.Create(ctx, old))
+	_, err := db.Stop(ctx, old.Id, now.Add(-47*time.Hour))
+	require.NoError(t, err)
+	// 很早开始但仍在录制中
+	mine := &Recording{RoomId: 1, UserId: 3, Path: "1/b", StartedAt: now.Add(-48 * time.Hour)}
+	other := &Recording{RoomId: 2, UserId: 3, Path: "2/c", StartedAt: now}
+	for _, recording := range []*Recording{mine, other} {
+		require.NoError(t, db.Create(ctx, recording))
+	}
+
+	list, total, err := db.List(ctx, ListRecordingsOptions{RoomId: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, mine.Id, list[0].Id)
+
+	list, total, err = db.List(ctx, ListRecordingsOptions{UserId: 3})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+
+	expired, err := db.ListEndedBefore(ctx, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, old.Id, expired[0].Id)
+
+	require.NoError(t, db.DeleteByID(ctx, old.Id))
+	_, err = db.GetByID(ctx, old.Id)
+	assert.Error(t, err)
+}
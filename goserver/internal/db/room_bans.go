@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RoomBan 房间禁入名单表结构体
+type RoomBan struct {
+	Id         uint64 `gorm:"primaryKey" json:"id"`
+	RoomId     uint64 `gorm:"uniqueIndex:idx_room_ban_room_user;not null" json:"roomId"`
+	UserId     uint64 `gorm:"uniqueIndex:idx_room_ban_room_user;not null" json:"userId"`
+	OperatorId uint64 `gorm:"not null" json:"operatorId"`      // 操作人
+	Reason     string `gorm:"type:varchar(255)" json:"reason"` // 禁入原因
+
+	CreatedAt time.Time
+}
+
+type RoomBansStore interface {
+	// Create 将用户加入房间禁入名单, 已存在时忽略
+	Create(ctx context.Context, ban *RoomBan) error
+	Delete(ctx context.Context, roomId uint64, userId uint64) error
+	IsBanned(ctx context.Context, roomId uint64, userId uint64) (bool, error)
+	List(ctx context.Context, roomId uint64) ([]*RoomBan, error)
+}
+
+type roomBans struct {
+	*gorm.DB
+}
+
+var RoomBans RoomBansStore
+var _ RoomBansStore = (*roomBans)(nil)
+
+func (db *roomBans) Create(ctx context.Context, ban *RoomBan) error {
+	return db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(ban).Error
+}
+
+func (db *roomBans) Delete(ctx context.Context, roomId uint64, userId uint64) error {
+	return db.WithContext(ctx).Where("room_id = ? AND user_id = ?", roomId, userId).Delete(&RoomBan{}).Error
+}
+
+func (db *roomBans) IsBanned(ctx context.Context, roomId uint64, userId uint64) (bool, error) {
+	var count int64
+	err := db.WithContext(ctx).Model(&RoomBan{}).Where("room_id = ? AND user_id = ?", roomId, userId).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (db *roomBans) List(ctx context.Context, roomId uint64) ([]*RoomBan, error) {
+	bans := make([]*RoomBan, 0)
+	err := db.WithContext(ctx).Where("room_id = ?", roomId).Order("id").Find(&bans).Error
+	if err != nil {
+		return nil, err
+	}
+	return bans, nil
+}
+
+func useRoomBansStore(db *gorm.DB) RoomBansStore {
+	return &roomBans{DB: db}
+}
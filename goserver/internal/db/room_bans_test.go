@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/db/dbtest"
+)
+
+func TestRoomBans(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+
+	ctx := context.Background()
+	tables := []any{
+		new(RoomBan),
+	}
+	db := &roomBans{
+		DB: dbtest.NewDB(t, "roomBans", tables...),
+	}
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, ctx context.Context, db *roomBans)
+	}{
+		{"Ban", roomBansBan},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := clearTables(t, db.DB, tables...)
+				require.NoError(t, err)
+			})
+			tc.test(t, ctx, db)
+		})
+		if t.Failed() {
+			break
+		}
+	}
+}
+
+func roomBansBan(t *testing.T, ctx context.Context, db *roomBans) {
+	banned, err := db.IsBanned(ctx, 1, 2)
+	require.NoError(t, err)
+	assert.False(t, banned)
+
+	require.NoError(t, db.Create(ctx, &RoomBan{RoomId: 1, UserId: 2, OperatorId: 1}))
+	// 重复加入时忽略
+	require.NoError(t, db.Create(ctx, &RoomBan{RoomId: 1, UserId: 2, OperatorId: 1}))
+
+	banned, err = db.IsBanned(ctx, 1, 2)
+	require.NoError(t, err)
+	assert.True(t, banned)
+
+	bans, err := db.List(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, bans, 1)
+
+	require.NoError(t, db.Delete(ctx, 1, 2))
+	banned, err = db.IsBanned(ctx, 1, 2)
+	require.NoError(t, err)
+	assert.False(t, banned)
+}
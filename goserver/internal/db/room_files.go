@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// RoomFile 房间共享文件表结构体
+type RoomFile struct {
+	Id       uint64 `gorm:"primaryKey" json:"id"`
+	RoomId   uint64 `gorm:"index:idx_room_file_room;not null" json:"roomId"`
+	UserId   uint64 `gorm:"not null" json:"userId"`                     // 上传者
+	Name     string `gorm:"type:varchar(255);not null" json:"name"`     // 原始文件名
+	Size     int64  `gorm:"not null" json:"size"`                       // 文件大小(字节)
+	MimeType string `gorm:"type:varchar(255);not null" json:"mimeType"` // 按内容识别的文件类型
+	Path     string `gorm:"type:varchar(255);not null" json:"-"`        // 相对附件目录的存储路径
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type RoomFilesStore interface {
+	// Create 保存文件信息, 多个文件在同一条语句中保存, 全部成功或全部失败
+	Create(ctx context.Context, files ...*RoomFile) error
+	// GetByID 获取房间内的文件
+	GetByID(ctx context.Context, roomId uint64, id uint64) (*RoomFile, error)
+	// List 按上传时间倒序获取房间内的全部文件
+	List(ctx context.Context, roomId uint64) ([]*RoomFile, error)
+}
+
+type roomFiles struct {
+	*gorm.DB
+}
+
+var RoomFiles RoomFilesStore
+var _ RoomFilesStore = (*roomFiles)(nil)
+
+func (db *roomFiles) Create(ctx context.Context, files ...*RoomFile) error {
+	if len(files) == 0 {
+		return nil
+	}
+	return db.WithContext(ctx).Create(files).Error
+}
+
+func (db *roomFiles) GetByID(ctx context.Context, roomId uint64, id uint64) (*RoomFile, error) {
+	file := new(RoomFile)
+	err := db.WithContext(ctx).Where("room_id = ? AND id = ?", roomId, id).First(file).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Wrapf(err, "文件不存在(%d)", id)
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+func (db *roomFiles) List(ctx context.Context, roomId uint64) ([]*RoomFile, error) {
+	files := make([]*RoomFile, 0)
+	err := db.WithContext(ctx).Where("room_id = ?", roomId).Order("id DESC").Find(&files).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "list room files")
+	}
+	return files, nil
+}
+
+func useRoomFilesStore(db *gorm.DB) RoomFilesStore {
+	return &roomFiles{DB: db}
+}
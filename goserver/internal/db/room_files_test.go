@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/db/dbtest"
+)
+
+func TestRoomFiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+
+	ctx := context.Background()
+	tables := []any{
+		new(RoomFile),
+	}
+	db := &roomFiles{
+		DB: dbtest.NewDB(t, "roomFiles", tables...),
+	}
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, ctx context.Context, db *roomFiles)
+	}{
+		{"Create", roomFilesCreate},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := clearTables(t, db.DB, tables...)
+				require.NoError(t, err)
+			})
+			tc.test(t, ctx, db)
+		})
+		if t.Failed() {
+			break
+		}
+	}
+}
+
+func roomFilesCreate(t *testing.T, ctx context.Context, db *roomFiles) {
+	first := &RoomFile{RoomId: 1, UserId: 2, Name: "a.png", Size: 10, MimeType: "image/png", Path: "1/a"}
+	second := &RoomFile{RoomId: 1, UserId: 3, Name: "b.pdf", Size: 20, MimeType: "application/pdf", Path: "1/b"}
+	require.NoError(t, db.Create(ctx, first, second))
+	assert.NotZero(t, first.Id)
+	assert.Greater(t, second.Id, first.Id)
+	require.NoError(t, db.Create(ctx, &RoomFile{RoomId: 2, UserId: 2, Name: "c.png", MimeType: "image/png", Path: "2/c"}))
+
+	got, err := db.GetByID(ctx, 1, first.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "1/a", got.Path)
+
+	// 其它房间的文件不可见
+	_, err = db.GetByID(ctx, 2, first.Id)
+	assert.Error(t, err)
+
+	files, err := db.List(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, second.Id, files[0].Id)
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RoomRole 房间角色
+type RoomRole string
+
+const (
+	RoleParticipant RoomRole = "participant" // 参会者
+	RoleCoHost      RoomRole = "cohost"      // 联席主持人
+	RoleHost        RoomRole = "host"        // 主持人
+	RoleOwner       RoomRole = "owner"       // 房间所有者
+)
+
+// roleLevels 角色等级, 数值越大权限越高
+var roleLevels = map[RoomRole]int{
+	RoleParticipant: 1,
+	RoleCoHost:      2,
+	RoleHost:        3,
+	RoleOwner:       4,
+}
+
+// Level 角色等级, 未知角色为 0
+func (r RoomRole) Level() int {
+	return roleLevels[r]
+}
+
+// IsValid 是否为有效角色
+func (r RoomRole) IsValid() bool {
+	return r.Level() > 0
+}
+
+// AtLeast 角色是否不低于 other
+func (r RoomRole) AtLeast(other RoomRole) bool {
+	return r.Level() >= other.Level()
+}
+
+// RoomMember 房间成员角色表结构体, 未记录的用户为参会者
+type RoomMember struct {
+	Id     uint64   `gorm:"primaryKey" json:"id"`
+	RoomId uint64   `gorm:"uniqueIndex:idx_room_member_room_user;not null" json:"roomId"`
+	UserId uint64   `gorm:"uniqueIndex:idx_room_member_room_user;not null" json:"userId"`
+	Role   RoomRole `gorm:"type:varchar(20);not null" json:"role"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type RoomMembersStore interface {
+	// GetRole 获取用户在房间中的角色, 房间创建者为所有者
+	GetRole(ctx context.Context, roomId uint64, userId uint64) (RoomRole, error)
+	// SetRole 设置用户在房间中的角色, 设置为参会者时删除记录
+	SetRole(ctx context.Context, roomId uint64, userId uint64, role RoomRole) error
+	List(ctx context.Context, roomId uint64) ([]*RoomMember, error)
+}
+
+type roomMembers struct {
+	*gorm.DB
+}
+
+var RoomMembers RoomMembersStore
+var _ RoomMembersStore = (*roomMembers)(nil)
+
+func (db *roomMembers) GetRole(ctx context.Context, roomId uint64, userId uint64) (RoomRole, error) {
+	room := new(Room)
+	err := db.WithContext(ctx).Where("id = ?", roomId).First(room).Error
+	if err != nil {
+		return "", errors.Wrapf(err, "get room %d", roomId)
+	}
+	if room.IsOwner(userId) {
+		return RoleOwner, nil
+	}
+
+	member := new(RoomMember)
+	err = db.WithContext(ctx).Where("room_id = ? AND user_id = ?", roomId, userId).First(member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return RoleParticipant, nil
+		}
+		return "", err
+	}
+	return member.Role, nil
+}
+
+func (db *roomMembers) SetRole(ctx context.Context, roomId uint64, userId uint64, role RoomRole) error {
+	if !role.IsValid() || role == RoleOwner {
+		return errors.Errorf("无效的角色: %s", role)
+	}
+	if role == RoleParticipant {
+		return db.WithContext(ctx).Where("room_id = ? AND user_id = ?", roomId, userId).Delete(&RoomMember{}).Error
+	}
+
+	return db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "room_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(&RoomMember{
+		RoomId: roomId,
+		UserId: userId,
+		Role:   role,
+	}).Error
+}
+
+func (db *roomMembers) List(ctx context.Context, roomId uint64) ([]*RoomMember, error) {
+	members := make([]*RoomMember, 0)
+	err := db.WithContext(ctx).Where("room_id = ?", roomId).Order("id").Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func useRoomMembersStore(db *gorm.DB) RoomMembersStore {
+	return &roomMembers{DB: db}
+}
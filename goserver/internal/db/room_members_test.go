@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/db/dbtest"
+)
+
+func TestRoomMembers(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+
+	ctx := context.Background()
+	tables := []any{
+		new(Room),
+		new(RoomMember),
+	}
+	db := &roomMembers{
+		DB: dbtest.NewDB(t, "roomMembers", tables...),
+	}
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, ctx context.Context, db *roomMembers)
+	}{
+		{"GetRole", roomMembersGetRole},
+		{"SetRole", roomMembersSetRole},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := clearTables(t, db.DB, tables...)
+				require.NoError(t, err)
+			})
+			tc.test(t, ctx, db)
+		})
+		if t.Failed() {
+			break
+		}
+	}
+}
+
+func roomMembersGetRole(t *testing.T, ctx context.Context, db *roomMembers) {
+	room := &Room{UserId: 1, Name: "daily"}
+	require.NoError(t, db.WithContext(ctx).Create(room).Error)
+
+	role, err := db.GetRole(ctx, room.Id, 1)
+	require.NoError(t, err)
+	assert.Equal(t, RoleOwner, role)
+
+	role, err = db.GetRole(ctx, room.Id, 2)
+	require.NoError(t, err)
+	assert.Equal(t, RoleParticipant, role)
+
+	_, err = db.GetRole(ctx, 404, 1)
+	assert.Error(t, err)
+}
+
+func roomMembersSetRole(t *testing.T, ctx context.Context, db *roomMembers) {
+	room := &Room{UserId: 1, Name: "daily"}
+	require.NoError(t, db.WithContext(ctx).Create(room).Error)
+
+	require.NoError(t, db.SetRole(ctx, room.Id, 2, RoleCoHost))
+	role, err := db.GetRole(ctx, room.Id, 2)
+	require.NoError(t, err)
+	assert.Equal(t, RoleCoHost, role)
+
+	require.NoError(t, db.SetRole(ctx, room.Id, 2, RoleHost))
+	role, err = db.GetRole(ctx, room.Id, 2)
+	require.NoError(t, err)
+	assert.Equal(t, RoleHost, role)
+
+	members, err := db.List(ctx, room.Id)
+	require.NoError(t, err)
+	assert.Len(t, members, 1)
+
+	require.NoError(t, db.SetRole(ctx, room.Id, 2, RoleParticipant))
+	members, err = db.List(ctx, room.Id)
+	require.NoError(t, err)
+	assert.Len(t, members, 0)
+
+	assert.Error(t, db.SetRole(ctx, room.Id, 2, RoleOwner))
+	assert.Error(t, db.SetRole(ctx, room.Id, 2, "admin"))
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// RoomMessage 房间聊天消息表结构体
+type RoomMessage struct {
+	Id       uint64 `gorm:"primaryKey" json:"id"`
+	RoomId   uint64 `gorm:"index:idx_room_message_room;not null" json:"roomId"`
+	UserId   uint64 `gorm:"not null" json:"userId"`             // 发送者
+	UserName string `gorm:"type:varchar(255)" json:"userName"`  // 发送时的用户名
+	ToUserId uint64 `gorm:"not null;default:0" json:"toUserId"` // 私聊的接收者, 为 0 时发送给房间内全部成员
+	ReplyTo  uint64 `gorm:"not null;default:0" json:"replyTo"`  // 回复的消息ID
+	Content  string `gorm:"type:text;not null" json:"content"`  // 消息内容
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ListRoomMessagesOptions 聊天记录查询选项
+type ListRoomMessagesOptions struct {
+	RoomId uint64
+	// UserId 查看者, 私聊消息只对发送者和接收者可见
+	UserId   uint64
+	Page     int
+	PageSize int
+}
+
+type RoomMessagesStore interface {
+	// Create 保存聊天消息
+	Create(ctx context.Context, message *RoomMessage) error
+	// GetByID 获取房间内的聊天消息
+	GetByID(ctx context.Context, roomId uint64, id uint64) (*RoomMessage, error)
+	// List 按发送时间倒序分页查询查看者可见的聊天消息
+	List(ctx context.Context, opts ListRoomMessagesOptions) ([]*RoomMessage, int64, error)
+}
+
+type roomMessages struct {
+	*gorm.DB
+}
+
+var RoomMessages RoomMessagesStore
+var _ RoomMessagesStore = (*roomMessages)(nil)
+
+func (db *roomMessages) Create(ctx context.Context, message *RoomMessage) error {
+	return db.WithContext(ctx).Create(message).Error
+}
+
+func (db *roomMessages) GetByID(ctx context.Context, roomId uint64, id uint64) (*RoomMessage, error) {
+	message := new(RoomMessage)
+	err := db.WithContext(ctx).Where("room_id = ? AND id = ?", roomId, id).First(message).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Wrapf(err, "消息不存在(%d)", id)
+		}
+		return nil, err
+	}
+	return message, nil
+}
+
+func (db *roomMessages) List(ctx context.Context, opts ListRoomMessagesOptions) ([]*RoomMessage, int64, error) {
+	query := db.WithContext(ctx).Model(&RoomMessage{}).
+		Where("room_id = ?", opts.RoomId).
+		Where("to_user_id = 0 OR user_id = ? OR to_user_id = ?", opts.UserId, opts.UserId)
+
+	var count int64
+	err := query.Count(&count).Error
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "count room messages")
+	}
+
+	if opts.Page <= 0 {
+		opts.Page = 1
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = 20
+	}
+
+	messages := make([]*RoomMessage, 0, opts.PageSize)
+	err = query.Order("id DESC").
+		Limit(opts.PageSize).
+		Offset((opts.Page - 1) * opts.PageSize).
+		Find(&messages).Error
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "list room messages")
+	}
+	return messages, count, nil
+}
+
+func useRoomMessagesStore(db *gorm.DB) RoomMessagesStore {
+	return &roomMessages{DB: db}
+}
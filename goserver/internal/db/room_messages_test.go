@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/db/dbtest"
+)
+
+func TestRoomMessages(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+
+	ctx := context.Background()
+	tables := []any{
+		new(RoomMessage),
+	}
+	db := &roomMessages{
+		DB: dbtest.NewDB(t, "roomMessages", tables...),
+	}
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, ctx context.Context, db *roomMessages)
+	}{
+		{"Create", roomMessagesCreate},
+		{"List", roomMessagesList},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := clearTables(t, db.DB, tables...)
+				require.NoError(t, err)
+			})
+			tc.test(t, ctx, db)
+		})
+		if t.Failed() {
+			break
+		}
+	}
+}
+
+func roomMessagesCreate(t *testing.T, ctx context.Context, db *roomMessages) {
+	message := &RoomMessage{RoomId: 1, UserId: 2, UserName: "alice", Content: "hello"}
+	require.NoError(t, db.Create(ctx, message))
+	assert.NotZero(t, message.Id)
+	assert.False(t, message.CreatedAt.IsZero())
+
+	got, err := db.GetByID(ctx, 1, message.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", got.Content)
+
+	// 其它房间的消息不可见
+	_, err = db.GetByID(ctx, 2, message.Id)
+	assert.Error(t, err)
+}
+
+func roomMessagesList(t *testing.T, ctx context.Context, db *roomMessages) {
+	for _, message := range []*RoomMessage{
+		{RoomId: 1, UserId: 2, Content: "public 1"},
+		{RoomId: 1, UserId: 2, ToUserId: 3, Content: "private 2 -> 3"},
+		{RoomId: 1, UserId: 4, ToUserId: 5, Content: "private 4 -> 5"},
+		{RoomId: 1, UserId: 3, Content: "public 2"},
+		{RoomId: 2, UserId: 2, Content: "other room"},
+	} {
+		require.NoError(t, db.Create(ctx, message))
+	}
+
+	messages, total, err := db.List(ctx, ListRoomMessagesOptions{RoomId: 1, UserId: 3})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, messages, 3)
+	// 按发送时间倒序
+	assert.Equal(t, "public 2", messages[0].Content)
+	assert.Equal(t, "private 2 -> 3", messages[1].Content)
+	assert.Equal(t, "public 1", messages[2].Content)
+
+	messages, total, err = db.List(ctx, ListRoomMessagesOptions{RoomId: 1, UserId: 2, Page: 2, PageSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "public 1", messages[0].Content)
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RoomWhiteboard 房间白板快照表结构体, 房间内最后一名成员离开时保存
+type RoomWhiteboard struct {
+	Id     uint64 `gorm:"primaryKey" json:"id"`
+	RoomId uint64 `gorm:"uniqueIndex;not null" json:"roomId"`
+	Locked bool   `gorm:"not null;default:false" json:"locked"`
+	Seq    uint64 `gorm:"not null;default:0" json:"seq"` // 已分配的最大绘制序号
+	Ops    string `gorm:"type:text" json:"ops"`          // JSON 编码的绘制列表
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type RoomWhiteboardsStore interface {
+	// Get 获取房间的白板快照, 不存在时返回空白板
+	Get(ctx context.Context, roomId uint64) (*RoomWhiteboard, error)
+	// Save 保存房间的白板快照, 已存在时覆盖
+	Save(ctx context.Context, board *RoomWhiteboard) error
+	Delete(ctx context.Context, roomId uint64) error
+}
+
+type roomWhiteboards struct {
+	*gorm.DB
+}
+
+var RoomWhiteboards RoomWhiteboardsStore
+var _ RoomWhiteboardsStore = (*roomWhiteboards)(nil)
+
+func (db *roomWhiteboards) Get(ctx context.Context, roomId uint64) (*RoomWhiteboard, error) {
+	board := new(RoomWhiteboard)
+	err := db.WithContext(ctx).Where("room_id = ?", roomId).First(board).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &RoomWhiteboard{RoomId: roomId}, nil
+		}
+		return nil, err
+	}
+	return board, nil
+}
+
+func (db *roomWhiteboards) Save(ctx context.Context, board *RoomWhiteboard) error {
+	return db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "room_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"locked", "seq", "ops", "updated_at"}),
+	}).Create(board).Error
+}
+
+func (db *roomWhiteboards) Delete(ctx context.Context, roomId uint64) error {
+	return db.WithContext(ctx).Where("room_id = ?", roomId).Delete(&RoomWhiteboard{}).Error
+}
+
+func useRoomWhiteboardsStore(db *gorm.DB) RoomWhiteboardsStore {
+	return &roomWhiteboards{DB: db}
+}
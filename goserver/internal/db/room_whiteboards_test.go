@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/db/dbtest"
+)
+
+func TestRoomWhiteboards(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+
+	ctx := context.Background()
+	tables := []any{
+		new(RoomWhiteboard),
+	}
+	db := &roomWhiteboards{
+		DB: dbtest.NewDB(t, "roomWhiteboards", tables...),
+	}
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, ctx context.Context, db *roomWhiteboards)
+	}{
+		{"Save", roomWhiteboardsSave},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := clearTables(t, db.DB, tables...)
+				require.NoError(t, err)
+			})
+			tc.test(t, ctx, db)
+		})
+		if t.Failed() {
+			break
+		}
+	}
+}
+
+func roomWhiteboardsSave(t *testing.T, ctx context.Context, db *roomWhiteboards) {
+	// 不存在时返回空白板
+	board, err := db.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), board.RoomId)
+	assert.Empty(t, board.Ops)
+
+	require.NoError(t, db.Save(ctx, &RoomWhiteboard{RoomId: 1, Seq: 1, Ops: `[{"seq":1}]`}))
+	// 已存在时覆盖
+	require.NoError(t, db.Save(ctx, &RoomWhiteboard{RoomId: 1, Locked: true, Seq: 2, Ops: `[{"seq":1},{"seq":2}]`}))
+
+	board, err = db.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, board.Locked)
+	assert.Equal(t, uint64(2), board.Seq)
+	assert.Equal(t, `[{"seq":1},{"seq":2}]`, board.Ops)
+
+	require.NoError(t, db.Delete(ctx, 1))
+	board, err = db.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, board.Locked)
+}
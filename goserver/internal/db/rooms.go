@@ -2,36 +2,118 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"io.wandao.meeting/internal/utils/errutil"
+	"io.wandao.meeting/internal/utils/userutil"
+
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
-// Room 用户表结构体
+// Room 房间表结构体
 type Room struct {
 	Id     uint64 `gorm:"primaryKey" json:"id"`
 	UserId uint64 `json:"userId"`
 	Name   string `xorm:"UNIQUE NOT NULL" gorm:"unique;not null" json:"name"`
 
+	Locked      bool   `gorm:"not null;default:false" json:"locked"`     // 是否锁定
+	Passwd      string `gorm:"column:passwd;type:varchar(255)" json:"-"` // 入会密码
+	Salt        string `gorm:"type:VARCHAR(10)" json:"-"`                // 密码盐
+	MaxPeers    int    `gorm:"not null;default:0" json:"maxPeers"`       // 最大人数, 0 为不限
+	Lobby       bool   `gorm:"not null;default:false" json:"lobby"`      // 是否启用等候室
+	Description string `gorm:"type:varchar(1024)" json:"description"`    // 房间描述
+	HasPasswd   bool   `gorm:"-" json:"hasPasswd"`                       // 是否设置了入会密码
+	ParentId    uint64 `gorm:"index;not null;default:0" json:"parentId"` // 分组讨论所属的主房间, 普通房间为 0
+	Closed      bool   `gorm:"not null;default:false" json:"closed"`     // 分组讨论是否已结束, 结束后保留房间数据
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
+// AfterFind 查询后填充计算字段
+func (r *Room) AfterFind(_ *gorm.DB) error {
+	r.HasPasswd = r.HasPassword()
+	return nil
+}
+
+// HasPassword 是否设置了入会密码
+func (r *Room) HasPassword() bool {
+	return len(r.Passwd) > 0
+}
+
+// ValidatePassword 验证入会密码
+func (r *Room) ValidatePassword(passwd string) bool {
+	if !r.HasPassword() {
+		return true
+	}
+	return userutil.ValidatePassword(r.Passwd, r.Salt, passwd)
+}
+
+// IsOwner 是否为房间所有者
+func (r *Room) IsOwner(userId uint64) bool {
+	return r.UserId == userId
+}
+
 type RoomInput struct {
 	Name string `xorm:"UNIQUE NOT NULL" json:"name"`
 }
 
+// ListRoomsOptions 房间列表查询选项
+type ListRoomsOptions struct {
+	// UserId 大于 0 时只查询该用户创建的房间
+	UserId   uint64
+	Page     int
+	PageSize int
+}
+
+// UpdateRoomSettingsOptions 房间设置更新选项, 为 nil 的字段不更新
+type UpdateRoomSettingsOptions struct {
+	Locked *bool
+	// Passwd 为空字符串时清除入会密码
+	Passwd      *string
+	MaxPeers    *int
+	Lobby       *bool
+	Description *string
+}
+
 type RoomsStore interface {
 	Save(ctx context.Context, room *Room) error
 	Create(ctx context.Context, room *Room) (*Room, error)
+	Rename(ctx context.Context, roomId uint64, name string) error
+	UpdateSettings(ctx context.Context, roomId uint64, opts UpdateRoomSettingsOptions) error
+	List(ctx context.Context, opts ListRoomsOptions) ([]*Room, int64, error)
 	GetByID(ctx context.Context, roomId uint64) (*Room, error)
 	GetByName(ctx context.Context, name string) (*Room, error)
+	// ListByParent 按 ID 获取主房间的分组讨论房间
+	ListByParent(ctx context.Context, parentId uint64) ([]*Room, error)
+	// ListBreakouts 获取全部未结束的分组讨论房间, 用于启动时清理遗留的分组
+	ListBreakouts(ctx context.Context) ([]*Room, error)
+	// SetClosed 结束或重新开启分组讨论房间, 房间的聊天、文件和录制等数据保留
+	SetClosed(ctx context.Context, roomId uint64, closed bool) error
 	DeleteByID(ctx context.Context, roomId uint64) error
+	// DeleteWithData 在同一事务中删除房间及其分组讨论房间, 以及成员、禁入名单、聊天、文件、白板、预约会议、会议记录和录制
+	// 返回被删除的文件和录制记录, 由调用方删除磁盘上对应的文件
+	DeleteWithData(ctx context.Context, roomId uint64) ([]*RoomFile, []*Recording, error)
 	DeleteByName(ctx context.Context, name string) error
 }
 
+// ErrRoomAlreadyExist 房间名称已存在
+type ErrRoomAlreadyExist struct {
+	args errutil.Args
+}
+
+// IsErrRoomAlreadyExist 是否为房间名称已存在错误
+func IsErrRoomAlreadyExist(err error) bool {
+	return errors.As(err, &ErrRoomAlreadyExist{})
+}
+
+func (err ErrRoomAlreadyExist) Error() string {
+	return fmt.Sprintf("房间名称已存在: %v", err.args)
+}
+
 type rooms struct {
 	*gorm.DB
 }
@@ -56,9 +138,95 @@ func (db *rooms) Create(ctx context.Context, room *Room) (*Room, error) {
 		return nil, errors.New("房间名称必须")
 	}
 
+	_, err := db.GetByName(ctx, room.Name)
+	if err == nil {
+		return nil, ErrRoomAlreadyExist{args: errutil.Args{"name": room.Name}}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
 	return room, db.WithContext(ctx).Create(&room).Error
 }
 
+func (db *rooms) Rename(ctx context.Context, roomId uint64, name string) error {
+	if len(name) == 0 {
+		return errors.New("房间名称必须")
+	}
+
+	other, err := db.GetByName(ctx, name)
+	if err == nil && other.Id != roomId {
+		return ErrRoomAlreadyExist{args: errutil.Args{"name": name}}
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	return db.WithContext(ctx).Model(&Room{}).Where("id = ?", roomId).Update("name", name).Error
+}
+
+func (db *rooms) UpdateSettings(ctx context.Context, roomId uint64, opts UpdateRoomSettingsOptions) error {
+	updates := make(map[string]any)
+	if opts.Locked != nil {
+		updates["locked"] = *opts.Locked
+	}
+	if opts.Passwd != nil {
+		if len(*opts.Passwd) > 0 {
+			salt, err := userutil.RandomSalt()
+			if err != nil {
+				return err
+			}
+			updates["salt"] = salt
+			updates["passwd"] = userutil.EncodePassword(*opts.Passwd, salt)
+		} else {
+			updates["salt"] = ""
+			updates["passwd"] = ""
+		}
+	}
+	if opts.MaxPeers != nil {
+		updates["max_peers"] = *opts.MaxPeers
+	}
+	if opts.Lobby != nil {
+		updates["lobby"] = *opts.Lobby
+	}
+	if opts.Description != nil {
+		updates["description"] = *opts.Description
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return db.WithContext(ctx).Model(&Room{}).Where("id = ?", roomId).Updates(updates).Error
+}
+
+func (db *rooms) List(ctx context.Context, opts ListRoomsOptions) ([]*Room, int64, error) {
+	// 分组讨论房间随主房间的分组讨论创建和删除, 不出现在房间列表中
+	query := db.WithContext(ctx).Model(&Room{}).Where("parent_id = 0")
+	if opts.UserId > 0 {
+		query = query.Where("user_id = ?", opts.UserId)
+	}
+
+	var count int64
+	err := query.Count(&count).Error
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "count rooms")
+	}
+
+	if opts.Page <= 0 {
+		opts.Page = 1
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = 20
+	}
+
+	rooms := make([]*Room, 0, opts.PageSize)
+	err = query.Order("id DESC").
+		Limit(opts.PageSize).
+		Offset((opts.Page - 1) * opts.PageSize).
+		Find(&rooms).Error
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "list rooms")
+	}
+	return rooms, count, nil
+}
+
 func (db *rooms) GetByID(ctx context.Context, roomId uint64) (*Room, error) {
 	room := new(Room)
 	err := db.WithContext(ctx).Where("id = ?", roomId).First(&room).Error
@@ -76,20 +244,93 @@ func (db *rooms) GetByName(ctx context.Context, name string) (*Room, error) {
 	err := db.WithContext(ctx).Where("name = ?", name).First(&room).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.Wrapf(err, "用户不存在(%s)", name)
+			return nil, errors.Wrapf(err, "房间不存在(%s)", name)
 		}
 		return nil, err
 	}
 	return room, nil
 }
 
+func (db *rooms) ListByParent(ctx context.Context, parentId uint64) ([]*Room, error) {
+	rooms := make([]*Room, 0)
+	err := db.WithContext(ctx).Where("parent_id = ?", parentId).Order("id").Find(&rooms).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "list breakout rooms")
+	}
+	return rooms, nil
+}
+
+func (db *rooms) ListBreakouts(ctx context.Context) ([]*Room, error) {
+	rooms := make([]*Room, 0)
+	err := db.WithContext(ctx).Where("parent_id > 0 AND closed = ?", false).Order("id").Find(&rooms).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "list all breakout rooms")
+	}
+	return rooms, nil
+}
+
+func (db *rooms) SetClosed(ctx context.Context, roomId uint64, closed bool) error {
+	return db.WithContext(ctx).Model(&Room{}).Where("id = ? AND parent_id > 0", roomId).Update("closed", closed).Error
+}
+
 func (db *rooms) DeleteByID(ctx context.Context, roomId uint64) error {
-	var room User
+	var room Room
 	return db.WithContext(ctx).Unscoped().Where("id=?", roomId).Delete(&room).Error
 }
 
+func (db *rooms) DeleteWithData(ctx context.Context, roomId uint64) (files []*RoomFile, recordings []*Recording, err error) {
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		roomIds := []uint64{roomId}
+		var children []uint64
+		err := tx.Model(&Room{}).Unscoped().Where("parent_id = ?", roomId).Pluck("id", &children).Error
+		if err != nil {
+			return errors.Wrap(err, "list breakout rooms")
+		}
+		roomIds = append(roomIds, children...)
+
+		if err = tx.Where("room_id IN ?", roomIds).Order("id").Find(&files).Error; err != nil {
+			return errors.Wrap(err, "list room files")
+		}
+		if err = tx.Where("room_id IN ?", roomIds).Order("id").Find(&recordings).Error; err != nil {
+			return errors.Wrap(err, "list recordings")
+		}
+
+		var meetingIds, sessionIds []uint64
+		if err = tx.Model(&Meeting{}).Where("room_id IN ?", roomIds).Pluck("id", &meetingIds).Error; err != nil {
+			return errors.Wrap(err, "list meetings")
+		}
+		if err = tx.Model(&MeetingSession{}).Where("room_id IN ?", roomIds).Pluck("id", &sessionIds).Error; err != nil {
+			return errors.Wrap(err, "list meeting sessions")
+		}
+		if len(meetingIds) > 0 {
+			if err = tx.Where("meeting_id IN ?", meetingIds).Delete(&MeetingInvitee{}).Error; err != nil {
+				return errors.Wrap(err, "delete meeting invitees")
+			}
+		}
+		if len(sessionIds) > 0 {
+			if err = tx.Where("session_id IN ?", sessionIds).Delete(&MeetingAttendance{}).Error; err != nil {
+				return errors.Wrap(err, "delete meeting attendances")
+			}
+		}
+
+		for _, table := range []any{
+			&RoomMember{}, &RoomBan{}, &RoomMessage{}, &RoomFile{}, &RoomWhiteboard{},
+			&Meeting{}, &MeetingSession{}, &Recording{},
+		} {
+			if err = tx.Where("room_id IN ?", roomIds).Delete(table).Error; err != nil {
+				return errors.Wrapf(err, "delete %T", table)
+			}
+		}
+		return tx.Unscoped().Where("id IN ?", roomIds).Delete(&Room{}).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return files, recordings, nil
+}
+
 func (db *rooms) DeleteByName(ctx context.Context, name string) error {
-	var room User
+	var room Room
 	return db.WithContext(ctx).Unscoped().Where("name=?", name).Delete(&room).Error
 }
 
@@ -0,0 +1,237 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/db/dbtest"
+)
+
+func TestRooms(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	t.Parallel()
+
+	ctx := context.Background()
+	tables := []any{
+		new(Room), new(RoomMember), new(RoomBan), new(RoomMessage), new(RoomFile), new(RoomWhiteboard),
+		new(Meeting), new(MeetingInvitee), new(MeetingSession), new(MeetingAttendance), new(Recording),
+	}
+	db := &rooms{
+		DB: dbtest.NewDB(t, "rooms", tables...),
+	}
+
+	for _, tc := range []struct {
+		name string
+		test func(t *testing.T, ctx context.Context, db *rooms)
+	}{
+		{"Create", roomsCreate},
+		{"Rename", roomsRename},
+		{"UpdateSettings", roomsUpdateSettings},
+		{"List", roomsList},
+		{"ListByParent", roomsListByParent},
+		{"DeleteByID", roomsDeleteByID},
+		{"DeleteWithData", roomsDeleteWithData},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				err := clearTables(t, db.DB, tables...)
+				require.NoError(t, err)
+			})
+			tc.test(t, ctx, db)
+		})
+		if t.Failed() {
+			break
+		}
+	}
+}
+
+func roomsCreate(t *testing.T, ctx context.Context, db *rooms) {
+	room, err := db.Create(ctx, &Room{UserId: 1, Name: "daily"})
+	require.NoError(t, err)
+	assert.NotZero(t, room.Id)
+
+	_, err = db.Create(ctx, &Room{UserId: 2, Name: "daily"})
+	assert.True(t, IsErrRoomAlreadyExist(err))
+}
+
+func roomsRename(t *testing.T, ctx context.Context, db *rooms) {
+	daily, err := db.Create(ctx, &Room{UserId: 1, Name: "daily"})
+	require.NoError(t, err)
+	_, err = db.Create(ctx, &Room{UserId: 1, Name: "weekly"})
+	require.NoError(t, err)
+
+	err = db.Rename(ctx, daily.Id, "weekly")
+	assert.True(t, IsErrRoomAlreadyExist(err))
+
+	err = db.Rename(ctx, daily.Id, "standup")
+	require.NoError(t, err)
+
+	got, err := db.GetByID(ctx, daily.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "standup", got.Name)
+}
+
+func roomsUpdateSettings(t *testing.T, ctx context.Context, db *rooms) {
+	room, err := db.Create(ctx, &Room{UserId: 1, Name: "daily"})
+	require.NoError(t, err)
+
+	locked := true
+	passwd := "secret"
+	maxPeers := 8
+	lobby := true
+	err = db.UpdateSettings(ctx, room.Id, UpdateRoomSettingsOptions{
+		Locked:   &locked,
+		Passwd:   &passwd,
+		MaxPeers: &maxPeers,
+		Lobby:    &lobby,
+	})
+	require.NoError(t, err)
+
+	got, err := db.GetByID(ctx, room.Id)
+	require.NoError(t, err)
+	assert.True(t, got.Locked)
+	assert.True(t, got.HasPasswd)
+	assert.Equal(t, 8, got.MaxPeers)
+	assert.True(t, got.Lobby)
+	assert.NotEqual(t, passwd, got.Passwd)
+	assert.True(t, got.ValidatePassword("secret"))
+	assert.False(t, got.ValidatePassword("wrong"))
+
+	passwd = ""
+	err = db.UpdateSettings(ctx, room.Id, UpdateRoomSettingsOptions{Passwd: &passwd})
+	require.NoError(t, err)
+
+	got, err = db.GetByID(ctx, room.Id)
+	require.NoError(t, err)
+	assert.False(t, got.HasPassword())
+	assert.True(t, got.ValidatePassword("anything"))
+	assert.True(t, got.Locked)
+}
+
+func roomsList(t *testing.T, ctx context.Context, db *rooms) {
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := db.Create(ctx, &Room{UserId: 1, Name: name})
+		require.NoError(t, err)
+	}
+	_, err := db.Create(ctx, &Room{UserId: 2, Name: "d"})
+	require.NoError(t, err)
+
+	list, total, err := db.List(ctx, ListRoomsOptions{Page: 1, PageSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+	require.Len(t, list, 2)
+	assert.Equal(t, "d", list[0].Name)
+
+	list, total, err = db.List(ctx, ListRoomsOptions{UserId: 1, Page: 2, PageSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, list, 1)
+	assert.Equal(t, "a", list[0].Name)
+}
+
+func roomsListByParent(t *testing.T, ctx context.Context, db *rooms) {
+	parent, err := db.Create(ctx, &Room{UserId: 1, Name: "training"})
+	require.NoError(t, err)
+	for _, name := range []string{"training-1", "training-2"} {
+		_, err = db.Create(ctx, &Room{UserId: 1, Name: name, ParentId: parent.Id})
+		require.NoError(t, err)
+	}
+
+	children, err := db.ListByParent(ctx, parent.Id)
+	require.NoError(t, err)
+	require.Len(t, children, 2)
+	assert.Equal(t, "training-1", children[0].Name)
+	assert.Equal(t, parent.Id, children[1].ParentId)
+
+	all, err := db.ListBreakouts(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	// 已结束的分组讨论房间保留, 但不再作为遗留的分组
+	require.NoError(t, db.SetClosed(ctx, children[0].Id, true))
+	require.NoError(t, db.SetClosed(ctx, parent.Id, true), "主房间不受影响")
+	closed, err := db.GetByID(ctx, children[0].Id)
+	require.NoError(t, err)
+	assert.True(t, closed.Closed)
+	all, err = db.ListBreakouts(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, children[1].Id, all[0].Id)
+	parent, err = db.GetByID(ctx, parent.Id)
+	require.NoError(t, err)
+	assert.False(t, parent.Closed)
+
+	require.NoError(t, db.SetClosed(ctx, children[0].Id, false))
+	all, err = db.ListBreakouts(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	// 分组讨论房间不出现在房间列表中
+	list, total, err := db.List(ctx, ListRoomsOptions{UserId: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, list, 1)
+	assert.Equal(t, parent.Id, list[0].Id)
+}
+
+func roomsDeleteByID(t *testing.T, ctx context.Context, db *rooms) {
+	room, err := db.Create(ctx, &Room{UserId: 1, Name: "daily"})
+	require.NoError(t, err)
+
+	err = db.DeleteByID(ctx, room.Id)
+	require.NoError(t, err)
+
+	_, err = db.GetByID(ctx, room.Id)
+	assert.Error(t, err)
+}
+
+func roomsDeleteWithData(t *testing.T, ctx context.Context, db *rooms) {
+	parent, err := db.Create(ctx, &Room{UserId: 1, Name: "training"})
+	require.NoError(t, err)
+	child, err := db.Create(ctx, &Room{UserId: 1, Name: "training-1", ParentId: parent.Id})
+	require.NoError(t, err)
+	other, err := db.Create(ctx, &Room{UserId: 1, Name: "daily"})
+	require.NoError(t, err)
+
+	now := time.Now()
+	for _, roomId := range []uint64{parent.Id, child.Id, other.Id} {
+		require.NoError(t, db.DB.Create(&RoomMember{RoomId: roomId, UserId: 2, Role: RoleParticipant}).Error)
+		require.NoError(t, db.DB.Create(&RoomBan{RoomId: roomId, UserId: 3, OperatorId: 1}).Error)
+		require.NoError(t, db.DB.Create(&RoomMessage{RoomId: roomId, UserId: 2, Content: "hello"}).Error)
+		require.NoError(t, db.DB.Create(&RoomFile{RoomId: roomId, UserId: 2, Name: "a.txt", MimeType: "text/plain", Path: fmt.Sprintf("%d/a.txt", roomId)}).Error)
+		require.NoError(t, db.DB.Create(&RoomWhiteboard{RoomId: roomId}).Error)
+		require.NoError(t, db.DB.Create(&Recording{RoomId: roomId, UserId: 1, Status: RecordingStopped, Path: fmt.Sprintf("%d/r", roomId), StartedAt: now}).Error)
+
+		meeting := &Meeting{RoomId: roomId, OrganizerId: 1, Title: "weekly", StartAt: now, EndAt: now.Add(time.Hour)}
+		require.NoError(t, db.DB.Create(meeting).Error)
+		require.NoError(t, db.DB.Create(&MeetingInvitee{MeetingId: meeting.Id, UserId: 2}).Error)
+		session := &MeetingSession{RoomId: roomId, StartedAt: now}
+		require.NoError(t, db.DB.Create(session).Error)
+		require.NoError(t, db.DB.Create(&MeetingAttendance{SessionId: session.Id, UserId: 2, UserName: "bob", JoinedAt: now}).Error)
+	}
+
+	files, recordings, err := db.DeleteWithData(ctx, parent.Id)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, fmt.Sprintf("%d/a.txt", parent.Id), files[0].Path)
+	require.Len(t, recordings, 2)
+	assert.Equal(t, fmt.Sprintf("%d/r", child.Id), recordings[1].Path)
+
+	// 主房间和分组讨论房间的数据全部删除, 其它房间不受影响
+	for _, table := range []any{
+		&Room{}, &RoomMember{}, &RoomBan{}, &RoomMessage{}, &RoomFile{}, &RoomWhiteboard{},
+		&Meeting{}, &MeetingInvitee{}, &MeetingSession{}, &MeetingAttendance{}, &Recording{},
+	} {
+		var count int64
+		require.NoError(t, db.Unscoped().Model(table).Count(&count).Error)
+		assert.Equal(t, int64(1), count, "%T", table)
+	}
+	_, err = db.GetByID(ctx, other.Id)
+	assert.NoError(t, err)
+}
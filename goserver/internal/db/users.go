@@ -2,10 +2,13 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
 
 	"io.wandao.meeting/internal/utils/cryptoutil"
+	"io.wandao.meeting/internal/utils/errutil"
+	"io.wandao.meeting/internal/utils/strutil"
 	"io.wandao.meeting/internal/utils/userutil"
 
 	"github.com/pkg/errors"
@@ -21,6 +24,10 @@ type User struct {
 	Email  string `xorm:"NOT NULL" gorm:"not null" json:"email"`
 	Avatar string `xorm:"VARCHAR(2048)" gorm:"type:VARCHAR(2048);" json:"avatar"`
 	Salt   string `xorm:"VARCHAR(10)" gorm:"type:VARCHAR(10)" json:"-"`
+	// TokenVersion 令牌版本, 修改密码时递增, 之前签发的令牌随即失效
+	TokenVersion uint64 `gorm:"not null;default:0" json:"-"`
+	// FeedToken 日历订阅令牌, 只用于访问会议日历订阅, 重置后原订阅地址失效
+	FeedToken string `gorm:"type:VARCHAR(64);index" json:"-"`
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -36,16 +43,55 @@ type UserInput struct {
 	Salt   string
 }
 
+// UpdateUserOptions 用户资料更新选项, 为 nil 的字段不更新
+type UpdateUserOptions struct {
+	Alias *string
+	Email *string
+}
+
 type UsersStore interface {
 	Login(ctx context.Context, name string, passwd string) (*User, error)
 	Save(ctx context.Context, user *User) error
 	Create(ctx context.Context, user *User) (*User, error)
+	Update(ctx context.Context, userId uint64, opts UpdateUserOptions) error
+	ChangePassword(ctx context.Context, userId uint64, passwd string) error
 	GetByID(ctx context.Context, id uint64) (*User, error)
 	GetByName(ctx context.Context, name string) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetByFeedToken(ctx context.Context, token string) (*User, error)
+	ResetFeedToken(ctx context.Context, userId uint64) (string, error)
 	DeleteByID(ctx context.Context, userId uint64) error
 	DeleteByName(ctx context.Context, name string) error
 }
 
+// ErrUserAlreadyExist 用户名已存在
+type ErrUserAlreadyExist struct {
+	args errutil.Args
+}
+
+// IsErrUserAlreadyExist 是否为用户名已存在错误
+func IsErrUserAlreadyExist(err error) bool {
+	return errors.As(err, &ErrUserAlreadyExist{})
+}
+
+func (err ErrUserAlreadyExist) Error() string {
+	return fmt.Sprintf("用户名已存在: %v", err.args)
+}
+
+// ErrEmailAlreadyUsed 邮箱已被使用
+type ErrEmailAlreadyUsed struct {
+	args errutil.Args
+}
+
+// IsErrEmailAlreadyUsed 是否为邮箱已被使用错误
+func IsErrEmailAlreadyUsed(err error) bool {
+	return errors.As(err, &ErrEmailAlreadyUsed{})
+}
+
+func (err ErrEmailAlreadyUsed) Error() string {
+	return fmt.Sprintf("邮箱已被使用: %v", err.args)
+}
+
 type users struct {
 	*gorm.DB
 }
@@ -104,7 +150,20 @@ func (db *users) Save(ctx context.Context, user *User) error {
 }
 
 func (db *users) Create(ctx context.Context, user *User) (*User, error) {
+	_, err := db.GetByName(ctx, user.Name)
+	if err == nil {
+		return nil, ErrUserAlreadyExist{args: errutil.Args{"name": user.Name}}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
 	if len(user.Email) > 0 {
+		_, err = db.GetByEmail(ctx, user.Email)
+		if err == nil {
+			return nil, ErrEmailAlreadyUsed{args: errutil.Args{"email": user.Email}}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
 		user.Avatar = cryptoutil.MD5(user.Email)
 	}
 
@@ -119,6 +178,42 @@ func (db *users) Create(ctx context.Context, user *User) (*User, error) {
 	return user, db.WithContext(ctx).Create(&user).Error
 }
 
+func (db *users) Update(ctx context.Context, userId uint64, opts UpdateUserOptions) error {
+	updates := make(map[string]any)
+	if opts.Alias != nil {
+		updates["alias"] = *opts.Alias
+	}
+	if opts.Email != nil {
+		if len(*opts.Email) > 0 {
+			other, err := db.GetByEmail(ctx, *opts.Email)
+			if err == nil && other.Id != userId {
+				return ErrEmailAlreadyUsed{args: errutil.Args{"email": *opts.Email}}
+			} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+		updates["email"] = *opts.Email
+		updates["avatar"] = cryptoutil.MD5(*opts.Email)
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return db.WithContext(ctx).Model(&User{}).Where("id = ?", userId).Updates(updates).Error
+}
+
+// ChangePassword 修改密码并递增令牌版本, 之前签发的令牌全部失效
+func (db *users) ChangePassword(ctx context.Context, userId uint64, passwd string) error {
+	salt, err := userutil.RandomSalt()
+	if err != nil {
+		return err
+	}
+	return db.WithContext(ctx).Model(&User{}).Where("id = ?", userId).Updates(map[string]any{
+		"salt":          salt,
+		"passwd":        userutil.EncodePassword(passwd, salt),
+		"token_version": gorm.Expr("token_version + 1"),
+	}).Error
+}
+
 func (db *users) GetByID(ctx context.Context, userId uint64) (*User, error) {
 	user := new(User)
 	err := db.WithContext(ctx).Where("id = ?", userId).First(&user).Error
@@ -143,6 +238,46 @@ func (db *users) GetByName(ctx context.Context, name string) (*User, error) {
 	return user, nil
 }
 
+func (db *users) GetByEmail(ctx context.Context, email string) (*User, error) {
+	user := new(User)
+	err := db.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Wrapf(err, "用户不存在(%s)", email)
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (db *users) GetByFeedToken(ctx context.Context, token string) (*User, error) {
+	if token == "" {
+		return nil, errors.Wrap(gorm.ErrRecordNotFound, "日历订阅令牌无效")
+	}
+	user := new(User)
+	err := db.WithContext(ctx).Where("feed_token = ?", token).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Wrap(err, "日历订阅令牌无效")
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// ResetFeedToken 生成新的日历订阅令牌, 原令牌随即失效
+func (db *users) ResetFeedToken(ctx context.Context, userId uint64) (string, error) {
+	token, err := strutil.RandomChars(32)
+	if err != nil {
+		return "", err
+	}
+	err = db.WithContext(ctx).Model(&User{}).Where("id = ?", userId).Update("feed_token", token).Error
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
 func (db *users) DeleteByID(ctx context.Context, userId uint64) error {
 	var user User
 	return db.WithContext(ctx).Unscoped().Where("id=?", userId).Delete(&user).Error
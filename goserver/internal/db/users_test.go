@@ -28,6 +28,10 @@ func TestUsers(t *testing.T) {
 		name string
 		test func(t *testing.T, ctx context.Context, db *users)
 	}{
+		{"Create", usersCreate},
+		{"Update", usersUpdate},
+		{"ChangePassword", usersChangePassword},
+		{"FeedToken", usersFeedToken},
 		{"useTexts", useTexts},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
@@ -108,3 +112,109 @@ func useTexts(t *testing.T, ctx context.Context, db *users) {
 		assert.Equal(t, wantErr, err)
 	})
 }
+
+func usersCreate(t *testing.T, ctx context.Context, db *users) {
+	_, err := db.Create(ctx, &User{
+		Name:   "alice",
+		Email:  "alice@example.com",
+		Passwd: "123456",
+	})
+	require.NoError(t, err)
+
+	_, err = db.Create(ctx, &User{
+		Name:   "alice",
+		Email:  "other@example.com",
+		Passwd: "123456",
+	})
+	assert.True(t, IsErrUserAlreadyExist(err))
+
+	_, err = db.Create(ctx, &User{
+		Name:   "bob",
+		Email:  "alice@example.com",
+		Passwd: "123456",
+	})
+	assert.True(t, IsErrEmailAlreadyUsed(err))
+}
+
+func usersUpdate(t *testing.T, ctx context.Context, db *users) {
+	alice, err := db.Create(ctx, &User{
+		Name:   "alice",
+		Email:  "alice@example.com",
+		Passwd: "123456",
+	})
+	require.NoError(t, err)
+	bob, err := db.Create(ctx, &User{
+		Name:   "bob",
+		Email:  "bob@example.com",
+		Passwd: "123456",
+	})
+	require.NoError(t, err)
+
+	alias := "Alice"
+	email := "alice@example.org"
+	err = db.Update(ctx, alice.Id, UpdateUserOptions{
+		Alias: &alias,
+		Email: &email,
+	})
+	require.NoError(t, err)
+
+	got, err := db.GetByID(ctx, alice.Id)
+	require.NoError(t, err)
+	assert.Equal(t, alias, got.Alias)
+	assert.Equal(t, email, got.Email)
+
+	err = db.Update(ctx, bob.Id, UpdateUserOptions{
+		Email: &email,
+	})
+	assert.True(t, IsErrEmailAlreadyUsed(err))
+}
+
+func usersChangePassword(t *testing.T, ctx context.Context, db *users) {
+	alice, err := db.Create(ctx, &User{
+		Name:   "alice",
+		Passwd: "123456",
+	})
+	require.NoError(t, err)
+
+	err = db.ChangePassword(ctx, alice.Id, "654321")
+	require.NoError(t, err)
+
+	_, err = db.Login(ctx, "alice", "123456")
+	assert.Error(t, err)
+
+	user, err := db.Login(ctx, "alice", "654321")
+	require.NoError(t, err)
+	assert.Equal(t, alice.Id, user.Id)
+	assert.Equal(t, alice.TokenVersion+1, user.TokenVersion)
+}
+
+func usersFeedToken(t *testing.T, ctx context.Context, db *users) {
+	alice, err := db.Create(ctx, &User{
+		Name:   "alice",
+		Passwd: "123456",
+	})
+	require.NoError(t, err)
+
+	_, err = db.GetByFeedToken(ctx, "")
+	assert.Error(t, err)
+
+	token, err := db.ResetFeedToken(ctx, alice.Id)
+	require.NoError(t, err)
+	assert.Len(t, token, 32)
+
+	user, err := db.GetByFeedToken(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, alice.Id, user.Id)
+
+	// 重置后原令牌失效
+	newToken, err := db.ResetFeedToken(ctx, alice.Id)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, newToken)
+
+	_, err = db.GetByFeedToken(ctx, token)
+	assert.Error(t, err)
+
+	user, err = db.GetByFeedToken(ctx, newToken)
+	require.NoError(t, err)
+	assert.Equal(t, alice.Id, user.Id)
+}
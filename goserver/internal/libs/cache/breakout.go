@@ -0,0 +1,138 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"io.wandao.meeting/internal/libs/redislib"
+	"io.wandao.meeting/internal/server/models"
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+const (
+	breakoutOwnerPrefix = "webrtc:breakout:owner:" // 保存分组讨论的节点, 按主房间 ID
+	breakoutStatePrefix = "webrtc:breakout:state:" // 分组讨论状态, 按主房间和分组房间 ID
+	breakoutCacheTime   = 24 * time.Hour
+)
+
+func getBreakoutOwnerKey(parentId uint64) (key string) {
+	key = fmt.Sprintf("%s%d", breakoutOwnerPrefix, parentId)
+	return
+}
+
+func getBreakoutStateKey(roomId uint64) (key string) {
+	key = fmt.Sprintf("%s%d", breakoutStatePrefix, roomId)
+	return
+}
+
+// delIfEqualScript 值与预期相同时删除 key
+var delIfEqualScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// ClaimBreakoutOwner 由 server 保存主房间的分组讨论, 已被其它节点保存时返回该节点
+func ClaimBreakoutOwner(parentId uint64, server *models.Server) (owner *models.Server, err error) {
+	key := getBreakoutOwnerKey(parentId)
+	redisClient := redislib.GetClient()
+	ok, err := redisClient.SetNX(context.Background(), key, server.String(), breakoutCacheTime).Result()
+	if err != nil {
+		fmt.Println("ClaimBreakoutOwner", key, err)
+		return
+	}
+	if ok {
+		return server, nil
+	}
+	owner, err = GetBreakoutOwner(parentId)
+	if err == nil && owner == nil {
+		// 保存者恰好释放, 重新获取
+		return ClaimBreakoutOwner(parentId, server)
+	}
+	return
+}
+
+// GetBreakoutOwner 保存主房间分组讨论的节点, 没有分组讨论时返回 nil
+func GetBreakoutOwner(parentId uint64) (owner *models.Server, err error) {
+	key := getBreakoutOwnerKey(parentId)
+	redisClient := redislib.GetClient()
+	value, err := redisClient.Get(context.Background(), key).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		fmt.Println("GetBreakoutOwner", key, err)
+		return
+	}
+	return models.StringToServer(value)
+}
+
+// DelBreakoutOwner 释放主房间的分组讨论, 只在仍由 server 保存时删除
+func DelBreakoutOwner(parentId uint64, server *models.Server) (err error) {
+	key := getBreakoutOwnerKey(parentId)
+	redisClient := redislib.GetClient()
+	err = delIfEqualScript.Run(context.Background(), redisClient, []string{key}, server.String()).Err()
+	if err != nil {
+		fmt.Println("DelBreakoutOwner", key, err)
+	}
+	return
+}
+
+// SetBreakoutState 保存主房间和分组房间的分组讨论状态, 供其它节点上加入的成员读取
+func SetBreakoutState(roomIds []uint64, state *protocol.BreakoutStateEvent) (err error) {
+	valueByte, err := json.Marshal(state)
+	if err != nil {
+		fmt.Println("SetBreakoutState json Marshal", state.ParentId, err)
+		return
+	}
+	redisClient := redislib.GetClient()
+	_, err = redisClient.Pipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		for _, roomId := range roomIds {
+			pipe.Set(context.Background(), getBreakoutStateKey(roomId), valueByte, breakoutCacheTime)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Println("SetBreakoutState", state.ParentId, err)
+	}
+	return
+}
+
+// DelBreakoutState 删除主房间和分组房间的分组讨论状态
+func DelBreakoutState(roomIds []uint64) (err error) {
+	keys := make([]string, 0, len(roomIds))
+	for _, roomId := range roomIds {
+		keys = append(keys, getBreakoutStateKey(roomId))
+	}
+	redisClient := redislib.GetClient()
+	err = redisClient.Del(context.Background(), keys...).Err()
+	if err != nil {
+		fmt.Println("DelBreakoutState", keys, err)
+	}
+	return
+}
+
+// GetBreakoutState 房间所属的分组讨论状态, 没有分组讨论时返回 nil
+func GetBreakoutState(roomId uint64) (state *protocol.BreakoutStateEvent, err error) {
+	key := getBreakoutStateKey(roomId)
+	redisClient := redislib.GetClient()
+	value, err := redisClient.Get(context.Background(), key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		fmt.Println("GetBreakoutState", key, err)
+		return
+	}
+	state = &protocol.BreakoutStateEvent{}
+	if err = json.Unmarshal(value, state); err != nil {
+		fmt.Println("GetBreakoutState json Unmarshal", key, err)
+		return nil, err
+	}
+	return
+}
@@ -0,0 +1,109 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"io.wandao.meeting/internal/libs/redislib"
+	"io.wandao.meeting/internal/server/models"
+)
+
+const (
+	roomMembersPrefix    = "webrtc:room:members:" // 房间内的成员, 各节点共享
+	lobbyMembersPrefix   = "webrtc:room:lobby:"   // 等候室中的用户, 各节点共享
+	roomMembersCacheTime = 24 * 60 * 60
+)
+
+func getRoomMembersKey(roomId uint64) (key string) {
+	key = fmt.Sprintf("%s%d", roomMembersPrefix, roomId)
+	return
+}
+
+func getLobbyMembersKey(roomId uint64) (key string) {
+	key = fmt.Sprintf("%s%d", lobbyMembersPrefix, roomId)
+	return
+}
+
+// SetRoomMember 记录房间成员
+func SetRoomMember(roomId uint64, member *models.RoomMember) (err error) {
+	return setMember(getRoomMembersKey(roomId), member)
+}
+
+// DelRoomMember 删除房间成员
+func DelRoomMember(roomId uint64, userId uint64) (err error) {
+	return delMember(getRoomMembersKey(roomId), userId)
+}
+
+// GetRoomMembers 获取房间内全部节点上的成员 key=userId
+func GetRoomMembers(roomId uint64) (members map[uint64]*models.RoomMember, err error) {
+	return getMembers(getRoomMembersKey(roomId))
+}
+
+// SetLobbyMember 记录等候室中的用户及其连接所在的节点
+func SetLobbyMember(roomId uint64, member *models.RoomMember) (err error) {
+	return setMember(getLobbyMembersKey(roomId), member)
+}
+
+// DelLobbyMember 删除等候室中的用户
+func DelLobbyMember(roomId uint64, userId uint64) (err error) {
+	return delMember(getLobbyMembersKey(roomId), userId)
+}
+
+// GetLobbyMembers 获取全部节点上等候室中的用户 key=userId
+func GetLobbyMembers(roomId uint64) (members map[uint64]*models.RoomMember, err error) {
+	return getMembers(getLobbyMembersKey(roomId))
+}
+
+func setMember(key string, member *models.RoomMember) (err error) {
+	valueByte, err := json.Marshal(member)
+	if err != nil {
+		fmt.Println("SetRoomMember json Marshal", key, err)
+		return
+	}
+	redisClient := redislib.GetClient()
+	err = redisClient.HSet(context.Background(), key, member.Peers.UserId, valueByte).Err()
+	if err != nil {
+		fmt.Println("SetRoomMember", key, err)
+		return
+	}
+	redisClient.Expire(context.Background(), key, roomMembersCacheTime*time.Second)
+	return
+}
+
+func delMember(key string, userId uint64) (err error) {
+	redisClient := redislib.GetClient()
+	err = redisClient.HDel(context.Background(), key, strconv.FormatUint(userId, 10)).Err()
+	if err != nil {
+		fmt.Println("DelRoomMember", key, err)
+		return
+	}
+	return
+}
+
+func getMembers(key string) (members map[uint64]*models.RoomMember, err error) {
+	members = make(map[uint64]*models.RoomMember)
+	redisClient := redislib.GetClient()
+	values, err := redisClient.HGetAll(context.Background(), key).Result()
+	if err != nil {
+		fmt.Println("GetRoomMembers", key, err)
+		return
+	}
+	for field, value := range values {
+		userId, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			fmt.Println("GetRoomMembers", key, field, err)
+			continue
+		}
+		member := &models.RoomMember{}
+		if err = json.Unmarshal([]byte(value), member); err != nil || member.Peers == nil {
+			fmt.Println("GetRoomMembers json Unmarshal", key, field, err)
+			continue
+		}
+		members[userId] = member
+	}
+	return
+}
@@ -0,0 +1,65 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"io.wandao.meeting/internal/libs/redislib"
+)
+
+const (
+	tokenRevokedPrefix = "webrtc:token:revoked:" // 已吊销的令牌
+)
+
+func getTokenRevokedKey(tokenId string) (key string) {
+	key = fmt.Sprintf("%s%s", tokenRevokedPrefix, tokenId)
+	return
+}
+
+// RevokeToken 吊销令牌, 记录保留到令牌过期为止
+func RevokeToken(tokenId string, expiresAt time.Time) (err error) {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return
+	}
+	redisClient := redislib.GetClient()
+	key := getTokenRevokedKey(tokenId)
+	err = redisClient.Set(context.Background(), key, "1", ttl).Err()
+	if err != nil {
+		fmt.Println("RevokeToken", key, err)
+		return
+	}
+	return
+}
+
+// IsTokenRevoked 令牌是否已被吊销
+func IsTokenRevoked(tokenId string) (revoked bool, err error) {
+	redisClient := redislib.GetClient()
+	key := getTokenRevokedKey(tokenId)
+	number, err := redisClient.Exists(context.Background(), key).Result()
+	if err != nil {
+		fmt.Println("IsTokenRevoked", key, err)
+		return
+	}
+	revoked = number > 0
+	return
+}
+
+// ConsumeToken 吊销只能使用一次的令牌, 令牌已被吊销时返回 false
+// 通过 SET NX 原子地检查并吊销, 并发使用同一令牌时只有一个成功
+func ConsumeToken(tokenId string, expiresAt time.Time) (ok bool, err error) {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return
+	}
+	redisClient := redislib.GetClient()
+	key := getTokenRevokedKey(tokenId)
+	ok, err = redisClient.SetNX(context.Background(), key, "1", ttl).Result()
+	if err != nil {
+		fmt.Println("ConsumeToken", key, err)
+		return
+	}
+	return
+}
@@ -43,8 +43,8 @@ func GetUserOnlineInfo(userKey string) (userOnline *models.UserOnline, err error
 		log.Error("[Redist]获取用户在线数据: json Unmarshal: %s | %v", userKey, err)
 		return
 	}
-	log.Info("[Redist]获取用户在线数据: ", userKey, "time",
-		userOnline.LoginTime, userOnline.HeartbeatTime, "AppIp",
+	log.Info("[Redist]获取用户在线数据: %s time: %d %d AppIp: %s %v", userKey,
+		userOnline.LoginTime, userOnline.HeartbeatTime,
 		userOnline.AppIp, userOnline.IsLogoff)
 	return
 }
@@ -0,0 +1,183 @@
+// Package cache 缓存
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"io.wandao.meeting/internal/libs/redislib"
+)
+
+const (
+	whiteboardPrefix    = "webrtc:whiteboard:" // 房间白板, 集群内各节点共享
+	whiteboardCacheTime = 7 * 24 * time.Hour   // 最后一次操作后保留的时间
+)
+
+// whiteboardKeys 白板的绘制序号、绘制列表和锁定状态
+func whiteboardKeys(roomId uint64) (seqKey string, opsKey string, lockedKey string) {
+	prefix := fmt.Sprintf("%s%d:", whiteboardPrefix, roomId)
+	return prefix + "seq", prefix + "ops", prefix + "locked"
+}
+
+// initWhiteboardScript 绘制序号不存在时用快照初始化白板, 已初始化时不处理
+// ARGV: 过期秒数, 绘制序号, 是否锁定, 绘制...
+var initWhiteboardScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2], "EX", ARGV[1])
+redis.call("DEL", KEYS[2], KEYS[3])
+for i = 4, #ARGV do
+	redis.call("RPUSH", KEYS[2], ARGV[i])
+end
+redis.call("EXPIRE", KEYS[2], ARGV[1])
+if ARGV[3] == "1" then
+	redis.call("SET", KEYS[3], "1", "EX", ARGV[1])
+end
+return 1`)
+
+// WhiteboardExists 白板是否已初始化
+func WhiteboardExists(roomId uint64) (exists bool, err error) {
+	seqKey, _, _ := whiteboardKeys(roomId)
+	redisClient := redislib.GetClient()
+	number, err := redisClient.Exists(context.Background(), seqKey).Result()
+	if err != nil {
+		fmt.Println("WhiteboardExists", seqKey, err)
+		return
+	}
+	exists = number > 0
+	return
+}
+
+// InitWhiteboard 用数据库中的快照初始化白板, 其它节点已初始化时不处理
+func InitWhiteboard(roomId uint64, seq uint64, ops []string, locked bool) (err error) {
+	seqKey, opsKey, lockedKey := whiteboardKeys(roomId)
+	args := make([]interface{}, 0, len(ops)+3)
+	lockedValue := "0"
+	if locked {
+		lockedValue = "1"
+	}
+	args = append(args, int64(whiteboardCacheTime/time.Second), seq, lockedValue)
+	for _, op := range ops {
+		args = append(args, op)
+	}
+	redisClient := redislib.GetClient()
+	err = initWhiteboardScript.Run(context.Background(), redisClient, []string{seqKey, opsKey, lockedKey}, args...).Err()
+	if err != nil {
+		fmt.Println("InitWhiteboard", seqKey, err)
+	}
+	return
+}
+
+// GetWhiteboard 获取白板的绘制序号、全部绘制和锁定状态
+func GetWhiteboard(roomId uint64) (seq uint64, ops []string, locked bool, err error) {
+	seqKey, opsKey, lockedKey := whiteboardKeys(roomId)
+	redisClient := redislib.GetClient()
+	ctx := context.Background()
+	var seqCmd *redis.StringCmd
+	var opsCmd *redis.StringSliceCmd
+	var lockedCmd *redis.IntCmd
+	_, err = redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		seqCmd = pipe.Get(ctx, seqKey)
+		opsCmd = pipe.LRange(ctx, opsKey, 0, -1)
+		lockedCmd = pipe.Exists(ctx, lockedKey)
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		fmt.Println("GetWhiteboard", seqKey, err)
+		return
+	}
+	seq, _ = seqCmd.Uint64()
+	return seq, opsCmd.Val(), lockedCmd.Val() > 0, nil
+}
+
+// GetWhiteboardMeta 获取白板的绘制数和锁定状态
+func GetWhiteboardMeta(roomId uint64) (length int64, locked bool, err error) {
+	_, opsKey, lockedKey := whiteboardKeys(roomId)
+	redisClient := redislib.GetClient()
+	ctx := context.Background()
+	var lenCmd, lockedCmd *redis.IntCmd
+	_, err = redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		lenCmd = pipe.LLen(ctx, opsKey)
+		lockedCmd = pipe.Exists(ctx, lockedKey)
+		return nil
+	})
+	if err != nil {
+		fmt.Println("GetWhiteboardMeta", opsKey, err)
+		return
+	}
+	return lenCmd.Val(), lockedCmd.Val() > 0, nil
+}
+
+// IncrWhiteboardSeq 分配新的绘制序号, 各节点分配的序号不重复
+func IncrWhiteboardSeq(roomId uint64) (seq uint64, err error) {
+	seqKey, _, _ := whiteboardKeys(roomId)
+	redisClient := redislib.GetClient()
+	ctx := context.Background()
+	number, err := redisClient.Incr(ctx, seqKey).Result()
+	if err != nil {
+		fmt.Println("IncrWhiteboardSeq", seqKey, err)
+		return
+	}
+	redisClient.Expire(ctx, seqKey, whiteboardCacheTime)
+	return uint64(number), nil
+}
+
+// PushWhiteboardOp 追加绘制
+func PushWhiteboardOp(roomId uint64, op string) (err error) {
+	_, opsKey, _ := whiteboardKeys(roomId)
+	redisClient := redislib.GetClient()
+	ctx := context.Background()
+	_, err = redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, opsKey, op)
+		pipe.Expire(ctx, opsKey, whiteboardCacheTime)
+		return nil
+	})
+	if err != nil {
+		fmt.Println("PushWhiteboardOp", opsKey, err)
+	}
+	return
+}
+
+// RemoveWhiteboardOp 删除绘制, 绘制已被删除时返回 false
+func RemoveWhiteboardOp(roomId uint64, op string) (removed bool, err error) {
+	_, opsKey, _ := whiteboardKeys(roomId)
+	redisClient := redislib.GetClient()
+	number, err := redisClient.LRem(context.Background(), opsKey, -1, op).Result()
+	if err != nil {
+		fmt.Println("RemoveWhiteboardOp", opsKey, err)
+		return
+	}
+	removed = number > 0
+	return
+}
+
+// ClearWhiteboard 清空全部绘制
+func ClearWhiteboard(roomId uint64) (err error) {
+	_, opsKey, _ := whiteboardKeys(roomId)
+	redisClient := redislib.GetClient()
+	err = redisClient.Del(context.Background(), opsKey).Err()
+	if err != nil {
+		fmt.Println("ClearWhiteboard", opsKey, err)
+	}
+	return
+}
+
+// SetWhiteboardLocked 设置白板锁定状态
+func SetWhiteboardLocked(roomId uint64, locked bool) (err error) {
+	_, _, lockedKey := whiteboardKeys(roomId)
+	redisClient := redislib.GetClient()
+	ctx := context.Background()
+	if locked {
+		err = redisClient.Set(ctx, lockedKey, "1", whiteboardCacheTime).Err()
+	} else {
+		err = redisClient.Del(ctx, lockedKey).Err()
+	}
+	if err != nil {
+		fmt.Println("SetWhiteboardLocked", lockedKey, err)
+	}
+	return
+}
@@ -34,3 +34,11 @@ func Init() {
 		log.Trace("Redis: PING<-%v", pong)
 	}
 }
+
+// Close 关闭 Redis 客户端
+func Close() error {
+	if client == nil {
+		return nil
+	}
+	return client.Close()
+}
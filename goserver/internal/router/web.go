@@ -5,6 +5,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"io.wandao.meeting/internal/context"
 	"io.wandao.meeting/internal/controller/home"
+	"io.wandao.meeting/internal/controller/meeting"
+	"io.wandao.meeting/internal/controller/recording"
+	"io.wandao.meeting/internal/controller/room"
 	"io.wandao.meeting/internal/controller/systems"
 	"io.wandao.meeting/internal/controller/user"
 )
@@ -18,6 +21,11 @@ func WebInit() *gin.Engine {
 	r.Use(context.CorsMiddleware)
 
 	r.POST("/login", context.Handle(user.Login))
+	r.POST("/register", context.Handle(user.Register))
+	r.POST("/refresh", context.Handle(user.Refresh))
+	r.POST("/logout", context.AuthMiddleware, context.Handle(user.Logout))
+	// 日历订阅, 由订阅地址中的令牌认证
+	r.GET("/calendar/:token/meetings.ics", context.FeedTokenMiddleware, context.Handle(meeting.Feed))
 
 	// home
 	homeRouter := r.Group("/home")
@@ -29,6 +37,7 @@ func WebInit() *gin.Engine {
 	systemRouter := r.Group("/system")
 	{
 		systemRouter.GET("/state", context.Handle(systems.Status))
+		systemRouter.GET("/protocol", context.Handle(systems.ProtocolSchema))
 	}
 
 	// 用户组
@@ -36,6 +45,51 @@ func WebInit() *gin.Engine {
 	{
 		userRouter.GET("/list", context.Handle(user.List))
 		userRouter.GET("/online", context.Handle(user.Online))
+		userRouter.GET("/me", context.Handle(user.Me))
+		userRouter.PUT("/me", context.Handle(user.UpdateProfile))
+		userRouter.DELETE("/me", context.Handle(user.Delete))
+		userRouter.PUT("/password", context.Handle(user.ChangePassword))
+		userRouter.GET("/meetings.ics", context.Handle(meeting.Feed))
+		userRouter.GET("/feed", context.Handle(meeting.FeedURL))
+		userRouter.POST("/feed/reset", context.Handle(meeting.ResetFeed))
+	}
+
+	// 房间组
+	roomRouter := r.Group("/room").Use(context.AuthMiddleware)
+	{
+		roomRouter.POST("", context.Handle(room.Create))
+		roomRouter.GET("/list", context.Handle(room.List))
+		roomRouter.GET("/:id", context.Handle(room.Info))
+		roomRouter.PUT("/:id", context.Handle(room.Rename))
+		roomRouter.PUT("/:id/settings", context.Handle(room.UpdateSettings))
+		roomRouter.DELETE("/:id", context.Handle(room.Delete))
+		roomRouter.GET("/:id/messages", context.Handle(room.Messages))
+		roomRouter.POST("/:id/files", context.Handle(room.UploadFiles))
+		roomRouter.GET("/:id/files", context.Handle(room.Files))
+		roomRouter.GET("/:id/files/:fileId", context.Handle(room.DownloadFile))
+		roomRouter.GET("/:id/sessions", context.Handle(room.Sessions))
+		roomRouter.GET("/:id/sessions/:sessionId/attendance", context.Handle(room.Attendance))
+	}
+
+	// 预约会议组
+	meetingRouter := r.Group("/meeting").Use(context.AuthMiddleware)
+	{
+		meetingRouter.POST("", context.Handle(meeting.Create))
+		meetingRouter.GET("/list", context.Handle(meeting.List))
+		meetingRouter.GET("/:id", context.Handle(meeting.Info))
+		meetingRouter.PUT("/:id", context.Handle(meeting.Update))
+		meetingRouter.DELETE("/:id", context.Handle(meeting.Cancel))
+		meetingRouter.GET("/:id/ics", context.Handle(meeting.Export))
+	}
+
+	// 会议录制组
+	recordingRouter := r.Group("/recording").Use(context.AuthMiddleware)
+	{
+		recordingRouter.GET("/list", context.Handle(recording.List))
+		recordingRouter.GET("/:id", context.Handle(recording.Info))
+		recordingRouter.GET("/:id/download", context.Handle(recording.Download))
+		recordingRouter.DELETE("/:id", context.Handle(recording.Delete))
+		recordingRouter.POST("/:id/chunks", context.Handle(recording.UploadChunk))
 	}
 
 	return r
@@ -1,11 +1,15 @@
 // Package router 路由
 package router
 
-import "io.wandao.meeting/internal/server/websocket"
+import (
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/websocket"
+)
 
 // WebRtcInit Websocket 路由
 func WebRtcInit() {
 	websocket.Register("login", websocket.LoginController)
+	websocket.Register("resume", websocket.ResumeController)
 	websocket.Register("ping", websocket.PingController)
 	websocket.Register("heartbeat", websocket.HeartbeatController)
 
@@ -15,4 +19,43 @@ func WebRtcInit() {
 	websocket.Register("roomAction", websocket.RoomAction)
 	websocket.Register("peerAction", websocket.PeerAction)
 	websocket.Register("peerStatus", websocket.PeerStatus)
+
+	websocket.Register("roleAction", websocket.RoleAction)
+	websocket.RequireRole("roleAction", db.RoleHost)
+
+	// 主持人管理
+	websocket.Register("kick", websocket.KickController)
+	websocket.Register("mute", websocket.MuteController)
+	websocket.Register("stopVideo", websocket.StopVideoController)
+	websocket.Register("muteAll", websocket.MuteAllController)
+	websocket.Register("ban", websocket.BanController)
+	websocket.Register("unban", websocket.UnbanController)
+	websocket.RequireRole("kick", db.RoleCoHost)
+	websocket.RequireRole("mute", db.RoleCoHost)
+	websocket.RequireRole("stopVideo", db.RoleCoHost)
+	websocket.RequireRole("muteAll", db.RoleCoHost)
+	websocket.RequireRole("ban", db.RoleHost)
+	websocket.RequireRole("unban", db.RoleHost)
+
+	// 等候室
+	websocket.Register("lobbyAdmit", websocket.LobbyAdmitController)
+	websocket.Register("lobbyReject", websocket.LobbyRejectController)
+	websocket.RequireRole("lobbyAdmit", db.RoleCoHost)
+	websocket.RequireRole("lobbyReject", db.RoleCoHost)
+
+	// 聊天
+	websocket.Register("chat", websocket.ChatController)
+
+	// 白板
+	websocket.Register("whiteboard", websocket.WhiteboardController)
+
+	// 录制
+	websocket.Register("startRecording", websocket.StartRecordingController)
+	websocket.Register("stopRecording", websocket.StopRecordingController)
+	websocket.RequireRole("startRecording", db.RoleHost)
+	websocket.RequireRole("stopRecording", db.RoleHost)
+
+	// 分组讨论
+	websocket.Register("breakout", websocket.BreakoutController)
+	websocket.RequireRole("breakout", db.RoleHost)
 }
@@ -0,0 +1,23 @@
+// Package models 数据模型
+package models
+
+import (
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+// RoomMember 房间成员及其所在的节点, 用于在多个节点之间同步房间成员
+type RoomMember struct {
+	AppIp   string          `json:"appIp"`   // 节点 Ip
+	AppPort string          `json:"appPort"` // 节点端口
+	Peers   *protocol.Peers `json:"peers"`   // 成员信息
+}
+
+// NewRoomMember 创建
+func NewRoomMember(server *Server, peers *protocol.Peers) *RoomMember {
+	return &RoomMember{AppIp: server.Ip, AppPort: server.Port, Peers: peers}
+}
+
+// Server 成员所在的节点
+func (m *RoomMember) Server() *Server {
+	return NewServer(m.AppIp, m.AppPort)
+}
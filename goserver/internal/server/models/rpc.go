@@ -0,0 +1,114 @@
+// Package models 数据模型
+package models
+
+import (
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+// RPCAuth 内部 RPC 调用携带的集群密钥, 嵌入全部调用参数
+type RPCAuth struct {
+	Secret string `json:"secret"`
+}
+
+// Authenticated 携带集群密钥的调用参数
+type Authenticated interface {
+	SetSecret(secret string)
+	GetSecret() string
+}
+
+// SetSecret 设置集群密钥, 由 rpcclient 在调用前填充
+func (a *RPCAuth) SetSecret(secret string) { a.Secret = secret }
+
+// GetSecret 获取调用携带的集群密钥
+func (a *RPCAuth) GetSecret() string { return a.Secret }
+
+// RoomArgs 按房间查询
+type RoomArgs struct {
+	RPCAuth
+	RoomId uint64 `json:"roomId"`
+}
+
+// OnlineUsersReply 节点上的在线用户
+type OnlineUsersReply struct {
+	UserIds []uint64 `json:"userIds"`
+}
+
+// SendMessageArgs 向用户或房间推送消息, UserId 为 0 时发送给房间内全部成员
+type SendMessageArgs struct {
+	RPCAuth
+	RoomId uint64      `json:"roomId"`
+	UserId uint64      `json:"userId"`
+	Cmd    string      `json:"cmd"`
+	Data   interface{} `json:"data"`
+}
+
+// SendMessageReply 推送结果
+type SendMessageReply struct {
+	Sent bool `json:"sent"` // 是否有成员收到消息
+}
+
+// KickArgs 将用户踢出房间
+type KickArgs struct {
+	RPCAuth
+	RoomId       uint64 `json:"roomId"`
+	UserId       uint64 `json:"userId"`
+	OperatorId   uint64 `json:"operatorId"`   // 操作者, 为 0 时表示由系统踢出
+	OperatorName string `json:"operatorName"` // 操作者用户名
+	Reason       string `json:"reason"`
+}
+
+// KickReply 踢出结果
+type KickReply struct {
+	Kicked bool `json:"kicked"` // 用户是否在该节点上并已被踢出
+}
+
+// RoomPeersReply 节点上的房间成员
+type RoomPeersReply struct {
+	Peers map[uint64]*protocol.Peers `json:"peers"`
+}
+
+// BreakoutArgs 由保存分组讨论的节点执行的分组操作
+type BreakoutArgs struct {
+	RPCAuth
+	RoomId  uint64                    `json:"roomId"` // 操作者所在的主房间或分组房间
+	UserId  uint64                    `json:"userId"` // 操作者
+	Request *protocol.BreakoutRequest `json:"request"`
+}
+
+// BreakoutReply 分组操作的结果, 与 BreakoutController 的应答一致
+type BreakoutReply struct {
+	Code uint64                       `json:"code"`
+	Msg  string                       `json:"msg"`
+	Data *protocol.BreakoutStateEvent `json:"data"`
+}
+
+// ModerateArgs 关闭成员的音频或视频, UserId 为 0 时关闭角色低于操作者的全部成员的麦克风
+type ModerateArgs struct {
+	RPCAuth
+	RoomId       uint64 `json:"roomId"`
+	UserId       uint64 `json:"userId"`
+	OperatorId   uint64 `json:"operatorId"`
+	OperatorName string `json:"operatorName"`
+	OperatorRole string `json:"operatorRole"` // 操作者的房间角色, 只处理角色低于操作者的成员
+	Action       string `json:"action"`       // audio 或 video
+}
+
+// ModerateReply 关闭结果
+type ModerateReply struct {
+	Done bool `json:"done"` // 用户是否在该节点上并已处理
+}
+
+// LobbyArgs 准入或拒绝等候室中的用户
+type LobbyArgs struct {
+	RPCAuth
+	RoomId     uint64 `json:"roomId"`
+	UserId     uint64 `json:"userId"`
+	OperatorId uint64 `json:"operatorId"`
+	Action     string `json:"action"` // admit 或 reject
+	Reason     string `json:"reason"`
+}
+
+// LobbyReply 处理结果, 与 LobbyAdmitController、LobbyRejectController 的应答码一致
+type LobbyReply struct {
+	Code uint64 `json:"code"`
+}
@@ -4,10 +4,8 @@ package models
 import (
 	"fmt"
 	"time"
-)
 
-const (
-	heartbeatTimeout = 3 * 60 // 用户心跳超时时间
+	"io.wandao.meeting/internal/conf"
 )
 
 // UserOnline 用户在线状态
@@ -27,6 +25,12 @@ type UserOnline struct {
 	IsLogoff      bool   `json:"isLogoff"`      // 是否下线
 }
 
+// GetUserKey 用户在线数据的 key
+func GetUserKey(roomId uint64, userId uint64) (key string) {
+	key = fmt.Sprintf("%d_%d", roomId, userId)
+	return
+}
+
 // UserLogin 用户登录
 func UserLogin(appIp, appPort string, roomId uint64, userId uint64, clientIp string, loginTime uint64) (userOnline *UserOnline) {
 	userOnline = &UserOnline{
@@ -55,13 +59,14 @@ func (u *UserOnline) LogOut() {
 	u.IsLogoff = true
 }
 
-// IsOnline 用户是否在线
+// IsOnline 用户是否在线, 心跳超过 conf.WebSocket.OnlineTimeout 未刷新视为离线
 func (u *UserOnline) IsOnline() (online bool) {
 	if u.IsLogoff {
 		return
 	}
 	currentTime := uint64(time.Now().Unix())
-	if u.HeartbeatTime < (currentTime - heartbeatTimeout) {
+	heartbeatTimeout := uint64(conf.WebSocket.OnlineTimeout / time.Second)
+	if u.HeartbeatTime+heartbeatTimeout < currentTime {
 		fmt.Println("用户是否在线 心跳超时", u.RoomId, u.UserId, u.HeartbeatTime)
 		return
 	}
@@ -0,0 +1,146 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+
+	"github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
+)
+
+const (
+	// SubprotocolJSON JSON 文本帧, 未协商子协议时的默认编码
+	SubprotocolJSON = "wdmeeting.json"
+	// SubprotocolMsgpack MessagePack 二进制帧
+	SubprotocolMsgpack = "wdmeeting.msgpack"
+)
+
+// Codec 信令帧的编码方式, 由 websocket 子协议协商
+type Codec interface {
+	// Name 对应的子协议名称
+	Name() string
+	// FrameType websocket 帧类型
+	FrameType() int
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	// UnmarshalStrict 与 Unmarshal 相同, 但数据中有 v 未定义的字段时返回错误
+	UnmarshalStrict(data []byte, v interface{}) error
+	// DecodeRequest 解析请求帧, data 保留未解析的原始数据, 由 DecodeCommand 按命令解析
+	DecodeRequest(frame []byte) (*RawRequest, error)
+}
+
+// RawRequest 尚未解析 data 的请求
+type RawRequest struct {
+	Seq  string
+	Cmd  string
+	Data []byte
+}
+
+var (
+	// JSON 默认的文本编码
+	JSON Codec = jsonCodec{}
+	// Msgpack 二进制编码
+	Msgpack Codec = newMsgpackCodec()
+)
+
+// Subprotocols 服务端支持的子协议, 按优先级排列
+func Subprotocols() []string {
+	return []string{SubprotocolMsgpack, SubprotocolJSON}
+}
+
+// CodecFor 根据协商的子协议获取编码方式, 未协商时使用 JSON
+func CodecFor(subprotocol string) Codec {
+	if subprotocol == SubprotocolMsgpack {
+		return Msgpack
+	}
+	return JSON
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string   { return SubprotocolJSON }
+func (jsonCodec) FrameType() int { return websocket.TextMessage }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) UnmarshalStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
+
+func (c jsonCodec) DecodeRequest(frame []byte) (*RawRequest, error) {
+	request := &struct {
+		Seq  string          `json:"seq"`
+		Cmd  string          `json:"cmd"`
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := c.Unmarshal(frame, request); err != nil {
+		return nil, err
+	}
+	return &RawRequest{Seq: request.Seq, Cmd: request.Cmd, Data: request.Data}, nil
+}
+
+type msgpackCodec struct {
+	handle *codec.MsgpackHandle
+	strict *codec.MsgpackHandle // 遇到未定义的字段时返回错误
+}
+
+func newMsgpackCodec() msgpackCodec {
+	strict := newMsgpackHandle()
+	strict.ErrorIfNoField = true
+	return msgpackCodec{handle: newMsgpackHandle(), strict: strict}
+}
+
+func newMsgpackHandle() *codec.MsgpackHandle {
+	handle := &codec.MsgpackHandle{}
+	// 与 JSON 保持一致: 使用 json 标签, 字符串按 str 类型编码, map 解析为 map[string]interface{}
+	handle.TypeInfos = codec.NewTypeInfos([]string{"json"})
+	handle.WriteExt = true
+	handle.RawToString = true
+	handle.Raw = true
+	handle.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	return handle
+}
+
+func (msgpackCodec) Name() string   { return SubprotocolMsgpack }
+func (msgpackCodec) FrameType() int { return websocket.BinaryMessage }
+
+func (c msgpackCodec) Marshal(v interface{}) (data []byte, err error) {
+	err = codec.NewEncoderBytes(&data, c.handle).Encode(v)
+	return
+}
+
+func (c msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return codec.NewDecoderBytes(data, c.handle).Decode(v)
+}
+
+func (c msgpackCodec) UnmarshalStrict(data []byte, v interface{}) error {
+	return codec.NewDecoderBytes(data, c.strict).Decode(v)
+}
+
+func (c msgpackCodec) DecodeRequest(frame []byte) (*RawRequest, error) {
+	request := &struct {
+		Seq  string    `json:"seq"`
+		Cmd  string    `json:"cmd"`
+		Data codec.Raw `json:"data"`
+	}{}
+	if err := c.Unmarshal(frame, request); err != nil {
+		return nil, err
+	}
+	return &RawRequest{Seq: request.Seq, Cmd: request.Cmd, Data: request.Data}, nil
+}
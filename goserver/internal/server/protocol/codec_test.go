@@ -0,0 +1,90 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecFor(t *testing.T) {
+	assert.Equal(t, JSON, CodecFor(""))
+	assert.Equal(t, JSON, CodecFor(SubprotocolJSON))
+	assert.Equal(t, Msgpack, CodecFor(SubprotocolMsgpack))
+	assert.Equal(t, websocket.TextMessage, JSON.FrameType())
+	assert.Equal(t, websocket.BinaryMessage, Msgpack.FrameType())
+}
+
+func TestCodec_DecodeRequest(t *testing.T) {
+	for _, c := range []Codec{JSON, Msgpack} {
+		t.Run(c.Name(), func(t *testing.T) {
+			frame, err := c.Marshal(map[string]interface{}{
+				"seq": "1",
+				"cmd": "roleAction",
+				"data": map[string]interface{}{
+					"action": "setRole",
+					"userId": 2,
+					"role":   "cohost",
+				},
+			})
+			require.NoError(t, err)
+
+			request, err := c.DecodeRequest(frame)
+			require.NoError(t, err)
+			assert.Equal(t, "1", request.Seq)
+			assert.Equal(t, "roleAction", request.Cmd)
+
+			payload, err := DecodeCommand(c, request.Cmd, request.Data)
+			require.NoError(t, err)
+			assert.Equal(t, &RoleAction{Action: "setRole", UserId: 2, Role: "cohost"}, payload)
+
+			_, err = c.DecodeRequest([]byte{0xc1})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCodec_UnknownField(t *testing.T) {
+	for _, c := range []Codec{JSON, Msgpack} {
+		t.Run(c.Name(), func(t *testing.T) {
+			data, err := c.Marshal(map[string]interface{}{
+				"userId": 2,
+				"reason": "spam",
+				"roomId": 1,
+			})
+			require.NoError(t, err)
+
+			_, err = DecodeCommand(c, "kick", data)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "roomId")
+
+			// 非严格解析仍然忽略未知字段
+			request := new(ModerationRequest)
+			require.NoError(t, c.Unmarshal(data, request))
+			assert.Equal(t, &ModerationRequest{UserId: 2, Reason: "spam"}, request)
+		})
+	}
+}
+
+func TestCodec_Message(t *testing.T) {
+	message := &Message{
+		Seq:  "1",
+		Cmd:  "peerRole",
+		From: 3,
+		Data: &PeerRoleEvent{RoomId: 101, UserId: 2, Role: "cohost"},
+	}
+	for _, c := range []Codec{JSON, Msgpack} {
+		t.Run(c.Name(), func(t *testing.T) {
+			frame, err := c.Marshal(message)
+			require.NoError(t, err)
+
+			got := make(map[string]interface{})
+			require.NoError(t, c.Unmarshal(frame, &got))
+			assert.Equal(t, "peerRole", got["cmd"])
+			data, ok := got["data"].(map[string]interface{})
+			require.True(t, ok)
+			assert.Equal(t, "cohost", data["role"])
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package protocol
+
+// 字段标签:
+//   validate:"required" 字段不能为零值
+//   enum:"a,b"          字段只能取列举的值, 零值由 required 决定是否允许
+
+// LoginRequest 登录请求数据
+type LoginRequest struct {
+	Token   string `json:"token" validate:"required"`  // 访问令牌
+	RoomId  uint64 `json:"roomId" validate:"required"` // 房间ID
+	UserId  uint64 `json:"userId"`                     // 用户ID, 以令牌中的用户为准
+	Version int    `json:"version"`                    // 客户端协议版本, 未声明时按 1 处理
+	Peers          // 直接嵌入的房间信息
+}
+
+// Peers 房间信息
+type Peers struct {
+	RoomId     uint64 `json:"roomId"`               // 房间ID
+	RoomName   string `json:"roomName"`             // 房间名称
+	RoomLock   bool   `json:"roomLock"`             // 房间锁
+	RoomPasswd string `json:"roomPasswd,omitempty"` // 房间密码, 仅在登录时提交
+
+	UserId   uint64 `json:"userId"`   // 用户ID
+	UserName string `json:"userName"` // 用户名
+	UserLock bool   `json:"userLock"` // 用户锁
+	Role     string `json:"role"`     // 房间角色
+
+	UseVideo bool `json:"useVideo"` // 是否有音频设备
+	UseAudio bool `json:"useAudio"` // 是否有视频设备
+
+	AudioStatus  bool `json:"audioStatus"`  // 音频播放状态
+	VideoStatus  bool `json:"videoStatus"`  // 视频显示状态
+	ScreenStatus bool `json:"screenStatus"` // 屏幕共享状态
+
+	HandStatus    bool `json:"handStatus"`    // 是否举手
+	RecordStatus  bool `json:"recordStatus"`  // 是否录音
+	PrivacyStatus bool `json:"privacyStatus"` // 是否小视图
+}
+
+// Empty 无数据的命令
+type Empty struct{}
+
+// HeartBeat 心跳请求数据
+type HeartBeat struct {
+	UserId uint64 `json:"userId"`
+}
+
+type IceCandidate struct {
+	SdpMLineIndex int    `json:"sdpMLineIndex"`
+	Candidate     string `json:"candidate"`
+}
+
+type IceCandidateRequest struct {
+	UserId       uint64       `json:"userId" validate:"required"`
+	IceCandidate IceCandidate `json:"iceCandidate"`
+}
+
+type RTCSdpType struct {
+	Sdp  string `json:"sdp"`
+	Type string `json:"type" enum:"answer,offer,pranswer,rollback"`
+}
+
+type SessionDescriptionRequest struct {
+	UserId             uint64     `json:"userId" validate:"required"`
+	SessionDescription RTCSdpType `json:"sessionDescription"`
+}
+
+type RoomAction struct {
+	Action   string `json:"action" validate:"required" enum:"lock,unlock,checkPassword"`
+	RoomId   uint64 `json:"roomId"`
+	UserId   uint64 `json:"userId"`
+	UserName string `json:"userName"`
+	Password string `json:"password"`
+}
+
+type RoomStatus struct {
+	Action string `json:"action" validate:"required" enum:"video,audio,screen,hand,record,privacy"`
+	RoomId uint64 `json:"roomId"`
+	UserId uint64 `json:"userId"`
+	Status bool   `json:"status"`
+}
+
+type PeerAction struct {
+	Action    string `json:"action" validate:"required"`
+	RoomId    uint64 `json:"roomId"`
+	UserId    uint64 `json:"userId"` // 目标用户, sendToAll 时忽略
+	PeerVideo bool   `json:"peerVideo"`
+	SendToAll bool   `json:"sendToAll"`
+}
+
+type RoleAction struct {
+	Action string `json:"action" validate:"required" enum:"setRole"`
+	UserId uint64 `json:"userId" validate:"required"`
+	Role   string `json:"role" validate:"required" enum:"participant,cohost,host"`
+}
+
+type ModerationRequest struct {
+	UserId uint64 `json:"userId" validate:"required"` // 目标用户
+	Reason string `json:"reason"`
+}
+
+type LobbyRequest struct {
+	UserId uint64 `json:"userId" validate:"required"` // 等候中的用户
+	Reason string `json:"reason"`
+}
+
+// ChatRequest 聊天消息, toUserId 不为 0 时为私聊
+type ChatRequest struct {
+	Content  string `json:"content" validate:"required"`
+	ToUserId uint64 `json:"toUserId,omitempty"`
+	ReplyTo  uint64 `json:"replyTo,omitempty"` // 回复的消息ID
+}
+
+// WhiteboardRequest 白板操作, draw 时 op 为客户端定义的绘制数据
+type WhiteboardRequest struct {
+	Action string      `json:"action" validate:"required" enum:"draw,undo,clear,lock,unlock"`
+	Op     interface{} `json:"op,omitempty"`
+}
+
+// BreakoutAssignment 将成员分配到分组讨论房间, roomId 为主房间时留在主房间
+type BreakoutAssignment struct {
+	UserId uint64 `json:"userId" validate:"required"`
+	RoomId uint64 `json:"roomId" validate:"required"`
+}
+
+// BreakoutRequest 分组讨论操作
+// create 创建 count 个分组; assign 按 assignments 分配成员, random 为 true 时随机分配其余成员;
+// open 将成员移入分组并在 duration 秒后结束; close 通知倒计时后将全部成员带回主房间
+type BreakoutRequest struct {
+	Action      string                `json:"action" validate:"required" enum:"create,assign,open,close"`
+	Count       int                   `json:"count,omitempty"`
+	Assignments []*BreakoutAssignment `json:"assignments,omitempty"`
+	Random      bool                  `json:"random,omitempty"`
+	Duration    int                   `json:"duration,omitempty"` // 讨论时长(秒), 为 0 时由主持人手动结束
+}
+
+func (a *RoomAction) GetAction() string        { return a.Action }
+func (a *RoomStatus) GetAction() string        { return a.Action }
+func (a *PeerAction) GetAction() string        { return a.Action }
+func (a *RoleAction) GetAction() string        { return a.Action }
+func (a *WhiteboardRequest) GetAction() string { return a.Action }
+func (a *BreakoutRequest) GetAction() string   { return a.Action }
+
+// LoginResponse 登录应答数据
+type LoginResponse struct {
+	RoomId      uint64 `json:"roomId"`
+	UserId      uint64 `json:"userId"`
+	Role        string `json:"role"`
+	Lobby       bool   `json:"lobby"`                 // 是否在等候室中等待准入
+	Version     int    `json:"version"`               // 协商后的协议版本
+	ResumeToken string `json:"resumeToken,omitempty"` // 连接断开后恢复会话的凭证, 进入房间后才有
+}
+
+// ResumeRequest 连接断开后使用 resumeToken 恢复会话
+type ResumeRequest struct {
+	RoomId uint64 `json:"roomId" validate:"required"`
+	UserId uint64 `json:"userId" validate:"required"`
+	Token  string `json:"token" validate:"required"`
+}
+
+// ResumeResponse 恢复会话应答数据, missed 为断开期间错过的房间事件
+type ResumeResponse struct {
+	RoomId      uint64            `json:"roomId"`
+	UserId      uint64            `json:"userId"`
+	Role        string            `json:"role"`
+	Version     int               `json:"version"`
+	ResumeToken string            `json:"resumeToken"` // 新的恢复凭证, 旧凭证已失效
+	Peers       map[uint64]*Peers `json:"peers"`
+	Missed      []*Message        `json:"missed"`
+}
+
+func init() {
+	registerCommand("login", LoginRequest{})
+	registerCommand("resume", ResumeRequest{})
+	registerCommand("ping", Empty{})
+	registerCommand("heartbeat", HeartBeat{})
+
+	registerCommand("relayICE", IceCandidateRequest{})
+	registerCommand("relaySDP", SessionDescriptionRequest{})
+
+	registerCommand("roomAction", RoomAction{})
+	registerCommand("peerAction", PeerAction{})
+	registerCommand("peerStatus", RoomStatus{})
+	registerCommand("roleAction", RoleAction{})
+
+	registerCommand("kick", ModerationRequest{})
+	registerCommand("mute", ModerationRequest{})
+	registerCommand("stopVideo", ModerationRequest{})
+	registerCommand("muteAll", Empty{})
+	registerCommand("ban", ModerationRequest{})
+	registerCommand("unban", ModerationRequest{})
+
+	registerCommand("chat", ChatRequest{})
+	registerCommand("whiteboard", WhiteboardRequest{})
+	registerCommand("startRecording", Empty{})
+	registerCommand("stopRecording", Empty{})
+	registerCommand("breakout", BreakoutRequest{})
+
+	registerCommand("lobbyAdmit", LobbyRequest{})
+	registerCommand("lobbyReject", LobbyRequest{})
+}
@@ -0,0 +1,223 @@
+package protocol
+
+const (
+	EventMessage = "message"
+	EventConnect = "connect"
+	EventExit    = "exit"
+
+	EventServerShutdown = "serverShutdown"
+)
+
+type IceServer struct {
+	Urls       string `json:"urls"`
+	Username   string `json:"username,omitempty"`
+	Credential string `json:"credential,omitempty"`
+}
+
+// CreateRTCPeerConnectionEvent 通知客户端与 userId 建立连接
+type CreateRTCPeerConnectionEvent struct {
+	UserId            uint64            `json:"userId"`
+	Peers             map[uint64]*Peers `json:"peers"`
+	ShouldCreateOffer bool              `json:"shouldCreateOffer"`
+	IceServers        []IceServer       `json:"iceServers"`
+}
+
+type IceCandidateEvent struct {
+	UserId       uint64       `json:"userId"`
+	IceCandidate IceCandidate `json:"iceCandidate"`
+}
+
+type SessionDescriptionEvent struct {
+	UserId             uint64     `json:"userId"`
+	SessionDescription RTCSdpType `json:"sessionDescription"`
+}
+
+type RoomEvent struct {
+	RoomId uint64 `json:"roomId"`
+}
+
+type PeerActionEvent struct {
+	UserId     uint64 `json:"userId"` // 操作者
+	UserName   string `json:"userName"`
+	PeerVideo  bool   `json:"peerVideo"`
+	PeerAction string `json:"peerAction"`
+}
+
+type PeerRoleEvent struct {
+	RoomId uint64 `json:"roomId"`
+	UserId uint64 `json:"userId"`
+	Role   string `json:"role" enum:"participant,cohost,host,owner"`
+}
+
+type KickOutEvent struct {
+	UserId   uint64 `json:"userId"` // 操作者
+	UserName string `json:"userName"`
+	Reason   string `json:"reason"`
+}
+
+type PeerKickedEvent struct {
+	RoomId     uint64 `json:"roomId"`
+	UserId     uint64 `json:"userId"`
+	OperatorId uint64 `json:"operatorId"`
+	Reason     string `json:"reason"`
+}
+
+type LobbyPeerEvent struct {
+	RoomId   uint64 `json:"roomId"`
+	UserId   uint64 `json:"userId"`
+	UserName string `json:"userName"`
+}
+
+type LobbyLeaveEvent struct {
+	RoomId     uint64 `json:"roomId"`
+	UserId     uint64 `json:"userId"`
+	Action     string `json:"action" enum:"leave,admit,reject"`
+	OperatorId uint64 `json:"operatorId,omitempty"`
+}
+
+type LobbyAdmittedEvent struct {
+	RoomId      uint64 `json:"roomId"`
+	ResumeToken string `json:"resumeToken"`
+}
+
+type LobbyRejectedEvent struct {
+	RoomId uint64 `json:"roomId"`
+	Reason string `json:"reason"`
+}
+
+type ExitEvent struct {
+	RoomId  uint64 `json:"roomId"`
+	UserId  uint64 `json:"userId"`
+	Message string `json:"message"`
+}
+
+// ChatEvent 聊天消息, 同时作为 chat 命令的应答数据, createdAt 为毫秒时间戳
+type ChatEvent struct {
+	Id        uint64 `json:"id"`
+	RoomId    uint64 `json:"roomId"`
+	UserId    uint64 `json:"userId"`
+	UserName  string `json:"userName"`
+	ToUserId  uint64 `json:"toUserId,omitempty"`
+	ReplyTo   uint64 `json:"replyTo,omitempty"`
+	Content   string `json:"content"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+// FileSharedEvent 房间内有新的共享文件, url 需要携带访问令牌下载, 仅房间成员可访问
+type FileSharedEvent struct {
+	Id       uint64 `json:"id"`
+	RoomId   uint64 `json:"roomId"`
+	UserId   uint64 `json:"userId"`
+	UserName string `json:"userName"`
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Url      string `json:"url"`
+}
+
+// WhiteboardOp 白板上的一次绘制, seq 在同一成员的绘制中唯一且递增
+type WhiteboardOp struct {
+	Seq    uint64      `json:"seq"`
+	UserId uint64      `json:"userId"`
+	Op     interface{} `json:"op"`
+}
+
+// WhiteboardEvent 白板操作, draw 时 seq 为新绘制的序号, undo 时为撤销的绘制的序号
+type WhiteboardEvent struct {
+	RoomId uint64      `json:"roomId"`
+	UserId uint64      `json:"userId"`
+	Action string      `json:"action" enum:"draw,undo,clear,lock,unlock"`
+	Seq    uint64      `json:"seq,omitempty"`
+	Op     interface{} `json:"op,omitempty"`
+}
+
+// WhiteboardStateEvent 白板当前的全部绘制, 加入房间后发送
+type WhiteboardStateEvent struct {
+	RoomId uint64          `json:"roomId"`
+	Locked bool            `json:"locked"`
+	Ops    []*WhiteboardOp `json:"ops"`
+}
+
+// RecordingEvent 房间开始或停止录制, 客户端据此显示录制提示
+// 加入正在录制的房间时也会收到
+type RecordingEvent struct {
+	RoomId      uint64 `json:"roomId"`
+	RecordingId uint64 `json:"recordingId"`
+	UserId      uint64 `json:"userId"` // 开始录制的主持人
+	UserName    string `json:"userName"`
+	Recording   bool   `json:"recording"`
+	StartedAt   int64  `json:"startedAt"` // 毫秒时间戳
+}
+
+// BreakoutRoom 分组讨论房间及分配到该房间的成员
+type BreakoutRoom struct {
+	Id      uint64   `json:"id"`
+	Name    string   `json:"name"`
+	UserIds []uint64 `json:"userIds"`
+}
+
+// BreakoutStateEvent 主房间的分组讨论状态, 分组或分配变化时发送给主房间和分组内的全部成员
+type BreakoutStateEvent struct {
+	ParentId uint64          `json:"parentId"`
+	Rooms    []*BreakoutRoom `json:"rooms"`
+	Open     bool            `json:"open"`             // 成员是否已移入分组
+	EndsAt   int64           `json:"endsAt,omitempty"` // 讨论结束的毫秒时间戳, 未设置时长时为 0
+}
+
+// BreakoutCountdownEvent 分组讨论即将结束, seconds 秒后全部成员回到主房间
+type BreakoutCountdownEvent struct {
+	ParentId uint64 `json:"parentId"`
+	Seconds  int    `json:"seconds"`
+}
+
+// BreakoutMoveEvent 成员被移动到另一个房间, 客户端应关闭原房间的全部连接
+// 随后按 createRTCPeerConnection 与新房间的成员建立连接
+type BreakoutMoveEvent struct {
+	ParentId uint64 `json:"parentId"`
+	UserId   uint64 `json:"userId"`
+	RoomId   uint64 `json:"roomId"` // 移入的房间
+	RoomName string `json:"roomName"`
+}
+
+// ServerShutdownEvent 节点即将关闭, 客户端应在 retryAfter 毫秒后重新连接
+type ServerShutdownEvent struct {
+	Reconnect  bool   `json:"reconnect"`
+	RetryAfter int64  `json:"retryAfter"`
+	Message    string `json:"message"`
+}
+
+func init() {
+	registerEvent(EventConnect, "")
+	registerEvent(EventMessage, "")
+	registerEvent(EventExit, ExitEvent{})
+
+	registerEvent("createRTCPeerConnection", CreateRTCPeerConnectionEvent{})
+	registerEvent("iceCandidate", IceCandidateEvent{})
+	registerEvent("sessionDescription", SessionDescriptionEvent{})
+
+	registerEvent("roomIsLocked", RoomEvent{})
+	registerEvent("roomAction", RoomAction{})
+	registerEvent("peerAction", PeerActionEvent{})
+	registerEvent("peerStatus", RoomStatus{})
+	registerEvent("peerRole", PeerRoleEvent{})
+
+	registerEvent("kickOut", KickOutEvent{})
+	registerEvent("peerKicked", PeerKickedEvent{})
+
+	registerEvent("lobbyWaiting", RoomEvent{})
+	registerEvent("lobbyAdmitted", LobbyAdmittedEvent{})
+	registerEvent("lobbyRejected", LobbyRejectedEvent{})
+	registerEvent("lobbyJoin", LobbyPeerEvent{})
+	registerEvent("lobbyLeave", LobbyLeaveEvent{})
+
+	registerEvent("chat", ChatEvent{})
+	registerEvent("fileShared", FileSharedEvent{})
+	registerEvent("whiteboard", WhiteboardEvent{})
+	registerEvent("whiteboardState", WhiteboardStateEvent{})
+	registerEvent("recording", RecordingEvent{})
+	registerEvent("breakoutState", BreakoutStateEvent{})
+	registerEvent("breakoutCountdown", BreakoutCountdownEvent{})
+	registerEvent("breakoutMove", BreakoutMoveEvent{})
+
+	registerEvent(EventServerShutdown, ServerShutdownEvent{})
+}
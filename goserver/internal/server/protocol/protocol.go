@@ -0,0 +1,169 @@
+// Package protocol 信令协议定义
+//
+// websocket 上传输的全部命令(客户端 -> 服务端)和事件(服务端 -> 客户端)都在本包中定义,
+// 并据此生成 JSON Schema 文档 schema.json, 供客户端校验。修改协议结构后执行
+//
+//	go test ./internal/server/protocol -run TestSchema -update TestSchema
+//
+// 重新生成文档。不兼容的修改需要提升 Version。
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"io.wandao.meeting/internal/helper"
+)
+
+const (
+	// Version 服务端支持的最高协议版本
+	Version = 1
+	// MinVersion 服务端支持的最低协议版本
+	MinVersion = 1
+)
+
+// ErrUnsupportedVersion 客户端协议版本过低
+var ErrUnsupportedVersion = errors.New("不支持的协议版本")
+
+// NegotiateVersion 协商协议版本, 未声明版本的旧客户端按版本 1 处理,
+// 高于服务端的版本降为服务端支持的最高版本
+func NegotiateVersion(version int) (int, error) {
+	if version == 0 {
+		version = 1
+	}
+	if version < MinVersion {
+		return 0, ErrUnsupportedVersion
+	}
+	if version > Version {
+		version = Version
+	}
+	return version, nil
+}
+
+// Request 客户端请求
+type Request struct {
+	Seq  string      `json:"seq" validate:"required"` // 消息的唯一ID
+	Cmd  string      `json:"cmd" validate:"required"` // 请求命令字
+	Data interface{} `json:"data,omitempty"`          // 数据 json
+}
+
+// Message 服务端推送的事件
+type Message struct {
+	Seq  string      `json:"seq"`            // 消息的唯一ID
+	Cmd  string      `json:"cmd"`            // 事件名
+	From uint64      `json:"from,omitempty"` // 发送者userId
+	Data interface{} `json:"data,omitempty"` // 数据 json
+}
+
+// NewMessage 创建事件
+func NewMessage(cmd string, data interface{}, from uint64) *Message {
+	return &Message{
+		Seq:  helper.GetOrderIDTime(),
+		Cmd:  cmd,
+		From: from,
+		Data: data,
+	}
+}
+
+func (m *Message) String() (messageStr string) {
+	bytes, _ := json.Marshal(m)
+	messageStr = string(bytes)
+	return
+}
+
+// Head 响应数据头, 回传请求的 seq 和 cmd
+type Head struct {
+	Seq      string    `json:"seq"`      // 消息的ID
+	Cmd      string    `json:"cmd"`      // 消息的 cmd 动作
+	Response *Response `json:"response"` // 消息体
+}
+
+// Response 响应数据体
+type Response struct {
+	Code    uint64      `json:"code"`
+	CodeMsg string      `json:"codeMsg"`
+	Data    interface{} `json:"data"` // 数据 json
+}
+
+// NewResponseHead 设置返回消息
+func NewResponseHead(seq string, cmd string, code uint64, codeMsg string, data interface{}) *Head {
+	return &Head{Seq: seq, Cmd: cmd, Response: &Response{Code: code, CodeMsg: codeMsg, Data: data}}
+}
+
+// String to string
+func (h *Head) String() (headStr string) {
+	headBytes, _ := json.Marshal(h)
+	headStr = string(headBytes)
+	return
+}
+
+// definition 命令或事件的数据结构
+type definition struct {
+	name string
+	typ  reflect.Type
+}
+
+var (
+	commands = make(map[string]reflect.Type)
+	events   = make(map[string]reflect.Type)
+)
+
+func registerCommand(cmd string, payload interface{}) {
+	commands[cmd] = reflect.TypeOf(payload)
+}
+
+func registerEvent(event string, payload interface{}) {
+	events[event] = reflect.TypeOf(payload)
+}
+
+func sortedDefinitions(m map[string]reflect.Type) []definition {
+	list := make([]definition, 0, len(m))
+	for name, typ := range m {
+		list = append(list, definition{name: name, typ: typ})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list
+}
+
+// Commands 全部命令名
+func Commands() []string {
+	list := make([]string, 0, len(commands))
+	for _, def := range sortedDefinitions(commands) {
+		list = append(list, def.name)
+	}
+	return list
+}
+
+// IsCommand 是否为协议中定义的命令
+func IsCommand(cmd string) bool {
+	_, ok := commands[cmd]
+	return ok
+}
+
+// DecodeCommand 按命令的数据结构解析并校验请求数据
+// 命令未定义的字段视为无效请求, 避免拼写错误的字段被静默忽略
+func DecodeCommand(c Codec, cmd string, data []byte) (interface{}, error) {
+	typ, ok := commands[cmd]
+	if !ok {
+		return nil, fmt.Errorf("未定义的命令: %s", cmd)
+	}
+	payload := reflect.New(typ)
+	if len(data) > 0 {
+		if err := c.UnmarshalStrict(data, payload.Interface()); err != nil {
+			return nil, errors.Wrapf(err, "解析 %s 数据", cmd)
+		}
+	}
+	if err := Validate(payload.Interface()); err != nil {
+		return nil, err
+	}
+	return payload.Interface(), nil
+}
+
+// ActionPayload 带 action 字段的命令
+type ActionPayload interface {
+	GetAction() string
+}
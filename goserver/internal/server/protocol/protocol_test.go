@@ -0,0 +1,66 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"io.wandao.meeting/internal/utils/testutil"
+)
+
+func TestSchema(t *testing.T) {
+	testutil.AssertGolden(t, "schema.json", testutil.Update(t.Name()), Schema())
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		want    int
+		wantErr error
+	}{
+		{name: "未声明版本", version: 0, want: 1},
+		{name: "当前版本", version: Version, want: Version},
+		{name: "高于服务端版本", version: Version + 1, want: Version},
+		{name: "无效版本", version: -1, wantErr: ErrUnsupportedVersion},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NegotiateVersion(test.version)
+			assert.Equal(t, test.wantErr, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestDecodeCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmd     string
+		data    string
+		wantErr string
+	}{
+		{name: "登录", cmd: "login", data: `{"token":"t","roomId":1,"roomName":"daily","useVideo":true}`},
+		{name: "缺少必填字段", cmd: "login", data: `{"roomId":1}`, wantErr: "字段 token 不能为空"},
+		{name: "字段类型错误", cmd: "login", data: `{"token":"t","roomId":"1"}`, wantErr: "解析 login 数据"},
+		{name: "枚举值无效", cmd: "roleAction", data: `{"action":"setRole","userId":2,"role":"owner"}`, wantErr: `字段 role 的值 "owner" 无效`},
+		{name: "嵌套字段", cmd: "relaySDP", data: `{"userId":2,"sessionDescription":{"type":"bad"}}`, wantErr: "字段 sessionDescription.type"},
+		{name: "未知字段", cmd: "peerAction", data: `{"action":"muteAudio","roomName":"","useVideo":true}`, wantErr: `unknown field "roomName"`},
+		{name: "嵌入结构的字段", cmd: "login", data: `{"token":"t","roomId":1,"userName":"alice","privacyStatus":true}`},
+		{name: "无数据的命令", cmd: "muteAll", data: `null`},
+		{name: "未定义的命令", cmd: "notExist", data: `{}`, wantErr: "未定义的命令"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payload, err := DecodeCommand(JSON, test.cmd, []byte(test.data))
+			if test.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, payload)
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package protocol
+
+import (
+	"reflect"
+)
+
+// Schema 生成协议的 JSON Schema 文档
+// commands、events 分别列出各命令和事件 data 字段的结构, 命令中未列出的字段会被服务端拒绝
+func Schema() map[string]interface{} {
+	gen := &schemaGenerator{definitions: make(map[string]interface{})}
+
+	envelopes := map[string]interface{}{
+		"request":  gen.schemaOf(reflect.TypeOf(Request{})),
+		"message":  gen.schemaOf(reflect.TypeOf(Message{})),
+		"response": gen.schemaOf(reflect.TypeOf(Head{})),
+	}
+	cmds := make(map[string]interface{}, len(commands))
+	for _, def := range sortedDefinitions(commands) {
+		cmds[def.name] = gen.schemaOf(def.typ)
+	}
+	evts := make(map[string]interface{}, len(events))
+	for _, def := range sortedDefinitions(events) {
+		evts[def.name] = gen.schemaOf(def.typ)
+	}
+	responses := map[string]interface{}{
+		"login":          gen.schemaOf(reflect.TypeOf(LoginResponse{})),
+		"resume":         gen.schemaOf(reflect.TypeOf(ResumeResponse{})),
+		"chat":           gen.schemaOf(reflect.TypeOf(ChatEvent{})),
+		"whiteboard":     gen.schemaOf(reflect.TypeOf(WhiteboardEvent{})),
+		"startRecording": gen.schemaOf(reflect.TypeOf(RecordingEvent{})),
+		"stopRecording":  gen.schemaOf(reflect.TypeOf(RecordingEvent{})),
+		"breakout":       gen.schemaOf(reflect.TypeOf(BreakoutStateEvent{})),
+	}
+
+	return map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"title":       "WDMeeting 信令协议",
+		"version":     Version,
+		"minVersion":  MinVersion,
+		"envelopes":   envelopes,
+		"commands":    cmds,
+		"events":      evts,
+		"responses":   responses,
+		"definitions": gen.definitions,
+	}
+}
+
+type schemaGenerator struct {
+	definitions map[string]interface{}
+}
+
+func (g *schemaGenerator) schemaOf(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schemaOf(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schemaOf(t.Elem())}
+	case reflect.Map:
+		schema := map[string]interface{}{"type": "object", "additionalProperties": g.schemaOf(t.Elem())}
+		if t.Key().Kind() != reflect.String {
+			schema["propertyNames"] = map[string]interface{}{"pattern": "^[0-9]+$"}
+		}
+		return schema
+	case reflect.Struct:
+		return g.ref(t)
+	default:
+		// interface{} 等任意类型
+		return map[string]interface{}{}
+	}
+}
+
+// ref 结构体放入 definitions 并返回引用
+func (g *schemaGenerator) ref(t reflect.Type) map[string]interface{} {
+	if _, ok := g.definitions[t.Name()]; !ok {
+		// 先占位, 避免自引用时无限递归
+		g.definitions[t.Name()] = nil
+		g.definitions[t.Name()] = g.structSchema(t)
+	}
+	return map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+	g.collectFields(t, properties, &required)
+
+	schema := map[string]interface{}{"type": "object"}
+	if len(properties) > 0 {
+		schema["properties"] = properties
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (g *schemaGenerator) collectFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+		if isEmbedded(field) {
+			g.collectFields(field.Type, properties, required)
+			continue
+		}
+
+		schema := g.schemaOf(field.Type)
+		if enum := enumValues(field); len(enum) > 0 {
+			schema["enum"] = enum
+		}
+		properties[name] = schema
+		if hasTag(field, "validate", "required") {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package protocol
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Validate 按 validate、enum 标签校验数据
+func Validate(v interface{}) error {
+	return validateValue(reflect.ValueOf(v), "")
+}
+
+func validateValue(v reflect.Value, path string) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, ok := jsonName(field)
+			if !ok {
+				continue
+			}
+			fieldPath := joinPath(path, name)
+			if isEmbedded(field) {
+				fieldPath = path
+			}
+			value := v.Field(i)
+			if hasTag(field, "validate", "required") && value.IsZero() {
+				return fmt.Errorf("字段 %s 不能为空", fieldPath)
+			}
+			if enum := enumValues(field); len(enum) > 0 && value.Kind() == reflect.String && value.Len() > 0 {
+				if !contains(enum, value.String()) {
+					return fmt.Errorf("字段 %s 的值 %q 无效, 可选值: %s", fieldPath, value.String(), strings.Join(enum, ","))
+				}
+			}
+			if err := validateValue(value, fieldPath); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := validateValue(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonName 字段的 json 名称, 忽略的字段返回 false
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// isEmbedded 是否为展开到父结构的嵌入字段
+func isEmbedded(field reflect.StructField) bool {
+	return field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct
+}
+
+func hasTag(field reflect.StructField, key string, value string) bool {
+	for _, v := range strings.Split(field.Tag.Get(key), ",") {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func enumValues(field reflect.StructField) []string {
+	tag := field.Tag.Get("enum")
+	if tag == "" {
+		return nil
+	}
+	return strings.Split(tag, ",")
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+// Package recording 会议录制文件的存储
+package recording
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/db"
+
+	log "unknwon.dev/clog/v2"
+)
+
+// UploadGrace 停止录制后仍接受分片上传的时间, 客户端在停止录制后才能上传最后的分片
+const UploadGrace = 5 * time.Minute
+
+var (
+	ErrNotUploadable = errors.New("录制已结束")
+	ErrChunkOrder    = errors.New("分片序号不连续")
+	ErrTooLarge      = errors.New("录制文件超过大小限制")
+)
+
+// uploadLocks 按录制 ID 分配的上传锁, 保证同一录制的分片按顺序写入
+var uploadLocks [64]sync.Mutex
+
+// Dir 录制文件目录
+func Dir() string {
+	return filepath.Join(conf.Server.AppDataPath, "recordings")
+}
+
+// NewPath 为新的录制生成相对录制目录的存储路径
+func NewPath(roomId uint64) string {
+	return path.Join(strconv.FormatUint(roomId, 10), uuid.NewString())
+}
+
+// FilePath 录制文件的绝对路径
+func FilePath(recording *db.Recording) string {
+	return filepath.Join(Dir(), filepath.FromSlash(recording.Path))
+}
+
+// Uploadable 是否仍接受分片上传, 录制中或停止后 UploadGrace 内可以上传
+func Uploadable(recording *db.Recording, now time.Time) bool {
+	if recording.Status == db.RecordingActive {
+		return true
+	}
+	return recording.EndedAt != nil && now.Sub(*recording.EndedAt) <= UploadGrace
+}
+
+// AppendChunk 将第 seq 个分片追加到录制文件, 返回更新后的录制
+// seq 小于已上传的分片数时视为客户端重试, 不重复写入
+func AppendChunk(ctx context.Context, id uint64, seq int, mimeType string, r io.Reader) (*db.Recording, error) {
+	lock := &uploadLocks[id%uint64(len(uploadLocks))]
+	lock.Lock()
+	defer lock.Unlock()
+
+	recording, err := db.Recordings.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !Uploadable(recording, time.Now()) {
+		return nil, ErrNotUploadable
+	}
+	if seq < recording.Chunks {
+		return recording, nil
+	}
+	if seq > recording.Chunks {
+		return nil, ErrChunkOrder
+	}
+
+	name := FilePath(recording)
+	if err = os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// 从已记录的大小处写入, 覆盖上次失败时写入的部分数据
+	if err = f.Truncate(recording.Size); err != nil {
+		return nil, err
+	}
+	if _, err = f.Seek(recording.Size, io.SeekStart); err != nil {
+		return nil, err
+	}
+	// 多读一个字节用于判断是否超过录制文件的大小限制
+	remaining := max(conf.Recording.MaxSize<<20-recording.Size, 0)
+	n, err := io.Copy(f, io.LimitReader(r, remaining+1))
+	if err == nil && n > remaining {
+		err = ErrTooLarge
+	}
+	if err == nil {
+		err = db.Recordings.AddChunk(ctx, id, seq, n, mimeType)
+	}
+	if err != nil {
+		_ = f.Truncate(recording.Size)
+		return nil, err
+	}
+
+	recording.Chunks++
+	recording.Size += n
+	if seq == 0 {
+		recording.MimeType = mimeType
+	}
+	return recording, nil
+}
+
+// Remove 删除录制文件和录制记录
+func Remove(ctx context.Context, recording *db.Recording) error {
+	if err := os.Remove(FilePath(recording)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return db.Recordings.DeleteByID(ctx, recording.Id)
+}
+
+// CleanExpired 删除停止后超过保留时长的录制, 未设置保留时长时不处理
+// 录制中和仍在 UploadGrace 内接受上传的录制不会被删除
+func CleanExpired(ctx context.Context, now time.Time) (count int, err error) {
+	if conf.Recording.Retention <= 0 {
+		return 0, nil
+	}
+	retention := max(conf.Recording.Retention, UploadGrace)
+	recordings, err := db.Recordings.ListEndedBefore(ctx, now.Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	for _, recording := range recordings {
+		if e := Remove(ctx, recording); e != nil {
+			log.Error("[Recording]删除过期录制失败: %d %v", recording.Id, e)
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,170 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/db"
+)
+
+func TestUploadable(t *testing.T) {
+	now := time.Now()
+	ended := func(d time.Duration) *time.Time {
+		v := now.Add(-d)
+		return &v
+	}
+	tests := []struct {
+		name      string
+		recording *db.Recording
+		expVal    bool
+	}{
+		{name: "recording", recording: &db.Recording{Status: db.RecordingActive}, expVal: true},
+		{name: "just stopped", recording: &db.Recording{Status: db.RecordingStopped, EndedAt: ended(time.Minute)}, expVal: true},
+		{name: "stopped long ago", recording: &db.Recording{Status: db.RecordingStopped, EndedAt: ended(UploadGrace + time.Second)}, expVal: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expVal, Uploadable(test.recording, now))
+		})
+	}
+}
+
+// memRecordings 内存中的录制存储, 只实现上传分片用到的方法
+type memRecordings struct {
+	db.RecordingsStore
+	recordings map[uint64]*db.Recording
+	addErr     error // 不为 nil 时 AddChunk 返回该错误
+}
+
+func (m *memRecordings) GetByID(_ context.Context, id uint64) (*db.Recording, error) {
+	recording, ok := m.recordings[id]
+	if !ok {
+		return nil, errors.New("录制不存在")
+	}
+	copied := *recording
+	return &copied, nil
+}
+
+func (m *memRecordings) AddChunk(_ context.Context, id uint64, seq int, size int64, mimeType string) error {
+	if m.addErr != nil {
+		return m.addErr
+	}
+	recording := m.recordings[id]
+	if recording.Chunks != seq {
+		return ErrChunkOrder
+	}
+	recording.Chunks++
+	recording.Size += size
+	if seq == 0 {
+		recording.MimeType = mimeType
+	}
+	return nil
+}
+
+// failingReader 读出 data 后返回 err, 模拟上传中断
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestAppendChunk(t *testing.T) {
+	conf.SetMockServer(t, conf.ServerOpts{AppDataPath: t.TempDir()})
+	conf.SetMockRecording(t, conf.RecordingOpts{MaxSize: 1})
+	store := &memRecordings{recordings: map[uint64]*db.Recording{
+		1: {Id: 1, RoomId: 101, Status: db.RecordingActive, Path: NewPath(101)},
+	}}
+	before := db.Recordings
+	db.Recordings = store
+	t.Cleanup(func() { db.Recordings = before })
+
+	ctx := context.Background()
+	readFile := func() string {
+		data, err := os.ReadFile(FilePath(store.recordings[1]))
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("按顺序追加分片", func(t *testing.T) {
+		r, err := AppendChunk(ctx, 1, 0, "video/webm", strings.NewReader("ab"))
+		require.NoError(t, err)
+		assert.Equal(t, 1, r.Chunks)
+		assert.Equal(t, int64(2), r.Size)
+		assert.Equal(t, "video/webm", r.MimeType)
+
+		r, err = AppendChunk(ctx, 1, 1, "video/webm", strings.NewReader("cd"))
+		require.NoError(t, err)
+		assert.Equal(t, 2, r.Chunks)
+		assert.Equal(t, "abcd", readFile())
+	})
+
+	t.Run("跳过的分片", func(t *testing.T) {
+		_, err := AppendChunk(ctx, 1, 3, "video/webm", strings.NewReader("x"))
+		assert.ErrorIs(t, err, ErrChunkOrder)
+	})
+
+	t.Run("重试已上传的分片不重复写入", func(t *testing.T) {
+		r, err := AppendChunk(ctx, 1, 1, "video/webm", strings.NewReader("cd"))
+		require.NoError(t, err)
+		assert.Equal(t, 2, r.Chunks)
+		assert.Equal(t, "abcd", readFile())
+	})
+
+	t.Run("写入失败后截断", func(t *testing.T) {
+		_, err := AppendChunk(ctx, 1, 2, "video/webm", &failingReader{data: []byte("ef"), err: io.ErrUnexpectedEOF})
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, "abcd", readFile())
+
+		store.addErr = errors.New("数据库错误")
+		_, err = AppendChunk(ctx, 1, 2, "video/webm", strings.NewReader("ef"))
+		store.addErr = nil
+		assert.Error(t, err)
+		assert.Equal(t, "abcd", readFile())
+
+		r, err := AppendChunk(ctx, 1, 2, "video/webm", strings.NewReader("ef"))
+		require.NoError(t, err)
+		assert.Equal(t, 3, r.Chunks)
+		assert.Equal(t, "abcdef", readFile())
+	})
+
+	t.Run("超过录制文件的大小限制", func(t *testing.T) {
+		_, err := AppendChunk(ctx, 1, 3, "video/webm", bytes.NewReader(make([]byte, 1<<20)))
+		assert.ErrorIs(t, err, ErrTooLarge)
+		assert.Equal(t, "abcdef", readFile())
+		assert.Equal(t, 3, store.recordings[1].Chunks)
+	})
+
+	t.Run("录制已结束", func(t *testing.T) {
+		ended := time.Now().Add(-UploadGrace - time.Second)
+		store.recordings[2] = &db.Recording{Id: 2, RoomId: 101, Status: db.RecordingStopped, EndedAt: &ended, Path: NewPath(101)}
+		_, err := AppendChunk(ctx, 2, 0, "video/webm", strings.NewReader("ab"))
+		assert.ErrorIs(t, err, ErrNotUploadable)
+	})
+}
+
+func TestFilePath(t *testing.T) {
+	conf.SetMockServer(t, conf.ServerOpts{AppDataPath: filepath.FromSlash("/data")})
+
+	name := NewPath(101)
+	assert.True(t, strings.HasPrefix(name, "101/"))
+	assert.Equal(t, filepath.Join("/data", "recordings", "101", strings.TrimPrefix(name, "101/")), FilePath(&db.Recording{Path: name}))
+}
@@ -0,0 +1,184 @@
+// Package rpcclient 调用其它节点的内部 RPC 服务
+package rpcclient
+
+import (
+	"net"
+	"net/rpc/jsonrpc"
+	"time"
+
+	"github.com/pkg/errors"
+	log "unknwon.dev/clog/v2"
+
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/libs/cache"
+	"io.wandao.meeting/internal/server/models"
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+const (
+	// ServiceName 注册的服务名称
+	ServiceName = "Meeting"
+
+	dialTimeout = 3 * time.Second  // 连接超时时间
+	callTimeout = 10 * time.Second // 调用超时时间
+)
+
+// ErrUserOffline 用户不在线, 无法确定所在节点
+var ErrUserOffline = errors.New("用户不在线")
+
+// ErrNoSecret 调用参数未携带集群密钥
+var ErrNoSecret = errors.New("调用参数不支持集群密钥")
+
+// Call 调用指定节点上的方法, 调用参数须嵌入 models.RPCAuth, 调用前填充本节点配置的集群密钥
+func Call(server *models.Server, method string, args interface{}, reply interface{}) (err error) {
+	auth, ok := args.(models.Authenticated)
+	if !ok {
+		return ErrNoSecret
+	}
+	auth.SetSecret(conf.Security.ClusterSecret)
+
+	conn, err := net.DialTimeout("tcp", server.String(), dialTimeout)
+	if err != nil {
+		return errors.Wrapf(err, "连接节点 %s", server)
+	}
+	_ = conn.SetDeadline(time.Now().Add(callTimeout))
+	client := jsonrpc.NewClient(conn)
+	defer func() { _ = client.Close() }()
+
+	if err = client.Call(ServiceName+"."+method, args, reply); err != nil {
+		return errors.Wrapf(err, "调用 %s %s", server, method)
+	}
+	return
+}
+
+// servers 全部在线的节点
+func servers() ([]*models.Server, error) {
+	return cache.GetServerAll(uint64(time.Now().Unix()))
+}
+
+// userServer 用户所在的节点
+func userServer(roomId uint64, userId uint64) (server *models.Server, err error) {
+	userOnline, err := cache.GetUserOnlineInfo(models.GetUserKey(roomId, userId))
+	if err != nil || !userOnline.IsOnline() {
+		return nil, ErrUserOffline
+	}
+	return models.NewServer(userOnline.AppIp, userOnline.AppPort), nil
+}
+
+// GetOnlineUsers 查询全部节点上的房间成员
+func GetOnlineUsers(roomId uint64) (userIds []uint64, err error) {
+	list, err := servers()
+	if err != nil {
+		return
+	}
+	userIds = make([]uint64, 0)
+	for _, server := range list {
+		reply := &models.OnlineUsersReply{}
+		if err := Call(server, "OnlineUsers", &models.RoomArgs{RoomId: roomId}, reply); err != nil {
+			log.Error("[RPC]查询在线用户失败: %v", err)
+			continue
+		}
+		userIds = append(userIds, reply.UserIds...)
+	}
+	return
+}
+
+// GetRoomPeers 查询全部节点上的房间成员信息
+func GetRoomPeers(roomId uint64) (peers map[uint64]*protocol.Peers, err error) {
+	list, err := servers()
+	if err != nil {
+		return
+	}
+	peers = make(map[uint64]*protocol.Peers)
+	for _, server := range list {
+		reply := &models.RoomPeersReply{}
+		if err := Call(server, "RoomPeers", &models.RoomArgs{RoomId: roomId}, reply); err != nil {
+			log.Error("[RPC]查询房间成员失败: %v", err)
+			continue
+		}
+		for userId, peer := range reply.Peers {
+			peers[userId] = peer
+		}
+	}
+	return
+}
+
+// SendMessage 向用户推送消息, UserId 为 0 时发送给全部节点上的房间成员
+func SendMessage(args *models.SendMessageArgs) (sent bool, err error) {
+	if args.UserId > 0 {
+		server, err := userServer(args.RoomId, args.UserId)
+		if err != nil {
+			return false, err
+		}
+		reply := &models.SendMessageReply{}
+		err = Call(server, "SendMessage", args, reply)
+		return reply.Sent, err
+	}
+
+	list, err := servers()
+	if err != nil {
+		return
+	}
+	for _, server := range list {
+		reply := &models.SendMessageReply{}
+		if err := Call(server, "SendMessage", args, reply); err != nil {
+			log.Error("[RPC]推送消息失败: %v", err)
+			continue
+		}
+		sent = sent || reply.Sent
+	}
+	return
+}
+
+// Kick 在用户所在的节点上将其踢出房间
+func Kick(args *models.KickArgs) (kicked bool, err error) {
+	server, err := userServer(args.RoomId, args.UserId)
+	if err != nil {
+		return
+	}
+	reply := &models.KickReply{}
+	err = Call(server, "Kick", args, reply)
+	return reply.Kicked, err
+}
+
+// Breakout 在保存分组讨论的节点上执行分组操作
+func Breakout(server *models.Server, args *models.BreakoutArgs) (reply *models.BreakoutReply, err error) {
+	reply = &models.BreakoutReply{}
+	err = Call(server, "Breakout", args, reply)
+	return
+}
+
+// Moderate 在用户所在的节点上关闭其音频或视频
+func Moderate(args *models.ModerateArgs) (done bool, err error) {
+	server, err := userServer(args.RoomId, args.UserId)
+	if err != nil {
+		return
+	}
+	reply := &models.ModerateReply{}
+	err = Call(server, "Moderate", args, reply)
+	return reply.Done, err
+}
+
+// MuteAll 在 except 以外的全部节点上关闭房间内角色低于操作者的成员的麦克风
+func MuteAll(args *models.ModerateArgs, except *models.Server) (err error) {
+	list, err := servers()
+	if err != nil {
+		return
+	}
+	for _, server := range list {
+		if server.String() == except.String() {
+			continue
+		}
+		if err := Call(server, "Moderate", args, &models.ModerateReply{}); err != nil {
+			log.Error("[RPC]关闭麦克风失败: %v", err)
+		}
+	}
+	return
+}
+
+// Lobby 在等候中的用户所在的节点上准入或拒绝
+func Lobby(server *models.Server, args *models.LobbyArgs) (code uint64, err error) {
+	reply := &models.LobbyReply{}
+	err = Call(server, "Lobby", args, reply)
+	return reply.Code, err
+}
@@ -0,0 +1,150 @@
+// Package rpcserver 内部 RPC 服务, 供其它节点和后端服务查询与操作本节点上的连接
+package rpcserver
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+
+	"github.com/pkg/errors"
+	log "unknwon.dev/clog/v2"
+
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/server/models"
+	"io.wandao.meeting/internal/server/rpcclient"
+	"io.wandao.meeting/internal/server/websocket"
+)
+
+var listener net.Listener
+
+// ErrUnauthorized 调用未携带正确的集群密钥
+var ErrUnauthorized = errors.New("集群密钥无效")
+
+// authorize 校验调用携带的集群密钥, 未配置密钥时拒绝全部调用
+func authorize(args models.Authenticated) error {
+	secret := conf.Security.ClusterSecret
+	if secret == "" || subtle.ConstantTimeCompare([]byte(args.GetSecret()), []byte(secret)) != 1 {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// Meeting 对外提供的 RPC 方法, 只处理本节点上的连接
+type Meeting struct{}
+
+// OnlineUsers 本节点上的房间成员
+func (m *Meeting) OnlineUsers(args *models.RoomArgs, reply *models.OnlineUsersReply) error {
+	if err := authorize(args); err != nil {
+		return err
+	}
+	reply.UserIds = websocket.LocalUserList(args.RoomId)
+	return nil
+}
+
+// RoomPeers 本节点上的房间成员信息
+func (m *Meeting) RoomPeers(args *models.RoomArgs, reply *models.RoomPeersReply) error {
+	if err := authorize(args); err != nil {
+		return err
+	}
+	reply.Peers = websocket.LocalRoomPeers(args.RoomId)
+	return nil
+}
+
+// SendMessage 向本节点上的用户或房间推送消息
+func (m *Meeting) SendMessage(args *models.SendMessageArgs, reply *models.SendMessageReply) error {
+	if err := authorize(args); err != nil {
+		return err
+	}
+	reply.Sent = websocket.SendLocalMessage(args.RoomId, args.UserId, args.Cmd, args.Data)
+	return nil
+}
+
+// Kick 将本节点上的用户踢出房间
+func (m *Meeting) Kick(args *models.KickArgs, reply *models.KickReply) error {
+	if err := authorize(args); err != nil {
+		return err
+	}
+	reply.Kicked = websocket.KickUser(args.RoomId, args.UserId, args.OperatorId, args.OperatorName, args.Reason)
+	return nil
+}
+
+// Moderate 关闭本节点上成员的音频或视频, UserId 为 0 时关闭角色低于操作者的全部成员的麦克风
+func (m *Meeting) Moderate(args *models.ModerateArgs, reply *models.ModerateReply) error {
+	if err := authorize(args); err != nil {
+		return err
+	}
+	if args.UserId == 0 {
+		websocket.MuteLocal(args.RoomId, args.OperatorId, args.OperatorName, args.OperatorRole)
+		reply.Done = true
+		return nil
+	}
+	reply.Done = websocket.ModerateUser(args.RoomId, args.UserId, args.OperatorId, args.OperatorName, args.Action)
+	return nil
+}
+
+// Lobby 准入或拒绝本节点上等候室中的用户
+func (m *Meeting) Lobby(args *models.LobbyArgs, reply *models.LobbyReply) error {
+	if err := authorize(args); err != nil {
+		return err
+	}
+	reply.Code = websocket.HandleLobby(args.RoomId, args.UserId, args.OperatorId, args.Action, args.Reason)
+	return nil
+}
+
+// Breakout 执行由本节点保存的分组讨论操作
+func (m *Meeting) Breakout(args *models.BreakoutArgs, reply *models.BreakoutReply) error {
+	if err := authorize(args); err != nil {
+		return err
+	}
+	if args.Request == nil {
+		return errors.New("缺少分组操作")
+	}
+	reply.Code, reply.Msg, reply.Data = websocket.HandleBreakout(args.RoomId, args.UserId, args.Request)
+	return nil
+}
+
+// NewServer 创建注册了 Meeting 服务的 RPC 服务
+func NewServer() (server *rpc.Server, err error) {
+	server = rpc.NewServer()
+	err = server.RegisterName(rpcclient.ServiceName, new(Meeting))
+	return
+}
+
+// Serve 在 listener 上接受连接, 使用 JSON-RPC 编码以便其它语言的后端服务调用
+// listener 关闭后返回
+func Serve(listener net.Listener) error {
+	server, err := NewServer()
+	if err != nil {
+		return err
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+// Start 在 host:port 上启动服务, 服务只应绑定内网地址
+// 未配置 CLUSTER_SECRET 时不启动
+func Start(host string, port string) (err error) {
+	if conf.Security.ClusterSecret == "" {
+		return errors.New("未设置 CLUSTER_SECRET")
+	}
+	listener, err = net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return
+	}
+	log.Trace("RPC Listen on: %s", listener.Addr())
+	go func() { _ = Serve(listener) }()
+	return
+}
+
+// Stop 停止接受新的 RPC 连接
+func Stop() {
+	if listener != nil {
+		_ = listener.Close()
+	}
+}
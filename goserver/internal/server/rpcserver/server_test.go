@@ -0,0 +1,98 @@
+package rpcserver
+
+import (
+	"net"
+	"net/rpc/jsonrpc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/server/models"
+	"io.wandao.meeting/internal/server/protocol"
+	"io.wandao.meeting/internal/server/rpcclient"
+)
+
+func TestServe(t *testing.T) {
+	conf.SetMockSecurity(t, conf.SecurityOpts{ClusterSecret: "secret"})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() { done <- Serve(listener) }()
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	server := models.NewServer(host, port)
+
+	t.Run("查询在线用户", func(t *testing.T) {
+		reply := &models.OnlineUsersReply{}
+		require.NoError(t, rpcclient.Call(server, "OnlineUsers", &models.RoomArgs{RoomId: 101}, reply))
+		assert.Empty(t, reply.UserIds)
+	})
+
+	t.Run("查询房间成员", func(t *testing.T) {
+		reply := &models.RoomPeersReply{}
+		require.NoError(t, rpcclient.Call(server, "RoomPeers", &models.RoomArgs{RoomId: 101}, reply))
+		assert.Empty(t, reply.Peers)
+	})
+
+	t.Run("推送给不在本节点的用户", func(t *testing.T) {
+		reply := &models.SendMessageReply{}
+		args := &models.SendMessageArgs{RoomId: 101, UserId: 1, Cmd: "msg", Data: "hello"}
+		require.NoError(t, rpcclient.Call(server, "SendMessage", args, reply))
+		assert.False(t, reply.Sent)
+	})
+
+	t.Run("踢出不在本节点的用户", func(t *testing.T) {
+		reply := &models.KickReply{}
+		require.NoError(t, rpcclient.Call(server, "Kick", &models.KickArgs{RoomId: 101, UserId: 1}, reply))
+		assert.False(t, reply.Kicked)
+	})
+
+	t.Run("关闭不在本节点的用户的麦克风", func(t *testing.T) {
+		reply := &models.ModerateReply{}
+		args := &models.ModerateArgs{RoomId: 101, UserId: 1, OperatorId: 2, Action: "audio"}
+		require.NoError(t, rpcclient.Call(server, "Moderate", args, reply))
+		assert.False(t, reply.Done)
+	})
+
+	t.Run("准入不在本节点等候的用户", func(t *testing.T) {
+		code, err := rpcclient.Lobby(server, &models.LobbyArgs{RoomId: 101, UserId: 1, OperatorId: 2, Action: "admit"})
+		require.NoError(t, err)
+		assert.Equal(t, uint64(common.NotUser), code)
+	})
+
+	t.Run("转发的分组操作", func(t *testing.T) {
+		reply, err := rpcclient.Breakout(server, &models.BreakoutArgs{RoomId: 101, UserId: 1, Request: &protocol.BreakoutRequest{Action: "open"}})
+		require.NoError(t, err)
+		assert.Equal(t, uint64(common.NotData), reply.Code)
+		assert.Equal(t, "没有分组讨论", reply.Msg)
+		assert.Nil(t, reply.Data)
+	})
+
+	t.Run("未携带集群密钥的调用被拒绝", func(t *testing.T) {
+		for _, secret := range []string{"", "wrong"} {
+			client, err := jsonrpc.Dial("tcp", server.String())
+			require.NoError(t, err)
+			args := &models.KickArgs{RPCAuth: models.RPCAuth{Secret: secret}, RoomId: 101, UserId: 1}
+			err = client.Call(rpcclient.ServiceName+".Kick", args, &models.KickReply{})
+			assert.EqualError(t, err, ErrUnauthorized.Error())
+			_ = client.Close()
+		}
+	})
+
+	t.Run("未定义的方法", func(t *testing.T) {
+		err := rpcclient.Call(server, "NotExist", &models.RoomArgs{}, &models.RoomPeersReply{})
+		assert.Error(t, err)
+	})
+
+	require.NoError(t, listener.Close())
+	assert.Error(t, <-done)
+}
+
+func TestStart(t *testing.T) {
+	conf.SetMockSecurity(t, conf.SecurityOpts{})
+	assert.Error(t, Start("127.0.0.1", "0"), "未设置集群密钥时不启动")
+}
@@ -1,12 +1,18 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"io.wandao.meeting/internal/conf"
 	"io.wandao.meeting/internal/db"
 	"io.wandao.meeting/internal/libs/redislib"
 	"io.wandao.meeting/internal/router"
+	"io.wandao.meeting/internal/server/rpcserver"
 	"io.wandao.meeting/internal/server/task"
 	"io.wandao.meeting/internal/server/websocket"
 	log "unknwon.dev/clog/v2"
@@ -39,8 +45,66 @@ func Start() {
 	task.Init()
 	// 服务注册
 	task.ServerInit()
-	go websocket.StartWebRtc("/webrtc")
+	websocket.StartWebRtc("/webrtc")
+	startRPC()
 
-	httpPort := conf.Server.HTTPPort
-	_ = http.ListenAndServe(":"+httpPort, r)
+	httpServer := &http.Server{Addr: ":" + conf.Server.HTTPPort, Handler: r}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("HTTP Listen on :%s failed: %v", conf.Server.HTTPPort, err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+	log.Info("Received %s, shutting down within %s", sig, conf.Server.ShutdownTimeout)
+	shutdown(httpServer)
+}
+
+// startRPC 启动节点间的内部 RPC 服务, 只在集群模式下需要
+// 集群模式下未设置 CLUSTER_SECRET 时拒绝启动, 单节点时跳过
+func startRPC() {
+	if conf.Security.ClusterSecret == "" {
+		if websocket.ClusterEnabled() {
+			log.Fatal("已启用集群, 请设置 [security] CLUSTER_SECRET")
+		}
+		log.Warn("未设置 CLUSTER_SECRET, 不启动内部 RPC 服务, 仅支持单节点部署")
+		return
+	}
+	// 内部 RPC 只监听内网地址, 默认为注册到 Redis 的本节点 IP
+	rpcAddr := conf.Server.RPCAddr
+	if rpcAddr == "" {
+		rpcAddr = websocket.GetServer().Ip
+	}
+	if rpcAddr == "" {
+		log.Fatal("无法确定本节点 IP, 请设置 RPC_ADDR")
+	}
+	if err := rpcserver.Start(rpcAddr, conf.Server.RPCPort); err != nil {
+		log.Fatal("RPC Listen on %s:%s failed: %v", rpcAddr, conf.Server.RPCPort, err)
+	}
+}
+
+// shutdown 先从 Redis 中下线本节点, 再断开连接并释放资源, 整体不超过 SHUTDOWN_TIMEOUT
+func shutdown(httpServer *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), conf.Server.ShutdownTimeout)
+	defer cancel()
+
+	// 其它节点和后端服务不再把请求路由到本节点
+	task.ServerStop()
+	rpcserver.Stop()
+	if err := websocket.Shutdown(ctx); err != nil {
+		log.Warn("WebRTC shutdown: %v", err)
+	}
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Warn("HTTP shutdown: %v", err)
+	}
+	if err := redislib.Close(); err != nil {
+		log.Warn("Redis close: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		log.Warn("Database close: %v", err)
+	}
+	log.Info("Server stopped")
+	log.Stop()
 }
@@ -3,6 +3,7 @@ package task
 
 import (
 	"runtime/debug"
+	"sync/atomic"
 	"time"
 
 	"io.wandao.meeting/internal/libs/cache"
@@ -11,13 +12,25 @@ import (
 	log "unknwon.dev/clog/v2"
 )
 
+// serverStopped 服务已下线, 不再注册
+var serverStopped atomic.Bool
+
 // ServerInit 服务初始化
 func ServerInit() {
 	Timer(2*time.Second, 60*time.Second, server, "", serverDefer, "")
 }
 
+// ServerStop 立即从 Redis 中下线服务, 定时注册随后停止
+func ServerStop() {
+	serverStopped.Store(true)
+	serverDefer("stop")
+}
+
 // server 服务注册
 func server(param interface{}) (result bool) {
+	if serverStopped.Load() {
+		return false
+	}
 	result = true
 	defer func() {
 		if r := recover(); r != nil {
@@ -2,10 +2,13 @@
 package task
 
 import (
+	"context"
 	"fmt"
 	"runtime/debug"
 	"time"
 
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/server/recording"
 	"io.wandao.meeting/internal/server/websocket"
 
 	log "unknwon.dev/clog/v2"
@@ -13,7 +16,10 @@ import (
 
 // Init 初始化
 func Init() {
-	Timer(3*time.Second, 30*time.Second, cleanConnection, "", nil, nil)
+	Timer(3*time.Second, conf.WebSocket.CleanInterval, cleanConnection, "", nil, nil)
+	if conf.Recording.Retention > 0 {
+		Timer(time.Minute, conf.Recording.CleanInterval, cleanRecordings, "", nil, nil)
+	}
 }
 
 // cleanConnection 清理超时连接
@@ -28,3 +34,20 @@ func cleanConnection(param interface{}) (result bool) {
 	websocket.ClearTimeoutConnections()
 	return
 }
+
+// cleanRecordings 删除超过保留时长的录制
+func cleanRecordings(param interface{}) (result bool) {
+	result = true
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("[Task]清理过期录制失败 %v, %s", r, string(debug.Stack()))
+		}
+	}()
+	count, err := recording.CleanExpired(context.Background(), time.Now())
+	if err != nil {
+		log.Error("[Task]清理过期录制失败 %v", err)
+		return
+	}
+	log.Trace("[Task]定时任务，清理过期录制 %d", count)
+	return
+}
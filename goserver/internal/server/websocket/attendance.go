@@ -0,0 +1,51 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"context"
+	"time"
+
+	"io.wandao.meeting/internal/db"
+
+	log "unknwon.dev/clog/v2"
+)
+
+// recordJoin 记录成员进入房间, 房间的第一名成员进入时开始新的会议记录
+// 在事件循环内调用, 由 persist 在后台写入
+func recordJoin(roomId uint64, userId uint64, userName string) {
+	if db.MeetingSessions == nil {
+		return
+	}
+	at := time.Now()
+	persist(func() {
+		err := db.MeetingSessions.Join(context.Background(), roomId, userId, userName, GetServer().String(), at)
+		if err != nil {
+			log.Error("[Attendance]记录进入房间失败: %d|%d %v", roomId, userId, err)
+		}
+	})
+}
+
+// recordLeave 记录成员离开房间, 最后一名成员离开时结束会议记录
+func recordLeave(roomId uint64, userId uint64) {
+	if db.MeetingSessions == nil {
+		return
+	}
+	at := time.Now()
+	persist(func() {
+		err := db.MeetingSessions.Leave(context.Background(), roomId, userId, GetServer().String(), at)
+		if err != nil {
+			log.Error("[Attendance]记录离开房间失败: %d|%d %v", roomId, userId, err)
+		}
+	})
+}
+
+// closeStaleAttendance 启动时结束本节点上次异常退出遗留的参会记录
+// 其它节点上的成员不受影响, 会议中仍有成员时不结束会议
+func closeStaleAttendance() {
+	if db.MeetingSessions == nil {
+		return
+	}
+	if err := db.MeetingSessions.CloseNode(context.Background(), GetServer().String(), time.Now()); err != nil {
+		log.Error("[Attendance]结束遗留的会议记录失败: %v", err)
+	}
+}
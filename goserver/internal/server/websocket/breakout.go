@@ -0,0 +1,710 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/libs/cache"
+	"io.wandao.meeting/internal/libs/redislib"
+	"io.wandao.meeting/internal/server/models"
+	"io.wandao.meeting/internal/server/protocol"
+	"io.wandao.meeting/internal/server/rpcclient"
+
+	log "unknwon.dev/clog/v2"
+)
+
+const (
+	maxBreakoutRooms    = 50               // 最多分组数
+	minBreakoutDuration = time.Minute      // 设置讨论时长时的最短时长
+	maxBreakoutDuration = 4 * time.Hour    // 最长讨论时长
+	breakoutCountdown   = 30 * time.Second // 结束前的倒计时
+)
+
+// breakout 主房间的分组讨论, 保存在创建分组的节点上
+// 其它节点收到的分组操作转发到保存的节点执行, 其它节点上的成员通过 breakoutMove 消息在所在节点上移动
+type breakout struct {
+	parentId   uint64
+	parentName string
+	rooms      []*protocol.BreakoutRoom
+	open       bool      // 成员是否已移入分组
+	closing    bool      // 是否已开始结束倒计时
+	endsAt     time.Time // 讨论结束时间, 未设置时长时为零值
+	warnTimer  *time.Timer
+	endTimer   *time.Timer
+}
+
+// move 将连接移动到另一个房间, 由 clientManager 的事件循环处理
+type move struct {
+	client *Client
+	event  *protocol.BreakoutMoveEvent
+}
+
+var (
+	// breakouts 按主房间 ID 保存的分组讨论, 同时保证分组操作按顺序执行
+	breakouts     = make(map[uint64]*breakout)
+	breakoutsLock sync.Mutex
+
+	// breakoutStates 主房间和分组房间当前的分组讨论状态, 按房间 ID 索引
+	// 事件循环读取时不持有 breakoutsLock, 持有 breakoutsLock 时可以向事件循环投递移动
+	breakoutStates     = make(map[uint64]*protocol.BreakoutStateEvent)
+	breakoutStatesLock sync.RWMutex
+)
+
+// BreakoutController 主持人创建分组、分配成员、开始和结束分组讨论
+// 在主房间或分组内均可操作, 应答数据为操作后的分组状态
+// 分组讨论由其它节点保存时转发到该节点执行
+func BreakoutController(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
+	code = common.OK
+	if !client.IsLogin() {
+		code = common.NotLoggedIn
+		return
+	}
+	request, ok := payload.(*protocol.BreakoutRequest)
+	if !ok {
+		code = common.ParameterIllegal
+		return
+	}
+
+	owner, err := breakoutOwner(client.GetRoomId(), request.Action == "create")
+	if err != nil {
+		log.Error("[WebSocket] 查询分组讨论所在节点失败: %s %d %v", seq, client.GetRoomId(), err)
+		code = common.ServerError
+		return
+	}
+	var state *protocol.BreakoutStateEvent
+	if owner != nil {
+		reply, err := rpcclient.Breakout(owner, &models.BreakoutArgs{RoomId: client.GetRoomId(), UserId: client.GetUserId(), Request: request})
+		if err != nil {
+			log.Error("[WebSocket] 转发分组操作失败: %s %d %v", seq, client.GetRoomId(), err)
+			code = common.ServerError
+			return
+		}
+		code, msg, state = reply.Code, reply.Msg, reply.Data
+	} else {
+		code, msg, state = HandleBreakout(client.GetRoomId(), client.GetUserId(), request)
+	}
+	if state != nil {
+		data = state
+	}
+	return
+}
+
+// HandleBreakout 在本节点执行分组操作, roomId 为操作者所在的房间
+// 由 BreakoutController 和其它节点转发的 RPC 调用
+func HandleBreakout(roomId uint64, userId uint64, request *protocol.BreakoutRequest) (code uint64, msg string, data *protocol.BreakoutStateEvent) {
+	breakoutsLock.Lock()
+	defer breakoutsLock.Unlock()
+
+	var b *breakout
+	if request.Action == "create" {
+		b, code, msg = createBreakout(roomId, userId, request.Count)
+	} else if b = findBreakout(roomId); b == nil {
+		code = common.NotData
+		msg = "没有分组讨论"
+	} else {
+		code = common.OK
+	}
+	if code != common.OK {
+		return
+	}
+	switch request.Action {
+	case "assign":
+		code, msg = b.assign(userId, request.Assignments, request.Random)
+	case "open":
+		code, msg = b.start(time.Duration(request.Duration) * time.Second)
+	case "close":
+		code, msg = b.close()
+	}
+	if code != common.OK {
+		return
+	}
+	data = b.state()
+	log.Info("[WebSocket] 分组讨论: %d|%d %s, %d", roomId, userId, request.Action, b.parentId)
+	return
+}
+
+// breakoutOwner 保存房间所属分组讨论的其它节点, 由本节点处理时返回 nil
+// claim 为 true 时没有节点保存则由本节点保存; 保存的节点已下线时清理其遗留的分组房间
+func breakoutOwner(roomId uint64, claim bool) (owner *models.Server, err error) {
+	if redislib.GetClient() == nil {
+		return
+	}
+	room, err := db.Rooms.GetByID(context.Background(), roomId)
+	if err != nil {
+		return nil, err
+	}
+	parentId := room.Id
+	if room.ParentId > 0 {
+		parentId = room.ParentId
+	}
+
+	self := GetServer()
+	if claim {
+		owner, err = cache.ClaimBreakoutOwner(parentId, self)
+	} else {
+		owner, err = cache.GetBreakoutOwner(parentId)
+	}
+	if err != nil || owner == nil || IsLocal(owner) {
+		return nil, err
+	}
+	if serverAlive(owner) {
+		return
+	}
+
+	log.Warn("[WebSocket] 分组讨论所在节点已下线: %d %s", parentId, owner)
+	rooms, err := db.Rooms.ListByParent(context.Background(), parentId)
+	if err != nil {
+		return nil, err
+	}
+	closeBreakoutRooms(parentId, rooms)
+	_ = cache.DelBreakoutOwner(parentId, owner)
+	if !claim {
+		return nil, nil
+	}
+	if owner, err = cache.ClaimBreakoutOwner(parentId, self); err != nil || IsLocal(owner) {
+		return nil, err
+	}
+	return
+}
+
+// serverAlive 节点是否在线
+func serverAlive(server *models.Server) bool {
+	servers, err := cache.GetServerAll(uint64(time.Now().Unix()))
+	if err != nil {
+		return false
+	}
+	for _, s := range servers {
+		if s.String() == server.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// createBreakout 为操作者所在的主房间创建 count 个分组, 已有的分组房间按顺序复用
+func createBreakout(roomId uint64, userId uint64, count int) (b *breakout, code uint64, msg string) {
+	code = common.OK
+	if count <= 0 || count > maxBreakoutRooms {
+		return nil, common.ParameterIllegal, fmt.Sprintf("分组数须为1-%d", maxBreakoutRooms)
+	}
+	if old := findBreakout(roomId); old != nil {
+		if old.open {
+			return nil, common.OperationFailure, "分组讨论进行中"
+		}
+		if old.parentId != roomId {
+			return nil, common.OperationFailure, "请在主房间内创建分组"
+		}
+	}
+
+	ctx := context.Background()
+	parent, err := db.Rooms.GetByID(ctx, roomId)
+	if err != nil {
+		return nil, common.NotRoom, ""
+	}
+	if parent.ParentId > 0 {
+		return nil, common.OperationFailure, "请在主房间内创建分组"
+	}
+	existing, err := db.Rooms.ListByParent(ctx, parent.Id)
+	if err != nil {
+		log.Error("[WebSocket] 查询分组房间失败: %d %v", parent.Id, err)
+		return nil, common.ServerError, ""
+	}
+
+	b = &breakout{parentId: parent.Id, parentName: parent.Name, rooms: make([]*protocol.BreakoutRoom, 0, count)}
+	for i := 0; i < count; i++ {
+		var room *db.Room
+		if i < len(existing) {
+			room = existing[i]
+			if room.Closed {
+				if err = db.Rooms.SetClosed(ctx, room.Id, false); err != nil {
+					log.Error("[WebSocket] 开启分组房间失败: %d %v", room.Id, err)
+					return nil, common.ModelStoreError, ""
+				}
+			}
+		} else {
+			room, err = db.Rooms.Create(ctx, &db.Room{
+				UserId:   parent.UserId,
+				Name:     fmt.Sprintf("%s-分组%d", parent.Name, i+1),
+				ParentId: parent.Id,
+			})
+			if db.IsErrRoomAlreadyExist(err) {
+				return nil, common.RoomExists, err.Error()
+			}
+			if err != nil {
+				log.Error("[WebSocket] 创建分组房间失败: %d %v", parent.Id, err)
+				return nil, common.ModelAddError, ""
+			}
+		}
+		b.rooms = append(b.rooms, &protocol.BreakoutRoom{Id: room.Id, Name: room.Name, UserIds: make([]uint64, 0)})
+	}
+	for _, room := range existing[min(count, len(existing)):] {
+		if room.Closed {
+			continue
+		}
+		if err = db.Rooms.SetClosed(ctx, room.Id, true); err != nil {
+			log.Error("[WebSocket] 结束分组房间失败: %d %v", room.Id, err)
+		}
+	}
+
+	if old := breakouts[b.parentId]; old != nil {
+		old.unpublish()
+	}
+	breakouts[b.parentId] = b
+	b.sendState(userId, userId)
+	return
+}
+
+// findBreakout 查找房间所属的分组讨论, roomId 可以是主房间或分组房间
+func findBreakout(roomId uint64) *breakout {
+	if b, ok := breakouts[roomId]; ok {
+		return b
+	}
+	for _, b := range breakouts {
+		if b.room(roomId) != nil {
+			return b
+		}
+	}
+	return nil
+}
+
+// room 获取分组房间, 不是本次讨论的分组时返回 nil
+func (b *breakout) room(roomId uint64) *protocol.BreakoutRoom {
+	for _, room := range b.rooms {
+		if room.Id == roomId {
+			return room
+		}
+	}
+	return nil
+}
+
+// state 分组讨论状态, 返回副本
+func (b *breakout) state() *protocol.BreakoutStateEvent {
+	event := &protocol.BreakoutStateEvent{
+		ParentId: b.parentId,
+		Rooms:    make([]*protocol.BreakoutRoom, 0, len(b.rooms)),
+		Open:     b.open,
+	}
+	for _, room := range b.rooms {
+		event.Rooms = append(event.Rooms, &protocol.BreakoutRoom{
+			Id:      room.Id,
+			Name:    room.Name,
+			UserIds: append(make([]uint64, 0, len(room.UserIds)), room.UserIds...),
+		})
+	}
+	if !b.endsAt.IsZero() {
+		event.EndsAt = b.endsAt.UnixMilli()
+	}
+	return event
+}
+
+// roomIds 主房间和全部分组房间的 ID
+func (b *breakout) roomIds() []uint64 {
+	roomIds := make([]uint64, 0, len(b.rooms)+1)
+	roomIds = append(roomIds, b.parentId)
+	for _, room := range b.rooms {
+		roomIds = append(roomIds, room.Id)
+	}
+	return roomIds
+}
+
+// publish 更新主房间和分组房间的状态索引, 连接 Redis 时同时保存到 Redis 供其它节点读取
+func (b *breakout) publish() {
+	state := b.state()
+	breakoutStatesLock.Lock()
+	for _, roomId := range b.roomIds() {
+		breakoutStates[roomId] = state
+	}
+	breakoutStatesLock.Unlock()
+	if redislib.GetClient() != nil {
+		_ = cache.SetBreakoutState(b.roomIds(), state)
+	}
+}
+
+// unpublish 清除主房间和分组房间的状态索引
+func (b *breakout) unpublish() {
+	breakoutStatesLock.Lock()
+	for _, roomId := range b.roomIds() {
+		delete(breakoutStates, roomId)
+	}
+	breakoutStatesLock.Unlock()
+	if redislib.GetClient() != nil {
+		_ = cache.DelBreakoutState(b.roomIds())
+	}
+}
+
+// sendState 更新状态索引并向主房间和全部分组内的成员(除了 exceptUserId)发送分组讨论状态
+func (b *breakout) sendState(from uint64, exceptUserId uint64) {
+	b.publish()
+	b.broadcast(protocol.NewMessage("breakoutState", b.state(), from), exceptUserId)
+}
+
+// broadcast 向主房间和全部分组内的成员(除了 exceptUserId)发送消息, 包括其它节点上的成员
+func (b *breakout) broadcast(message *protocol.Message, exceptUserId uint64) {
+	for _, roomId := range b.roomIds() {
+		if room := clientManager.GetRoom(roomId); room != nil {
+			room.Broadcast(message, room.GetClient(exceptUserId))
+		}
+		if cluster != nil {
+			cluster.PublishRoom(roomId, exceptUserId, message)
+		}
+	}
+}
+
+// assign 分配成员, 讨论进行中时立即将成员移动到分配的房间
+// random 为 true 时将主房间内未分配的成员随机分配到人数最少的分组, 不包括操作者和联席主持人以上角色
+func (b *breakout) assign(operatorId uint64, assignments []*protocol.BreakoutAssignment, random bool) (code uint64, msg string) {
+	code = common.OK
+	for _, a := range assignments {
+		if a.RoomId != b.parentId && b.room(a.RoomId) == nil {
+			return common.InvalidRoomId, fmt.Sprintf("不是本次讨论的分组(%d)", a.RoomId)
+		}
+		if b.location(a.UserId) == 0 {
+			return common.NotUser, fmt.Sprintf("成员不在房间内(%d)", a.UserId)
+		}
+	}
+
+	changed := make([]uint64, 0, len(assignments))
+	for _, a := range assignments {
+		b.assignUser(a.UserId, a.RoomId)
+		changed = append(changed, a.UserId)
+	}
+	if random {
+		candidates := make([]uint64, 0)
+		for userId, peer := range clientManager.GetRoomPeers(b.parentId) {
+			if userId == operatorId || db.RoomRole(peer.Role).AtLeast(db.RoleCoHost) || b.assigned(userId) != nil {
+				continue
+			}
+			candidates = append(candidates, userId)
+		}
+		rand.Shuffle(len(candidates), func(i, j int) {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		})
+		assignBalanced(b.rooms, candidates)
+		changed = append(changed, candidates...)
+	}
+
+	if b.open {
+		for _, userId := range changed {
+			target := b.parentId
+			if room := b.assigned(userId); room != nil {
+				target = room.Id
+			}
+			b.move(b.location(userId), userId, target)
+		}
+	}
+	b.sendState(operatorId, operatorId)
+	return
+}
+
+// assignUser 将成员分配到分组, roomId 为主房间时取消分配
+func (b *breakout) assignUser(userId uint64, roomId uint64) {
+	if room := b.assigned(userId); room != nil {
+		for i, id := range room.UserIds {
+			if id == userId {
+				room.UserIds = append(room.UserIds[:i], room.UserIds[i+1:]...)
+				break
+			}
+		}
+	}
+	if room := b.room(roomId); room != nil {
+		room.UserIds = append(room.UserIds, userId)
+	}
+}
+
+// assigned 成员分配到的分组, 未分配时返回 nil
+func (b *breakout) assigned(userId uint64) *protocol.BreakoutRoom {
+	for _, room := range b.rooms {
+		for _, id := range room.UserIds {
+			if id == userId {
+				return room
+			}
+		}
+	}
+	return nil
+}
+
+// location 成员当前所在的主房间或分组房间, 不在任一房间内时返回 0
+func (b *breakout) location(userId uint64) uint64 {
+	if InRoom(b.parentId, userId) {
+		return b.parentId
+	}
+	for _, room := range b.rooms {
+		if InRoom(room.Id, userId) {
+			return room.Id
+		}
+	}
+	return 0
+}
+
+// assignBalanced 依次将成员分配到人数最少的分组, 人数相同时分配到靠前的分组
+func assignBalanced(rooms []*protocol.BreakoutRoom, userIds []uint64) {
+	if len(rooms) == 0 {
+		return
+	}
+	for _, userId := range userIds {
+		target := rooms[0]
+		for _, room := range rooms[1:] {
+			if len(room.UserIds) < len(target.UserIds) {
+				target = room
+			}
+		}
+		target.UserIds = append(target.UserIds, userId)
+	}
+}
+
+// start 将主房间内已分配的成员移入分组, duration 大于 0 时到时自动结束
+func (b *breakout) start(duration time.Duration) (code uint64, msg string) {
+	code = common.OK
+	if b.open {
+		return common.OperationFailure, "分组讨论进行中"
+	}
+	if duration != 0 && (duration < minBreakoutDuration || duration > maxBreakoutDuration) {
+		return common.ParameterIllegal, fmt.Sprintf("讨论时长须为%d-%d分钟", minBreakoutDuration/time.Minute, maxBreakoutDuration/time.Minute)
+	}
+
+	b.open = true
+	if duration > 0 {
+		b.schedule(time.Now().Add(duration))
+	}
+	for _, room := range b.rooms {
+		for _, userId := range room.UserIds {
+			if InRoom(b.parentId, userId) {
+				b.move(b.parentId, userId, room.Id)
+			}
+		}
+	}
+	b.sendState(0, 0)
+	return
+}
+
+// close 通知全部成员倒计时后回到主房间, 已在倒计时中时不重复通知
+func (b *breakout) close() (code uint64, msg string) {
+	code = common.OK
+	if !b.open {
+		return common.OperationFailure, "分组讨论未开始"
+	}
+	if b.closing {
+		return
+	}
+	b.schedule(time.Now().Add(breakoutCountdown))
+	return
+}
+
+// schedule 设置讨论结束时间, 结束前 breakoutCountdown 开始倒计时
+func (b *breakout) schedule(endsAt time.Time) {
+	b.stopTimers()
+	b.endsAt = endsAt
+	b.publish()
+	parentId := b.parentId
+	warnAt := time.Until(endsAt) - breakoutCountdown
+	if warnAt <= 0 {
+		b.countdown()
+	} else {
+		b.warnTimer = time.AfterFunc(warnAt, func() {
+			breakoutsLock.Lock()
+			defer breakoutsLock.Unlock()
+			if breakouts[parentId] == b {
+				b.countdown()
+			}
+		})
+	}
+	b.endTimer = time.AfterFunc(time.Until(endsAt), func() {
+		endBreakout(parentId, b)
+	})
+}
+
+// countdown 通知全部成员讨论即将结束
+func (b *breakout) countdown() {
+	b.closing = true
+	seconds := int((time.Until(b.endsAt) + time.Second/2) / time.Second)
+	b.broadcast(protocol.NewMessage("breakoutCountdown", &protocol.BreakoutCountdownEvent{
+		ParentId: b.parentId,
+		Seconds:  seconds,
+	}, 0), 0)
+}
+
+func (b *breakout) stopTimers() {
+	if b.warnTimer != nil {
+		b.warnTimer.Stop()
+	}
+	if b.endTimer != nil {
+		b.endTimer.Stop()
+	}
+}
+
+// endBreakout 结束分组讨论并释放对主房间分组讨论的保存
+func endBreakout(parentId uint64, b *breakout) {
+	breakoutsLock.Lock()
+	defer breakoutsLock.Unlock()
+	if breakouts[parentId] != b {
+		return
+	}
+	delete(breakouts, parentId)
+	b.stopTimers()
+	b.end()
+	if redislib.GetClient() != nil {
+		_ = cache.DelBreakoutOwner(parentId, GetServer())
+	}
+}
+
+// end 将分组内的全部成员带回主房间并结束分组房间, 调用时持有 breakoutsLock
+// 分组房间不删除, 其聊天、文件、白板和录制等数据仍归属于主房间的所有者
+func (b *breakout) end() {
+	b.unpublish()
+	for _, room := range b.rooms {
+		for _, userId := range clientManager.GetUserList(room.Id) {
+			b.move(room.Id, userId, b.parentId)
+		}
+	}
+	event := &protocol.BreakoutStateEvent{ParentId: b.parentId, Rooms: make([]*protocol.BreakoutRoom, 0)}
+	b.broadcast(protocol.NewMessage("breakoutState", event, 0), 0)
+
+	if db.Rooms == nil {
+		return
+	}
+	for _, room := range b.rooms {
+		if err := db.Rooms.SetClosed(context.Background(), room.Id, true); err != nil {
+			log.Error("[WebSocket] 结束分组房间失败: %d %v", room.Id, err)
+		}
+	}
+	log.Info("[WebSocket] 分组讨论结束: %d", b.parentId)
+}
+
+// closeBreakoutRooms 结束已没有节点保存的分组讨论, 如节点重启或下线后遗留的分组房间
+func closeBreakoutRooms(parentId uint64, rooms []*db.Room) {
+	b := &breakout{parentId: parentId, rooms: make([]*protocol.BreakoutRoom, 0, len(rooms))}
+	if parent, err := db.Rooms.GetByID(context.Background(), parentId); err == nil {
+		b.parentName = parent.Name
+	}
+	for _, room := range rooms {
+		b.rooms = append(b.rooms, &protocol.BreakoutRoom{Id: room.Id, Name: room.Name, UserIds: make([]uint64, 0)})
+	}
+
+	breakoutsLock.Lock()
+	defer breakoutsLock.Unlock()
+	b.end()
+}
+
+// closeStaleBreakouts 启动时结束遗留的分组讨论, 由其它在线节点保存的分组讨论不做处理
+// 本节点重启前保存的分组讨论已随进程丢失, 一并结束
+func closeStaleBreakouts() {
+	if db.Rooms == nil {
+		return
+	}
+	rooms, err := db.Rooms.ListBreakouts(context.Background())
+	if err != nil {
+		log.Error("[WebSocket] 查询遗留的分组房间失败: %v", err)
+		return
+	}
+	groups := make(map[uint64][]*db.Room)
+	for _, room := range rooms {
+		groups[room.ParentId] = append(groups[room.ParentId], room)
+	}
+	for parentId, children := range groups {
+		if redislib.GetClient() != nil {
+			owner, err := cache.GetBreakoutOwner(parentId)
+			if err != nil || (owner != nil && !IsLocal(owner) && serverAlive(owner)) {
+				continue
+			}
+			if owner != nil {
+				_ = cache.DelBreakoutOwner(parentId, owner)
+			}
+		}
+		closeBreakoutRooms(parentId, children)
+	}
+}
+
+// move 将成员从 fromRoomId 移动到主房间或分组房间, 成员不在本节点时由所在节点移动
+func (b *breakout) move(fromRoomId uint64, userId uint64, toRoomId uint64) {
+	if fromRoomId == 0 || fromRoomId == toRoomId {
+		return
+	}
+	event := &protocol.BreakoutMoveEvent{ParentId: b.parentId, UserId: userId, RoomId: toRoomId, RoomName: b.parentName}
+	if room := b.room(toRoomId); room != nil {
+		event.RoomName = room.Name
+	}
+	if client := clientManager.GetUserClient(fromRoomId, userId); client != nil {
+		clientManager.Move <- &move{client: client, event: event}
+		return
+	}
+	sendToUser(fromRoomId, userId, protocol.NewMessage("breakoutMove", event, 0))
+}
+
+// moveClient 将本节点上的连接移动到 event 指定的房间, 只在事件循环内调用
+// 客户端收到 breakoutMove 后关闭原房间的连接, 原房间成员收到离开通知, 再与新房间的成员重新建立连接
+// 挂起的会话不移动, 恢复后由主持人重新分配
+func (manager *ClientManager) moveClient(client *Client, event *protocol.BreakoutMoveEvent) (ok bool) {
+	from := client.GetRoomId()
+	if from == event.RoomId {
+		return
+	}
+	peer := manager.GetPeer(from, client.GetUserId())
+	if peer == nil {
+		return
+	}
+	client.SendMessage("breakoutMove", event)
+	if !manager.LeaveRoom(client) {
+		return
+	}
+	manager.sendRoomIdAll(protocol.NewMessage(protocol.EventExit, &protocol.ExitEvent{
+		RoomId:  from,
+		UserId:  client.GetUserId(),
+		Message: "用户已进入其它房间",
+	}, 0), from, client)
+
+	// 已断开的连接不再加入新房间, 否则没有事件能将其移出
+	if !manager.InClient(client) {
+		return
+	}
+	currentTime := uint64(time.Now().Unix())
+	fromKey := client.GetKey()
+	client.Login(event.RoomId, client.GetUserId(), client.GetRole(), currentTime)
+	moveUserOnline(client, fromKey, currentTime)
+
+	peer.RoomId = event.RoomId
+	peer.RoomName = event.RoomName
+	manager.enterRoom(&login{
+		RoomId: event.RoomId,
+		UserId: client.GetUserId(),
+		Role:   client.GetRole(),
+		Client: client,
+		Peers:  peer,
+	})
+	log.Info("[WebSocket] 成员移动房间: %d|%d->%d", client.GetUserId(), from, event.RoomId)
+	return true
+}
+
+// moveUserOnline 将 Redis 中的在线信息从原房间移到新房间, 未连接 Redis 时不处理
+func moveUserOnline(client *Client, fromKey string, currentTime uint64) {
+	if redislib.GetClient() == nil {
+		return
+	}
+	if userOnline, err := cache.GetUserOnlineInfo(fromKey); err == nil {
+		userOnline.LogOut()
+		_ = cache.SetUserOnlineInfo(fromKey, userOnline)
+	}
+	userOnline := models.UserLogin(serverIp, serverPort, client.GetRoomId(), client.GetUserId(), client.Addr, currentTime)
+	if err := cache.SetUserOnlineInfo(client.GetKey(), userOnline); err != nil {
+		log.Error("[WebSocket] 更新在线信息失败: %d|%d %v", client.GetRoomId(), client.GetUserId(), err)
+	}
+}
+
+// sendBreakoutState 向加入主房间或分组房间的成员发送分组讨论状态
+// 分组讨论由其它节点保存时从 Redis 读取
+func sendBreakoutState(client *Client) {
+	breakoutStatesLock.RLock()
+	state := breakoutStates[client.GetRoomId()]
+	breakoutStatesLock.RUnlock()
+	if state == nil && redislib.GetClient() != nil {
+		state, _ = cache.GetBreakoutState(client.GetRoomId())
+	}
+	if state != nil {
+		client.SendMessage("breakoutState", state)
+	}
+}
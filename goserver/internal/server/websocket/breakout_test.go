@@ -0,0 +1,203 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+func TestAssignBalanced(t *testing.T) {
+	rooms := []*protocol.BreakoutRoom{
+		{Id: 1, UserIds: []uint64{10, 11}},
+		{Id: 2, UserIds: []uint64{}},
+		{Id: 3, UserIds: []uint64{12}},
+	}
+	assignBalanced(rooms, []uint64{1, 2, 3, 4, 5})
+	assert.Equal(t, []uint64{10, 11, 4}, rooms[0].UserIds)
+	assert.Equal(t, []uint64{1, 2, 5}, rooms[1].UserIds)
+	assert.Equal(t, []uint64{12, 3}, rooms[2].UserIds)
+
+	assignBalanced(nil, []uint64{1})
+}
+
+func TestBreakout_assignUser(t *testing.T) {
+	b := &breakout{
+		parentId: 300,
+		rooms: []*protocol.BreakoutRoom{
+			{Id: 301, UserIds: []uint64{}},
+			{Id: 302, UserIds: []uint64{}},
+		},
+	}
+	b.assignUser(1, 301)
+	b.assignUser(2, 301)
+	b.assignUser(1, 302)
+	assert.Equal(t, []uint64{2}, b.rooms[0].UserIds)
+	assert.Equal(t, uint64(302), b.assigned(1).Id)
+
+	// 分配到主房间即取消分配
+	b.assignUser(2, 300)
+	assert.Nil(t, b.assigned(2))
+
+	state := b.state()
+	assert.Equal(t, uint64(300), state.ParentId)
+	assert.Zero(t, state.EndsAt)
+	require.Len(t, state.Rooms, 2)
+	assert.Equal(t, []uint64{1}, state.Rooms[1].UserIds)
+	state.Rooms[1].UserIds[0] = 9
+	assert.Equal(t, []uint64{1}, b.rooms[1].UserIds, "state 返回副本")
+}
+
+func TestBreakout_move(t *testing.T) {
+	conf.SetMockWebSocket(t, conf.WebSocketOpts{SendQueueSize: 32, SlowConsumer: "drop"})
+	join := func(roomId uint64, userId uint64) *Client {
+		client := NewClient("127.0.0.1:1234", nil, 1, protocol.JSON)
+		client.Login(roomId, userId, db.RoleParticipant, 1)
+		clientManager.AddClients(client)
+		clientManager.enterRoom(&login{
+			RoomId: roomId,
+			UserId: userId,
+			Role:   db.RoleParticipant,
+			Client: client,
+			Peers:  &protocol.Peers{RoomId: roomId, RoomName: "training", UserId: userId, AudioStatus: true},
+		})
+		return client
+	}
+	received := func(client *Client) (cmds []string) {
+		for len(client.Send) > 0 {
+			message := &protocol.Message{}
+			require.NoError(t, protocol.JSON.Unmarshal(<-client.Send, message))
+			cmds = append(cmds, message.Cmd)
+		}
+		return
+	}
+	// 按事件循环的方式处理已投递的移动
+	processMoves := func() {
+		for len(clientManager.Move) > 0 {
+			clientManager.EventMove(<-clientManager.Move)
+		}
+	}
+
+	alice := join(300, 1)
+	bob := join(300, 2)
+	carol := join(300, 3)
+	received(alice)
+	received(bob)
+	received(carol)
+
+	b := &breakout{
+		parentId:   300,
+		parentName: "training",
+		rooms: []*protocol.BreakoutRoom{
+			{Id: 301, Name: "training-分组1", UserIds: []uint64{2}},
+			{Id: 302, Name: "training-分组2", UserIds: []uint64{3}},
+		},
+	}
+	breakoutsLock.Lock()
+	breakouts[b.parentId] = b
+	code, _ := b.start(0)
+	breakoutsLock.Unlock()
+	require.Equal(t, uint64(common.OK), code)
+	processMoves()
+
+	t.Run("开始后成员移入分配的分组", func(t *testing.T) {
+		assert.Equal(t, []uint64{1}, clientManager.GetUserList(300))
+		assert.Equal(t, []uint64{2}, clientManager.GetUserList(301))
+		assert.Equal(t, []uint64{3}, clientManager.GetUserList(302))
+		assert.Equal(t, uint64(301), bob.GetRoomId())
+
+		peer := clientManager.GetPeer(301, 2)
+		require.NotNil(t, peer)
+		assert.Equal(t, "training-分组1", peer.RoomName)
+		assert.True(t, peer.AudioStatus)
+
+		// 状态在移动前发送, 移入分组后再次收到分组讨论状态
+		assert.Equal(t, []string{"breakoutState", "breakoutMove", "whiteboardState", "breakoutState"}, received(bob))
+		assert.Equal(t, []string{"breakoutState", protocol.EventExit, protocol.EventExit}, received(alice))
+		received(carol)
+	})
+
+	t.Run("移入有成员的房间时重新建立连接", func(t *testing.T) {
+		breakoutsLock.Lock()
+		b.move(302, 3, 301)
+		breakoutsLock.Unlock()
+		processMoves()
+		assert.Equal(t, []string{"createRTCPeerConnection"}, received(bob))
+		assert.Equal(t, []string{"breakoutMove", "createRTCPeerConnection", "whiteboardState", "breakoutState"}, received(carol))
+		assert.Nil(t, clientManager.GetRoom(302))
+	})
+
+	t.Run("结束后全部成员回到主房间", func(t *testing.T) {
+		endBreakout(300, b)
+		processMoves()
+		assert.Nil(t, findBreakout(300))
+		assert.ElementsMatch(t, []uint64{1, 2, 3}, clientManager.GetUserList(300))
+		assert.Nil(t, clientManager.GetRoom(301))
+		assert.Equal(t, uint64(300), carol.GetRoomId())
+
+		// 已移动的连接不会重复移动
+		assert.False(t, clientManager.moveClient(carol, &protocol.BreakoutMoveEvent{ParentId: 300, UserId: 3, RoomId: 300}))
+	})
+
+	t.Run("已断开的连接不再移动", func(t *testing.T) {
+		received(carol)
+		clientManager.Move <- &move{client: carol, event: &protocol.BreakoutMoveEvent{ParentId: 300, UserId: 3, RoomId: 301}}
+		clientManager.DelClients(carol)
+		processMoves()
+		assert.Equal(t, uint64(300), carol.GetRoomId())
+		assert.Nil(t, clientManager.GetRoom(301))
+		assert.Empty(t, received(carol))
+	})
+
+	for _, client := range []*Client{alice, bob, carol} {
+		clientManager.LeaveRoom(client)
+		clientManager.DelClients(client)
+	}
+}
+
+func TestBreakout_moveDuringCommand(t *testing.T) {
+	conf.SetMockWebSocket(t, conf.WebSocketOpts{SendQueueSize: 32, SlowConsumer: "drop"})
+	Register("peerStatus", PeerStatus)
+
+	bob := NewClient("127.0.0.1:1234", nil, 1, protocol.JSON)
+	bob.Login(310, 2, db.RoleParticipant, 1)
+	clientManager.AddClients(bob)
+	clientManager.enterRoom(&login{
+		RoomId: 310,
+		UserId: 2,
+		Role:   db.RoleParticipant,
+		Client: bob,
+		Peers:  &protocol.Peers{RoomId: 310, RoomName: "training", UserId: 2},
+	})
+
+	// 读协程处理命令的同时, 事件循环将连接在主房间和分组房间之间移动
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			ProcessData(bob, []byte(`{"seq":"1","cmd":"peerStatus","data":{"action":"audio","status":true}}`))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		roomId := uint64(311)
+		if i%2 == 1 {
+			roomId = 310
+		}
+		clientManager.EventMove(&move{client: bob, event: &protocol.BreakoutMoveEvent{ParentId: 310, UserId: 2, RoomId: roomId}})
+		for len(bob.Send) > 0 {
+			<-bob.Send
+		}
+	}
+	<-done
+
+	assert.Equal(t, uint64(310), bob.GetRoomId())
+	assert.Equal(t, []uint64{2}, clientManager.GetUserList(310))
+	assert.Nil(t, clientManager.GetRoom(311))
+
+	clientManager.LeaveRoom(bob)
+	clientManager.DelClients(bob)
+}
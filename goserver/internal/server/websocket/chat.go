@@ -0,0 +1,87 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/protocol"
+
+	log "unknwon.dev/clog/v2"
+)
+
+// maxChatLength 聊天消息最大长度
+const maxChatLength = 2000
+
+// ChatController 发送聊天消息, 保存后发送给房间内全部成员或私聊的接收者
+func ChatController(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
+	code = common.OK
+	request, ok := payload.(*protocol.ChatRequest)
+	if !ok {
+		code = common.ParameterIllegal
+		return
+	}
+	if !client.IsLogin() {
+		code = common.NotLoggedIn
+		return
+	}
+	content := strings.TrimSpace(request.Content)
+	if content == "" || utf8.RuneCountInString(content) > maxChatLength {
+		code = common.ParameterIllegal
+		msg = "消息内容须为1-2000个字符"
+		return
+	}
+	if request.ToUserId > 0 && (request.ToUserId == client.GetUserId() || !InRoom(client.GetRoomId(), request.ToUserId)) {
+		code = common.NotUser
+		return
+	}
+
+	ctx := context.Background()
+	if request.ReplyTo > 0 {
+		if _, err := db.RoomMessages.GetByID(ctx, client.GetRoomId(), request.ReplyTo); err != nil {
+			code = common.NotData
+			msg = "回复的消息不存在"
+			return
+		}
+	}
+	message := &db.RoomMessage{
+		RoomId:   client.GetRoomId(),
+		UserId:   client.GetUserId(),
+		UserName: operatorName(client),
+		ToUserId: request.ToUserId,
+		ReplyTo:  request.ReplyTo,
+		Content:  content,
+	}
+	if err := db.RoomMessages.Create(ctx, message); err != nil {
+		code = common.ModelAddError
+		log.Error("[WebSocket] 保存聊天消息失败: %s, %v", seq, err)
+		return
+	}
+
+	event := newChatEvent(message)
+	m := protocol.NewMessage("chat", event, client.GetUserId())
+	if request.ToUserId > 0 {
+		sendToUser(client.GetRoomId(), request.ToUserId, m)
+	} else {
+		clientManager.sendRoomIdAll(m, client.GetRoomId(), client)
+	}
+	data = event
+	return
+}
+
+// newChatEvent 聊天消息事件
+func newChatEvent(message *db.RoomMessage) *protocol.ChatEvent {
+	return &protocol.ChatEvent{
+		Id:        message.Id,
+		RoomId:    message.RoomId,
+		UserId:    message.UserId,
+		UserName:  message.UserName,
+		ToUserId:  message.ToUserId,
+		ReplyTo:   message.ReplyTo,
+		Content:   message.Content,
+		CreatedAt: message.CreatedAt.UnixMilli(),
+	}
+}
@@ -0,0 +1,37 @@
+package websocket
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+func TestChatController(t *testing.T) {
+	conf.SetMockWebSocket(t, conf.WebSocketOpts{SendQueueSize: 8, SlowConsumer: "drop"})
+
+	client := NewClient("127.0.0.1:1234", nil, 1, protocol.JSON)
+	code, _, _ := ChatController(client, "1", &protocol.ChatRequest{Content: "hello"})
+	assert.Equal(t, uint64(common.NotLoggedIn), code)
+
+	client.Login(101, 1, db.RoleParticipant, 1)
+	for _, tc := range []struct {
+		name    string
+		request *protocol.ChatRequest
+		code    uint64
+	}{
+		{"内容为空", &protocol.ChatRequest{Content: "   "}, common.ParameterIllegal},
+		{"内容过长", &protocol.ChatRequest{Content: strings.Repeat("长", maxChatLength+1)}, common.ParameterIllegal},
+		{"私聊自己", &protocol.ChatRequest{Content: "hello", ToUserId: 1}, common.NotUser},
+		{"私聊不在房间内的成员", &protocol.ChatRequest{Content: "hello", ToUserId: 2}, common.NotUser},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			code, _, _ := ChatController(client, "1", tc.request)
+			assert.Equal(t, tc.code, code)
+		})
+	}
+}
@@ -4,25 +4,30 @@ package websocket
 import (
 	"fmt"
 	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	jsoniter "github.com/json-iterator/go"
-	"io.wandao.meeting/internal/helper"
-	"io.wandao.meeting/internal/server/models"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/protocol"
 
 	"github.com/gorilla/websocket"
+	log "unknwon.dev/clog/v2"
 )
 
-const (
-	// 用户连接超时时间
-	heartbeatExpirationTime = 6 * 60
+var (
+	droppedMessages   atomic.Uint64 // 因待发送队列已满丢弃的消息数
+	slowConsumerKicks atomic.Uint64 // 因待发送队列已满断开的连接数
 )
 
 // 用户登录
 type login struct {
 	RoomId uint64
 	UserId uint64
+	Role   db.RoomRole
 	Client *Client
-	Peers  *models.Peers
+	Peers  *protocol.Peers
 }
 
 // GetKey 获取 key
@@ -33,31 +38,44 @@ func (l *login) GetKey() (key string) {
 
 // Client 用户连接
 type Client struct {
-	Addr          string          // 客户端地址
-	Socket        *websocket.Conn // 用户连接
-	Send          chan []byte     // 待发送的数据
-	RoomId        uint64          // 登录的平台ID app/web/ios
-	UserId        uint64          // 用户ID，用户登录以后才有
-	FirstTime     uint64          // 首次连接事件
-	HeartbeatTime uint64          // 用户上次心跳时间
-	LoginTime     uint64          // 登录时间 登录以后才有
+	Addr            string          // 客户端地址
+	Socket          *websocket.Conn // 用户连接
+	Send            chan []byte     // 待发送的数据, 只由 write 读取, 不会被关闭
+	done            chan struct{}   // 连接关闭信号
+	closeOnce       sync.Once       // 保证 done 只关闭一次
+	Dropped         atomic.Uint64   // 丢弃的消息数
+	roomId          uint64          // 所在房间ID, 移动到分组讨论房间时在事件循环内修改
+	userId          uint64          // 用户ID，用户登录以后才有
+	role            db.RoomRole     // 房间角色，用户登录以后才有
+	loginTime       uint64          // 登录时间 登录以后才有
+	loginLock       sync.RWMutex    // 房间、用户、角色和登录时间的读写锁, 事件循环与读协程并发访问
+	FirstTime       uint64          // 首次连接事件
+	heartbeatTime   atomic.Uint64   // 上次收到 heartbeat 或 pong 的时间
+	ProtocolVersion int             // 协商后的协议版本, 登录以后才有
+	codec           protocol.Codec  // 连接建立时按子协议协商的编码方式
+	ResumeToken     string          // 恢复会话的凭证, 进入房间以后才有
+	final           atomic.Bool     // 连接被服务端主动关闭, 断开后不挂起会话
 }
 
 // NewClient 初始化
-func NewClient(addr string, socket *websocket.Conn, firstTime uint64) (client *Client) {
+func NewClient(addr string, socket *websocket.Conn, firstTime uint64, codec protocol.Codec) (client *Client) {
 	client = &Client{
-		Addr:          addr,
-		Socket:        socket,
-		Send:          make(chan []byte, 100),
-		FirstTime:     firstTime,
-		HeartbeatTime: firstTime,
+		codec:     codec,
+		Addr:      addr,
+		Socket:    socket,
+		Send:      make(chan []byte, conf.WebSocket.SendQueueSize),
+		done:      make(chan struct{}),
+		FirstTime: firstTime,
 	}
+	client.Heartbeat(firstTime)
 	return
 }
 
 // GetKey 获取 key
 func (c *Client) GetKey() (key string) {
-	key = GetUserKey(c.RoomId, c.UserId)
+	c.loginLock.RLock()
+	defer c.loginLock.RUnlock()
+	key = GetUserKey(c.roomId, c.userId)
 	return
 }
 
@@ -65,86 +83,224 @@ func (c *Client) GetKey() (key string) {
 func (c *Client) read() {
 	defer func() {
 		if r := recover(); r != nil {
-			fmt.Println("write stop", string(debug.Stack()), r)
+			fmt.Println("read stop", string(debug.Stack()), r)
 		}
 	}()
 	defer func() {
-		fmt.Println("读取客户端数据 关闭send", c)
-		close(c.Send)
+		fmt.Println("读取客户端数据 关闭连接", c)
+		c.close()
 	}()
+
+	// 收到 pong 或任何消息都会延长读超时, pong 同时视为一次心跳
+	_ = c.Socket.SetReadDeadline(time.Now().Add(conf.WebSocket.PongTimeout))
+	c.Socket.SetPongHandler(func(string) error {
+		c.Heartbeat(uint64(time.Now().Unix()))
+		return c.Socket.SetReadDeadline(time.Now().Add(conf.WebSocket.PongTimeout))
+	})
 	for {
 		_, message, err := c.Socket.ReadMessage()
 		if err != nil {
 			fmt.Println("读取客户端数据 错误", c.Addr, err)
 			return
 		}
+		_ = c.Socket.SetReadDeadline(time.Now().Add(conf.WebSocket.PongTimeout))
 
 		// 处理程序
-		fmt.Println("读取客户端数据 处理:", string(message))
+		fmt.Println("读取客户端数据 处理:", c.codec.Name(), len(message))
 		ProcessData(c, message)
 	}
 }
 
 // 向客户端写数据
 func (c *Client) write() {
+	ticker := time.NewTicker(conf.WebSocket.PingInterval)
 	defer func() {
 		if r := recover(); r != nil {
 			fmt.Println("write stop", string(debug.Stack()), r)
 		}
 	}()
 	defer func() {
+		ticker.Stop()
 		clientManager.Unregister <- c
 		_ = c.Socket.Close()
 		fmt.Println("Client发送数据 defer", c)
 	}()
 	for {
 		select {
-		case message, ok := <-c.Send:
-			if !ok {
-				// 发送数据错误 关闭连接
-				fmt.Println("Client发送数据 关闭连接", c.Addr, "ok", ok)
+		case message := <-c.Send:
+			if err := c.writeMessage(c.codec.FrameType(), message); err != nil {
+				log.Error("[websocket]发送数据失败: %s|%d|%d %v", c.Addr, c.GetRoomId(), c.GetUserId(), err)
+				return
+			}
+		case <-ticker.C:
+			if err := c.writeMessage(websocket.PingMessage, nil); err != nil {
+				log.Error("[websocket]发送 ping 失败: %s|%d|%d %v", c.Addr, c.GetRoomId(), c.GetUserId(), err)
+				return
+			}
+		case <-c.done:
+			// 尽量发出关闭前已入队的数据, 例如 kickOut 通知
+			c.flush()
+			_ = c.writeMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			log.Trace("[websocket]关闭连接: %s|%d|%d", c.Addr, c.GetRoomId(), c.GetUserId())
+			return
+		}
+	}
+}
+
+// writeMessage 带写超时地发送一帧数据
+func (c *Client) writeMessage(messageType int, data []byte) error {
+	_ = c.Socket.SetWriteDeadline(time.Now().Add(conf.WebSocket.WriteTimeout))
+	return c.Socket.WriteMessage(messageType, data)
+}
+
+// flush 发送队列中剩余的数据
+func (c *Client) flush() {
+	for {
+		select {
+		case message := <-c.Send:
+			if err := c.writeMessage(c.codec.FrameType(), message); err != nil {
 				return
 			}
-			_ = c.Socket.WriteMessage(websocket.TextMessage, message)
+		default:
+			return
 		}
 	}
 }
 
-// SendMsg 发送数据
-func (c *Client) SendMsg(msg []byte) {
+// Codec 连接使用的编码方式
+func (c *Client) Codec() protocol.Codec {
+	return c.codec
+}
+
+// SendData 按连接的编码方式序列化后发送
+func (c *Client) SendData(v interface{}) (ok bool) {
 	if c == nil {
 		return
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Println("SendMsg stop:", r, string(debug.Stack()))
-		}
-	}()
-	c.Send <- msg
+	msg, err := c.codec.Marshal(v)
+	if err != nil {
+		log.Error("[websocket]编码数据失败: %s|%s %v", c.Addr, c.codec.Name(), err)
+		return
+	}
+	return c.SendMsg(msg)
 }
 
-// close 关闭客户端连接
+// SendMsg 发送已编码的数据, 连接已关闭或待发送队列已满时返回 false
+// 队列已满时按 conf.WebSocket.SlowConsumer 丢弃消息或断开连接
+func (c *Client) SendMsg(msg []byte) (ok bool) {
+	if c == nil {
+		return
+	}
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+
+	select {
+	case c.Send <- msg:
+		return true
+	default:
+	}
+
+	c.Dropped.Add(1)
+	droppedMessages.Add(1)
+	if conf.WebSocket.SlowConsumer == "disconnect" {
+		slowConsumerKicks.Add(1)
+		log.Warn("[websocket]待发送队列已满 断开连接: %s|%d|%d", c.Addr, c.GetRoomId(), c.GetUserId())
+		c.close()
+	}
+	return
+}
+
+// close 关闭客户端连接, 可重复调用
 func (c *Client) close() {
-	close(c.Send)
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// closeFinal 关闭连接且不保留会话, 用于踢出成员、关闭服务等不应恢复的场景
+func (c *Client) closeFinal() {
+	c.final.Store(true)
+	c.close()
+}
+
+// isFinal 连接是否被主动关闭
+func (c *Client) isFinal() bool {
+	return c.final.Load()
+}
+
+// IsClosed 连接是否已关闭
+func (c *Client) IsClosed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
 }
 
 // Login 用户登录
-func (c *Client) Login(roomId uint64, userId uint64, loginTime uint64) {
-	c.RoomId = roomId
-	c.UserId = userId
-	c.LoginTime = loginTime
+func (c *Client) Login(roomId uint64, userId uint64, role db.RoomRole, loginTime uint64) {
+	c.loginLock.Lock()
+	c.roomId = roomId
+	c.userId = userId
+	c.role = role
+	c.loginTime = loginTime
+	c.loginLock.Unlock()
 	// 登录成功=心跳一次
 	c.Heartbeat(loginTime)
 }
 
-// Heartbeat 用户心跳
+// GetRoomId 所在房间ID, 成员移动到分组讨论房间后改变
+func (c *Client) GetRoomId() uint64 {
+	c.loginLock.RLock()
+	defer c.loginLock.RUnlock()
+	return c.roomId
+}
+
+// GetUserId 用户ID
+func (c *Client) GetUserId() uint64 {
+	c.loginLock.RLock()
+	defer c.loginLock.RUnlock()
+	return c.userId
+}
+
+// LoginTime 登录时间, 未登录时为 0
+func (c *Client) LoginTime() uint64 {
+	c.loginLock.RLock()
+	defer c.loginLock.RUnlock()
+	return c.loginTime
+}
+
+// GetRole 获取房间角色
+func (c *Client) GetRole() db.RoomRole {
+	c.loginLock.RLock()
+	defer c.loginLock.RUnlock()
+	return c.role
+}
+
+// SetRole 更新房间角色
+func (c *Client) SetRole(role db.RoomRole) {
+	c.loginLock.Lock()
+	defer c.loginLock.Unlock()
+	c.role = role
+}
+
+// Heartbeat 用户心跳, heartbeat 命令和 pong 都会更新
 func (c *Client) Heartbeat(currentTime uint64) {
-	c.HeartbeatTime = currentTime
+	c.heartbeatTime.Store(currentTime)
 }
 
-// IsHeartbeatTimeout 心跳超时
+// HeartbeatTime 上次心跳时间
+func (c *Client) HeartbeatTime() uint64 {
+	return c.heartbeatTime.Load()
+}
+
+// IsHeartbeatTimeout 超过 conf.WebSocket.HeartbeatTimeout 未收到心跳
 func (c *Client) IsHeartbeatTimeout(currentTime uint64) (timeout bool) {
-	if c.HeartbeatTime+heartbeatExpirationTime <= currentTime {
+	if c.HeartbeatTime()+uint64(conf.WebSocket.HeartbeatTimeout/time.Second) <= currentTime {
 		timeout = true
 	}
 	return
@@ -153,7 +309,7 @@ func (c *Client) IsHeartbeatTimeout(currentTime uint64) (timeout bool) {
 // IsLogin 是否登录了
 func (c *Client) IsLogin() (isLogin bool) {
 	// 用户登录了
-	if c.LoginTime > 0 {
+	if c.LoginTime() > 0 {
 		isLogin = true
 		return
 	}
@@ -162,28 +318,33 @@ func (c *Client) IsLogin() (isLogin bool) {
 
 // SendMessage 发送数据
 func (c *Client) SendMessage(cmd string, data interface{}) {
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Println("SendMessage stop:", r, string(debug.Stack()))
-		}
-	}()
-
 	if c == nil {
 		return
 	}
 
-	data = &models.Message{
-		Seq:  helper.GetOrderIDTime(),
-		Cmd:  cmd,
-		From: c.UserId,
-		Data: data,
-	}
+	c.SendData(protocol.NewMessage(cmd, data, c.GetUserId()))
+}
 
-	msg, err := jsoniter.Marshal(data)
+// frames 按编码方式缓存同一条消息的编码结果, 广播时每种编码只序列化一次
+type frames struct {
+	message interface{}
+	encoded map[protocol.Codec][]byte
+}
 
-	if err != nil {
-		return
-	}
+func newFrames(message interface{}) *frames {
+	return &frames{message: message, encoded: make(map[protocol.Codec][]byte, 2)}
+}
 
-	c.Send <- msg
+// send 按客户端的编码方式发送消息
+func (f *frames) send(c *Client) (ok bool) {
+	msg, has := f.encoded[c.codec]
+	if !has {
+		var err error
+		if msg, err = c.codec.Marshal(f.message); err != nil {
+			log.Error("[websocket]编码数据失败: %s %v", c.codec.Name(), err)
+			return
+		}
+		f.encoded[c.codec] = msg
+	}
+	return c.SendMsg(msg)
 }
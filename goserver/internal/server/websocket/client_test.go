@@ -0,0 +1,42 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+func TestClient_SendMsg(t *testing.T) {
+	t.Run("队列已满时丢弃消息", func(t *testing.T) {
+		conf.SetMockWebSocket(t, conf.WebSocketOpts{SendQueueSize: 1, SlowConsumer: "drop"})
+		client := NewClient("127.0.0.1:1234", nil, 1, protocol.JSON)
+		before := droppedMessages.Load()
+
+		assert.True(t, client.SendMsg([]byte("1")))
+		assert.False(t, client.SendMsg([]byte("2")))
+		assert.Equal(t, uint64(1), client.Dropped.Load())
+		assert.Equal(t, before+1, droppedMessages.Load())
+		assert.False(t, client.IsClosed())
+	})
+
+	t.Run("队列已满时断开连接", func(t *testing.T) {
+		conf.SetMockWebSocket(t, conf.WebSocketOpts{SendQueueSize: 1, SlowConsumer: "disconnect"})
+		client := NewClient("127.0.0.1:1234", nil, 1, protocol.JSON)
+
+		assert.True(t, client.SendMsg([]byte("1")))
+		assert.False(t, client.SendMsg([]byte("2")))
+		assert.True(t, client.IsClosed())
+	})
+
+	t.Run("关闭后不再入队且可重复关闭", func(t *testing.T) {
+		conf.SetMockWebSocket(t, conf.WebSocketOpts{SendQueueSize: 4, SlowConsumer: "drop"})
+		client := NewClient("127.0.0.1:1234", nil, 1, protocol.JSON)
+		client.close()
+		client.close()
+
+		assert.False(t, client.SendMsg([]byte("1")))
+		assert.Len(t, client.Send, 0)
+	})
+}
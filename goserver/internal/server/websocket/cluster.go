@@ -0,0 +1,265 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	log "unknwon.dev/clog/v2"
+
+	"io.wandao.meeting/internal/libs/cache"
+	"io.wandao.meeting/internal/libs/redislib"
+	"io.wandao.meeting/internal/server/models"
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+const (
+	nodeChannelPrefix = "webrtc:node:" // 发送给指定节点上成员的消息
+	roomChannelPrefix = "webrtc:room:" // 发送给房间内全部成员的消息
+)
+
+// cluster 节点间的信令转发, 未连接 Redis 时为 nil, 消息只在本节点内投递
+var cluster *Cluster
+
+// relayMessage 节点之间转发的消息
+type relayMessage struct {
+	Origin       string            `json:"origin"`                 // 发出消息的节点
+	RoomId       uint64            `json:"roomId"`                 // 房间ID
+	UserId       uint64            `json:"userId,omitempty"`       // 接收消息的成员, 为 0 时发送给房间内全部成员
+	ExceptUserId uint64            `json:"exceptUserId,omitempty"` // 房间广播时不接收消息的成员
+	Hosts        bool              `json:"hosts,omitempty"`        // 只发送给房间内联席主持人及以上角色
+	Takeover     bool              `json:"takeover,omitempty"`     // UserId 已在发出消息的节点上进入房间
+	Message      *protocol.Message `json:"message"`
+}
+
+// Cluster 通过 Redis pub/sub 在多个节点之间转发信令
+// 每个节点订阅自己的节点频道, 以及本节点上有成员的房间频道
+type Cluster struct {
+	node    *models.Server
+	manager *ClientManager
+	client  *redis.Client
+	pubsub  *redis.PubSub
+}
+
+func nodeChannel(server *models.Server) string {
+	return nodeChannelPrefix + server.String()
+}
+
+func roomChannel(roomId uint64) string {
+	return fmt.Sprintf("%s%d", roomChannelPrefix, roomId)
+}
+
+// ClusterEnabled 是否已通过 Redis 与其它节点组成集群
+func ClusterEnabled() bool {
+	return cluster != nil
+}
+
+// startCluster 订阅本节点的频道并开始接收其它节点转发的消息
+func startCluster(node *models.Server, manager *ClientManager) {
+	client := redislib.GetClient()
+	if client == nil {
+		return
+	}
+	ctx := context.Background()
+	pubsub := client.Subscribe(ctx, nodeChannel(node))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		log.Error("[Cluster]订阅节点频道失败, 仅在本节点内投递消息: %s %v", node, err)
+		_ = pubsub.Close()
+		return
+	}
+	cluster = &Cluster{node: node, manager: manager, client: client, pubsub: pubsub}
+	go cluster.run()
+	log.Trace("[Cluster]节点频道: %s", nodeChannel(node))
+}
+
+// run 接收其它节点转发的消息
+func (c *Cluster) run() {
+	for msg := range c.pubsub.Channel() {
+		c.handle([]byte(msg.Payload))
+	}
+}
+
+// Close 取消全部订阅
+func (c *Cluster) Close() {
+	_ = c.pubsub.Close()
+}
+
+// handle 将其它节点转发的消息投递给本节点上的成员
+func (c *Cluster) handle(payload []byte) {
+	relay := &relayMessage{}
+	if err := json.Unmarshal(payload, relay); err != nil || relay.Message == nil {
+		log.Error("[Cluster]解析转发消息失败: %v", err)
+		return
+	}
+	// 房间频道也会收到本节点发出的广播, 本节点的成员已在发出时收到
+	if relay.Origin == c.node.String() {
+		return
+	}
+	if relay.Hosts {
+		notifyLocalHosts(c.manager, relay.RoomId, relay.Message.Cmd, relay.Message.Data)
+		return
+	}
+	if relay.Takeover {
+		c.manager.DropSession(relay.RoomId, relay.UserId)
+		return
+	}
+	if relay.UserId > 0 {
+		client := c.manager.GetUserClient(relay.RoomId, relay.UserId)
+		if relay.Message.Cmd == "breakoutMove" && client != nil {
+			c.applyBreakoutMove(client, relay.Message.Data)
+			return
+		}
+		client.SendData(relay.Message)
+		return
+	}
+	if room := c.manager.GetRoom(relay.RoomId); room != nil {
+		room.Broadcast(relay.Message, room.GetClient(relay.ExceptUserId))
+	}
+}
+
+// applyBreakoutMove 按创建分组讨论的节点的通知移动本节点上的成员
+func (c *Cluster) applyBreakoutMove(client *Client, data interface{}) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	event := &protocol.BreakoutMoveEvent{}
+	if err = json.Unmarshal(raw, event); err != nil {
+		log.Error("[Cluster]解析分组移动失败: %d %v", client.GetUserId(), err)
+		return
+	}
+	c.manager.Move <- &move{client: client, event: event}
+}
+
+// publish 向频道发布消息
+func (c *Cluster) publish(channel string, relay *relayMessage) (err error) {
+	relay.Origin = c.node.String()
+	data, err := json.Marshal(relay)
+	if err != nil {
+		return
+	}
+	err = c.client.Publish(context.Background(), channel, data).Err()
+	if err != nil {
+		log.Error("[Cluster]发布消息失败: %s %s %v", channel, relay.Message.Cmd, err)
+	}
+	return
+}
+
+// SubscribeRoom 本节点上有成员加入房间后订阅房间频道
+func (c *Cluster) SubscribeRoom(roomId uint64) {
+	if err := c.pubsub.Subscribe(context.Background(), roomChannel(roomId)); err != nil {
+		log.Error("[Cluster]订阅房间频道失败: %d %v", roomId, err)
+	}
+}
+
+// UnsubscribeRoom 本节点上的成员全部离开房间后取消订阅
+func (c *Cluster) UnsubscribeRoom(roomId uint64) {
+	if err := c.pubsub.Unsubscribe(context.Background(), roomChannel(roomId)); err != nil {
+		log.Error("[Cluster]取消订阅房间频道失败: %d %v", roomId, err)
+	}
+}
+
+// PublishRoom 向其它节点上的房间成员广播消息
+func (c *Cluster) PublishRoom(roomId uint64, exceptUserId uint64, message *protocol.Message) {
+	_ = c.publish(roomChannel(roomId), &relayMessage{RoomId: roomId, ExceptUserId: exceptUserId, Message: message})
+}
+
+// PublishHosts 向其它节点上房间内的联席主持人及以上角色发送消息
+func (c *Cluster) PublishHosts(roomId uint64, message *protocol.Message) {
+	_ = c.publish(roomChannel(roomId), &relayMessage{RoomId: roomId, Hosts: true, Message: message})
+}
+
+// PublishTakeover 通知其它节点成员已在本节点上进入房间, 结束其在其它节点上挂起的会话
+func (c *Cluster) PublishTakeover(roomId uint64, userId uint64) {
+	_ = c.publish(roomChannel(roomId), &relayMessage{
+		RoomId:   roomId,
+		UserId:   userId,
+		Takeover: true,
+		Message:  protocol.NewMessage("sessionTakeover", nil, 0),
+	})
+}
+
+// SendToUser 向其它节点上的成员发送消息, 成员不在线时返回 false
+func (c *Cluster) SendToUser(roomId uint64, userId uint64, message *protocol.Message) (ok bool) {
+	userOnline, err := cache.GetUserOnlineInfo(GetUserKey(roomId, userId))
+	if err != nil || !userOnline.IsOnline() || userOnline.UserIsLocal(c.node.Ip, c.node.Port) {
+		return
+	}
+	server := models.NewServer(userOnline.AppIp, userOnline.AppPort)
+	return c.publish(nodeChannel(server), &relayMessage{RoomId: roomId, UserId: userId, Message: message}) == nil
+}
+
+// SaveMember 记录本节点上的房间成员
+func (c *Cluster) SaveMember(roomId uint64, peer *protocol.Peers) {
+	if peer == nil {
+		return
+	}
+	_ = cache.SetRoomMember(roomId, models.NewRoomMember(c.node, peer))
+}
+
+// DelMember 删除本节点上的房间成员
+func (c *Cluster) DelMember(roomId uint64, userId uint64) {
+	_ = cache.DelRoomMember(roomId, userId)
+}
+
+// RemotePeers 其它节点上的房间成员, 已下线节点的成员会被忽略
+func (c *Cluster) RemotePeers(roomId uint64) (peers map[uint64]*protocol.Peers) {
+	peers = make(map[uint64]*protocol.Peers)
+	members, err := cache.GetRoomMembers(roomId)
+	if err != nil {
+		return
+	}
+	for userId, member := range c.remoteMembers(members) {
+		peers[userId] = member.Peers
+	}
+	return
+}
+
+// SaveLobby 记录本节点上等候室中的用户, 供其它节点上的主持人查询和准入
+func (c *Cluster) SaveLobby(roomId uint64, peer *protocol.Peers) {
+	if peer == nil {
+		return
+	}
+	_ = cache.SetLobbyMember(roomId, models.NewRoomMember(c.node, peer))
+}
+
+// DelLobby 删除等候室中的用户
+func (c *Cluster) DelLobby(roomId uint64, userId uint64) {
+	_ = cache.DelLobbyMember(roomId, userId)
+}
+
+// RemoteLobby 其它节点上等候室中的用户, 已下线节点的用户会被忽略
+func (c *Cluster) RemoteLobby(roomId uint64) (members map[uint64]*models.RoomMember) {
+	members, err := cache.GetLobbyMembers(roomId)
+	if err != nil {
+		return make(map[uint64]*models.RoomMember)
+	}
+	return c.remoteMembers(members)
+}
+
+// remoteMembers 过滤出其它在线节点上的成员
+func (c *Cluster) remoteMembers(members map[uint64]*models.RoomMember) map[uint64]*models.RoomMember {
+	remote := make(map[uint64]*models.RoomMember)
+	if len(members) == 0 {
+		return remote
+	}
+	servers, err := cache.GetServerAll(uint64(time.Now().Unix()))
+	if err != nil {
+		return remote
+	}
+	alive := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		alive[server.String()] = true
+	}
+	for userId, member := range members {
+		server := member.Server().String()
+		if server == c.node.String() || !alive[server] {
+			continue
+		}
+		remote[userId] = member
+	}
+	return remote
+}
@@ -0,0 +1,117 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/models"
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+func TestCluster_handle(t *testing.T) {
+	conf.SetMockWebSocket(t, conf.WebSocketOpts{SendQueueSize: 8, SlowConsumer: "drop", ResumeGrace: time.Minute})
+	manager := NewClientManager()
+	c := &Cluster{node: models.NewServer("10.0.0.1", "8688"), manager: manager}
+
+	clients := make(map[uint64]*Client)
+	for _, userId := range []uint64{1, 2} {
+		client := NewClient("127.0.0.1:1234", nil, 1, protocol.Msgpack)
+		client.Login(101, userId, db.RoleParticipant, 1)
+		manager.JoinRoom(&login{RoomId: 101, UserId: userId, Client: client, Peers: &protocol.Peers{RoomId: 101, UserId: userId}})
+		clients[userId] = client
+	}
+	relay := func(relay *relayMessage) []byte {
+		data, err := json.Marshal(relay)
+		require.NoError(t, err)
+		return data
+	}
+
+	t.Run("房间广播排除指定成员", func(t *testing.T) {
+		c.handle(relay(&relayMessage{
+			Origin:       "10.0.0.2:8688",
+			RoomId:       101,
+			ExceptUserId: 1,
+			Message:      protocol.NewMessage("peerStatus", &protocol.RoomStatus{Action: "audio", UserId: 1}, 0),
+		}))
+		assert.Len(t, clients[1].Send, 0)
+		require.Len(t, clients[2].Send, 1)
+
+		message := &protocol.Message{}
+		require.NoError(t, protocol.Msgpack.Unmarshal(<-clients[2].Send, message))
+		assert.Equal(t, "peerStatus", message.Cmd)
+		data, ok := message.Data.(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, "audio", data["action"])
+	})
+
+	t.Run("发送给指定成员", func(t *testing.T) {
+		c.handle(relay(&relayMessage{
+			Origin:  "10.0.0.2:8688",
+			RoomId:  101,
+			UserId:  1,
+			Message: protocol.NewMessage("iceCandidate", &protocol.IceCandidateEvent{UserId: 3}, 1),
+		}))
+		assert.Len(t, clients[1].Send, 1)
+		assert.Len(t, clients[2].Send, 0)
+		<-clients[1].Send
+	})
+
+	t.Run("只发送给主持人", func(t *testing.T) {
+		clients[2].SetRole(db.RoleCoHost)
+		defer clients[2].SetRole(db.RoleParticipant)
+		c.handle(relay(&relayMessage{
+			Origin:  "10.0.0.2:8688",
+			RoomId:  101,
+			Hosts:   true,
+			Message: protocol.NewMessage("lobbyJoin", &protocol.LobbyPeerEvent{RoomId: 101, UserId: 3, UserName: "dave"}, 0),
+		}))
+		assert.Len(t, clients[1].Send, 0)
+		require.Len(t, clients[2].Send, 1)
+
+		message := &protocol.Message{}
+		require.NoError(t, protocol.Msgpack.Unmarshal(<-clients[2].Send, message))
+		assert.Equal(t, "lobbyJoin", message.Cmd)
+		data, ok := message.Data.(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, "dave", data["userName"])
+	})
+
+	t.Run("忽略本节点发出的消息", func(t *testing.T) {
+		c.handle(relay(&relayMessage{
+			Origin:  c.node.String(),
+			RoomId:  101,
+			Message: protocol.NewMessage("peerStatus", nil, 0),
+		}))
+		assert.Len(t, clients[1].Send, 0)
+		assert.Len(t, clients[2].Send, 0)
+	})
+
+	t.Run("无效的消息", func(t *testing.T) {
+		c.handle([]byte("{"))
+		c.handle(relay(&relayMessage{Origin: "10.0.0.2:8688", RoomId: 101}))
+		assert.Len(t, clients[1].Send, 0)
+		assert.Len(t, clients[2].Send, 0)
+	})
+
+	t.Run("成员在其它节点进入房间后结束挂起的会话", func(t *testing.T) {
+		issueResumeToken(clients[1])
+		require.True(t, manager.SuspendRoom(clients[1]))
+		c.handle(relay(&relayMessage{
+			Origin:   "10.0.0.2:8688",
+			RoomId:   101,
+			UserId:   1,
+			Takeover: true,
+			Message:  protocol.NewMessage("sessionTakeover", nil, 0),
+		}))
+		assert.Nil(t, manager.GetUserClient(101, 1))
+		assert.Nil(t, manager.ResumeRoom(101, 1, clients[1].ResumeToken, NewClient("127.0.0.1:1234", nil, 1, protocol.Msgpack), 2))
+		// 成员仍在房间内, 不通知离开
+		assert.Len(t, clients[2].Send, 0)
+		assert.NotNil(t, manager.GetUserClient(101, 2))
+	})
+}
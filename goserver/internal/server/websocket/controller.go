@@ -3,15 +3,14 @@ package websocket
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
-	jsoniter "github.com/json-iterator/go"
 	"io.wandao.meeting/internal/db"
-	"io.wandao.meeting/internal/helper"
 	"io.wandao.meeting/internal/server/models"
+	"io.wandao.meeting/internal/server/protocol"
+	"io.wandao.meeting/internal/utils/jwtutil"
 
 	log "unknwon.dev/clog/v2"
 
@@ -21,48 +20,77 @@ import (
 )
 
 // PingController ping
-func PingController(client *Client, seq string, message []byte) (code uint64, msg string, data interface{}) {
+func PingController(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
 	code = common.OK
-	log.Info("[WebSocket] ping: %s, %s, %s", client.Addr, seq, message)
+	log.Info("[WebSocket] ping: %s, %s", client.Addr, seq)
 	data = "pong"
 	return
 }
 
 // LoginController 用户登录
-func LoginController(client *Client, seq string, message []byte) (code uint64, msg string, data interface{}) {
+func LoginController(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
 	code = common.OK
 	currentTime := uint64(time.Now().Unix())
-	request := &models.LoginRequest{}
-	if err := json.Unmarshal(message, request); err != nil {
+	request, ok := payload.(*protocol.LoginRequest)
+	if !ok {
 		code = common.ParameterIllegal
-		log.Error("[WebSocket] LoginController 参数解析失败: %s, %v", seq, err)
 		return
 	}
 
-	if request.UserId <= 0 {
-		code = common.InvalidUserId
-		log.Error("[WebSocket]LoginController: 无效的用户ID。%s, %d", seq, request.UserId)
-		return
-	}
 	if request.RoomId <= 0 {
 		code = common.InvalidRoomId
 		log.Error("[WebSocket]LoginController: 无效的房间ID。%s, %d", seq, request.RoomId)
 		return
 	}
-	if client.IsLogin() {
+	if client.IsLogin() || clientManager.InLobby(client) {
 		log.Error("[WebSocket]LoginController: 用户已登录。(seq:%s, userId:%d, roomId: %d)", seq, request.UserId, request.RoomId)
 		code = common.HasLoggedIn
 		return
 	}
 
-	// TODO::进行用户权限认证，一般是客户端传入TOKEN，然后检验TOKEN是否合法，通过TOKEN解析出来用户ID
-	log.Info("[WebSocket] login token:  %s, %s", seq, request.Token)
+	version, err := protocol.NegotiateVersion(request.Version)
+	if err != nil {
+		code = common.ProtocolVersion
+		log.Error("[WebSocket]LoginController: 不支持的协议版本。(seq:%s, version:%d)", seq, request.Version)
+		return
+	}
+	client.ProtocolVersion = version
+
+	// 用户身份以 TOKEN 中的声明为准，不信任客户端传入的 userId
+	claims, err := jwtutil.AnalyseToken(request.Token)
+	if err != nil || claims == nil {
+		code = common.Unauthorized
+		log.Error("[WebSocket]LoginController: TOKEN 无效或已过期。(seq:%s, err:%v)", seq, err)
+		return
+	}
+	if revoked, err := cache.IsTokenRevoked(claims.ID); err != nil || revoked {
+		code = common.Unauthorized
+		log.Error("[WebSocket]LoginController: TOKEN 已被吊销。(seq:%s, err:%v)", seq, err)
+		return
+	}
+	if request.UserId > 0 && request.UserId != claims.Id {
+		code = common.Unauthorized
+		log.Error("[WebSocket]LoginController: 用户ID与TOKEN不符。(seq:%s, userId:%d, tokenUserId:%d)", seq, request.UserId, claims.Id)
+		return
+	}
+	request.UserId = claims.Id
+	if request.UserId <= 0 {
+		code = common.InvalidUserId
+		log.Error("[WebSocket]LoginController: 无效的用户ID。%s, %d", seq, request.UserId)
+		return
+	}
+
 	user, err := db.Users.GetByID(context.Background(), request.UserId)
 	if err != nil || user == nil {
 		code = common.NotUser
 		log.Error("[WebSocket]LoginController: user does not exist")
 		return
 	}
+	if claims.Version != user.TokenVersion {
+		code = common.Unauthorized
+		log.Error("[WebSocket]LoginController: 修改密码前签发的 TOKEN 已失效。(seq:%s, userId:%d)", seq, request.UserId)
+		return
+	}
 	room, err := db.Rooms.GetByID(context.Background(), request.RoomId)
 	if err != nil || room == nil {
 		code = common.NotRoom
@@ -70,15 +98,91 @@ func LoginController(client *Client, seq string, message []byte) (code uint64, m
 		return
 	}
 
-	peers := &models.Peers{
+	// 分组讨论房间按主房间的角色、禁入名单和入会限制校验
+	gate := room
+	if room.Closed {
+		code = common.NotRoom
+		msg = "分组讨论已结束"
+		log.Error("[WebSocket]LoginController: 分组讨论已结束(seq: %s, roomId: %d)", seq, room.Id)
+		return
+	}
+	if room.ParentId > 0 {
+		gate, err = db.Rooms.GetByID(context.Background(), room.ParentId)
+		if err != nil {
+			code = common.NotRoom
+			log.Error("[WebSocket]LoginController: 主房间不存在(seq: %s, roomId: %d)", seq, room.Id)
+			return
+		}
+	}
+
+	role, err := db.RoomMembers.GetRole(context.Background(), gate.Id, request.UserId)
+	if err != nil {
+		code = common.ServerError
+		log.Error("[WebSocket]LoginController: 获取房间角色失败(seq: %s, err: %v)", seq, err)
+		return
+	}
+
+	banned, err := db.RoomBans.IsBanned(context.Background(), gate.Id, request.UserId)
+	if err != nil {
+		code = common.ServerError
+		log.Error("[WebSocket]LoginController: 查询禁入名单失败(seq: %s, err: %v)", seq, err)
+		return
+	}
+	if banned {
+		code = common.UserBanned
+		log.Error("[WebSocket]LoginController: 用户已被禁止进入房间。(seq:%s, roomId:%d, userId:%d)", seq, room.Id, request.UserId)
+		return
+	}
+
+	// 预约的房间在会议时间外只有组织者可以开启, 开启后其他成员可以加入
+	meetings, err := db.Meetings.ListByRoom(context.Background(), gate.Id)
+	if err != nil {
+		code = common.ServerError
+		log.Error("[WebSocket]LoginController: 查询预约会议失败(seq: %s, err: %v)", seq, err)
+		return
+	}
+	if open, next := meetingWindow(meetings, request.UserId, time.Now()); !open && len(clientManager.GetUserList(gate.Id)) == 0 {
+		code = common.MeetingNotStarted
+		msg = fmt.Sprintf("会议将于 %s 开始", next.Local().Format("2006-01-02 15:04"))
+		log.Error("[WebSocket]LoginController: 会议未开始。(seq:%s, roomId:%d, userId:%d)", seq, room.Id, request.UserId)
+		return
+	}
+
+	// 已在主房间内的成员可以直接进入分组讨论房间
+	admitted := room.ParentId > 0 && InRoom(gate.Id, request.UserId)
+
+	// 联席主持人及以上角色不受锁定、密码和人数限制
+	if !role.AtLeast(db.RoleCoHost) && !admitted {
+		if gate.HasPassword() {
+			if !gate.ValidatePassword(request.RoomPasswd) {
+				code = common.RoomPassError
+				client.SendMessage("roomIsLocked", &protocol.RoomEvent{RoomId: room.Id})
+				log.Error("[WebSocket]LoginController: 房间密码错误。(seq:%s, roomId:%d, userId:%d)", seq, room.Id, request.UserId)
+				return
+			}
+		} else if gate.Locked {
+			code = common.RoomLocked
+			client.SendMessage("roomIsLocked", &protocol.RoomEvent{RoomId: room.Id})
+			log.Error("[WebSocket]LoginController: 房间已锁定。(seq:%s, roomId:%d, userId:%d)", seq, room.Id, request.UserId)
+			return
+		}
+		if gate.MaxPeers > 0 && countMembers(gate) >= gate.MaxPeers {
+			code = common.RoomFull
+			log.Error("[WebSocket]LoginController: 房间人数已满。(seq:%s, roomId:%d, maxPeers:%d)", seq, room.Id, gate.MaxPeers)
+			return
+		}
+	}
+
+	peers := &protocol.Peers{
 		RoomId:     request.RoomId,
 		RoomName:   room.Name,
-		RoomLock:   false,
+		RoomLock:   gate.Locked || gate.HasPassword(),
 		RoomPasswd: "",
 
 		UserId:   request.UserId,
 		UserName: user.Name,
 		UserLock: false,
+		Role:     string(role),
 
 		UseVideo: request.UseVideo,
 		UseAudio: request.UseAudio,
@@ -92,114 +196,231 @@ func LoginController(client *Client, seq string, message []byte) (code uint64, m
 		PrivacyStatus: request.PrivacyStatus,
 	}
 
-	client.Login(request.RoomId, request.UserId, currentTime)
-
-	// 存储数据
-	userOnline := models.UserLogin(serverIp, serverPort, request.RoomId, request.UserId, client.Addr, currentTime)
-	err = cache.SetUserOnlineInfo(client.GetKey(), userOnline)
-	if err != nil {
-		code = common.ServerError
-		log.Error("[WebSocket]LoginController: 数据缓存失败(seq: %s, err: %v)", seq, err)
-		return
-	}
-
-	// 用户登录
 	login := &login{
 		RoomId: request.RoomId,
 		UserId: request.UserId,
+		Role:   role,
 		Client: client,
 		Peers:  peers,
 	}
 
-	clientManager.Login <- login
+	// 启用等候室时, 联席主持人以下的成员需等待主持人准入
+	// 主持人只在主房间内准入, 未进入主房间的成员不能直接进入分组讨论房间
+	if gate.Lobby && !role.AtLeast(db.RoleCoHost) && !admitted {
+		if room.ParentId > 0 {
+			code = common.Unauthorized
+			msg = "请先进入主房间"
+			log.Error("[WebSocket]LoginController: 未经等候室进入分组讨论房间。(seq:%s, roomId:%d, userId:%d)", seq, room.Id, request.UserId)
+			return
+		}
+		enterLobby(login)
+		data = loginResult(login, true)
+		log.Info("[WebSocket]LoginController: 用户进入等候室(seq: %s, IP: %s, userId: %d)", seq, client.Addr, request.UserId)
+		return
+	}
+
+	issueResumeToken(client)
+	if err = loginRoom(login, currentTime); err != nil {
+		code = common.ServerError
+		log.Error("[WebSocket]LoginController: 数据缓存失败(seq: %s, err: %v)", seq, err)
+		return
+	}
+	if role.AtLeast(db.RoleCoHost) {
+		sendLobbyList(client, request.RoomId)
+	}
+	data = loginResult(login, false)
 	log.Info("[WebSocket]LoginController: 用户登录成功(seq: %s, IP: %s, userId: %d)", seq, client.Addr, request.UserId)
 	return
 }
 
+// countMembers 房间内的成员数, 包括房间的分组讨论房间内的成员
+func countMembers(room *db.Room) int {
+	count := len(clientManager.GetUserList(room.Id))
+	children, err := db.Rooms.ListByParent(context.Background(), room.Id)
+	if err != nil {
+		log.Error("[WebSocket] 查询分组房间失败: %d %v", room.Id, err)
+		return count
+	}
+	for _, child := range children {
+		count += len(clientManager.GetUserList(child.Id))
+	}
+	return count
+}
+
+// loginResult 登录应答数据
+func loginResult(l *login, inLobby bool) *protocol.LoginResponse {
+	return &protocol.LoginResponse{
+		RoomId:  l.RoomId,
+		UserId:  l.UserId,
+		Role:    string(l.Role),
+		Lobby:   inLobby,
+		Version: l.Client.ProtocolVersion,
+
+		ResumeToken: l.Client.ResumeToken,
+	}
+}
+
+// ResumeController 连接断开后恢复会话, 不重新通知房间内其他成员
+// 会话只保存在断开前所在的节点上, 多节点部署时需将重连路由到原节点, 恢复失败时客户端需要重新登录
+func ResumeController(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
+	code = common.OK
+	currentTime := uint64(time.Now().Unix())
+	request, ok := payload.(*protocol.ResumeRequest)
+	if !ok {
+		code = common.ParameterIllegal
+		return
+	}
+	if client.IsLogin() || clientManager.InLobby(client) {
+		log.Error("[WebSocket]ResumeController: 用户已登录。(seq:%s, userId:%d, roomId: %d)", seq, request.UserId, request.RoomId)
+		code = common.HasLoggedIn
+		return
+	}
+
+	s := clientManager.ResumeRoom(request.RoomId, request.UserId, request.Token, client, currentTime)
+	if s == nil {
+		log.Error("[WebSocket]ResumeController: 会话不存在或已过期。(seq:%s, userId:%d, roomId: %d)", seq, request.UserId, request.RoomId)
+		code = common.ResumeFailed
+		return
+	}
+	token := issueResumeToken(client)
+
+	userOnline := models.UserLogin(serverIp, serverPort, request.RoomId, request.UserId, client.Addr, currentTime)
+	if err := cache.SetUserOnlineInfo(client.GetKey(), userOnline); err != nil {
+		log.Error("[WebSocket]ResumeController: 数据缓存失败(seq: %s, err: %v)", seq, err)
+	}
+	if client.GetRole().AtLeast(db.RoleCoHost) {
+		sendLobbyList(client, request.RoomId)
+	}
+
+	data = &protocol.ResumeResponse{
+		RoomId:      request.RoomId,
+		UserId:      request.UserId,
+		Role:        string(client.GetRole()),
+		Version:     client.ProtocolVersion,
+		ResumeToken: token,
+		Peers:       clientManager.GetRoomPeers(request.RoomId),
+		Missed:      s.missed,
+	}
+	log.Info("[WebSocket]ResumeController: 会话已恢复(seq: %s, IP: %s, userId: %d, missed: %d)", seq, client.Addr, request.UserId, len(s.missed))
+	return
+}
+
+// loginRoom 登录房间, 缓存在线信息并交由 clientManager 通知房间成员
+func loginRoom(l *login, currentTime uint64) (err error) {
+	client := l.Client
+	client.Login(l.RoomId, l.UserId, l.Role, currentTime)
+
+	// 存储数据
+	userOnline := models.UserLogin(serverIp, serverPort, l.RoomId, l.UserId, client.Addr, currentTime)
+	err = cache.SetUserOnlineInfo(client.GetKey(), userOnline)
+	if err != nil {
+		return
+	}
+
+	// 用户登录
+	clientManager.Login <- l
+	return
+}
+
 // HeartbeatController 心跳接口
-func HeartbeatController(client *Client, seq string, message []byte) (code uint64, msg string, data interface{}) {
+func HeartbeatController(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
 	code = common.OK
 	currentTime := uint64(time.Now().Unix())
-	request := &models.HeartBeat{}
-	if err := json.Unmarshal(message, request); err != nil {
+	if _, ok := payload.(*protocol.HeartBeat); !ok {
 		code = common.ParameterIllegal
-		fmt.Println("心跳接口 解析数据失败", seq, err)
 		return
 	}
-	fmt.Println("webSocket_request 心跳接口", client.RoomId, client.UserId)
+	fmt.Println("webSocket_request 心跳接口", client.GetRoomId(), client.GetUserId())
 	if !client.IsLogin() {
-		fmt.Println("心跳接口 用户未登录", client.RoomId, client.UserId, seq)
+		fmt.Println("心跳接口 用户未登录", client.GetRoomId(), client.GetUserId(), seq)
 		code = common.NotLoggedIn
 		return
 	}
-	userOnline, err := cache.GetUserOnlineInfo(client.GetKey())
-	if err != nil {
+	client.Heartbeat(currentTime)
+	if err := refreshUserOnline(client); err != nil {
 		if errors.Is(err, redis.Nil) {
 			code = common.NotLoggedIn
-			fmt.Println("心跳接口 用户未登录", seq, client.RoomId, client.UserId)
-			return
-		} else {
-			code = common.ServerError
-			fmt.Println("心跳接口 GetUserOnlineInfo", seq, client.RoomId, client.UserId, err)
+			fmt.Println("心跳接口 用户未登录", seq, client.GetRoomId(), client.GetUserId())
 			return
 		}
-	}
-	client.Heartbeat(currentTime)
-	userOnline.Heartbeat(currentTime)
-	err = cache.SetUserOnlineInfo(client.GetKey(), userOnline)
-	if err != nil {
 		code = common.ServerError
-		fmt.Println("心跳接口 SetUserOnlineInfo", seq, client.RoomId, client.UserId, err)
+		fmt.Println("心跳接口 刷新在线状态失败", seq, client.GetRoomId(), client.GetUserId(), err)
 		return
 	}
 	return
 }
 
 // RelayIceCandidate 应答 onIceCandidate 事件
-func RelayIceCandidate(client *Client, seq string, message []byte) (code uint64, msg string, data interface{}) {
+func RelayIceCandidate(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
 	code = common.OK
-	request := &models.IceCandidateRequest{}
-	if err := json.Unmarshal(message, request); err != nil {
+	request, ok := payload.(*protocol.IceCandidateRequest)
+	if !ok {
 		code = common.ParameterIllegal
-		log.Error("[WebSocket] OnIceCandidate 参数解析失败: %s, %v", seq, err)
 		return
 	}
 	client.SendIceCandidate(request)
 	return
 }
 
-func RelaySessionDescription(client *Client, seq string, message []byte) (code uint64, msg string, data interface{}) {
+func RelaySessionDescription(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
 	code = common.OK
-	request := &models.SessionDescriptionRequest{}
-	if err := json.Unmarshal(message, request); err != nil {
+	request, ok := payload.(*protocol.SessionDescriptionRequest)
+	if !ok {
 		code = common.ParameterIllegal
-		log.Error("[WebSocket] OnSessionDescription 参数解析失败: %s, %v", seq, err)
 		return
 	}
 	client.SendSessionDescription(request)
 	return
 }
 
-func RoomAction(client *Client, seq string, message []byte) (code uint64, msg string, data interface{}) {
+func RoomAction(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
 	code = common.OK
-	request := &models.RoomAction{}
-	if err := json.Unmarshal(message, request); err != nil {
+	request, ok := payload.(*protocol.RoomAction)
+	if !ok {
 		code = common.ParameterIllegal
-		log.Error("[WebSocket] RoomAction 参数解析失败: %s, %v", seq, err)
 		return
 	}
-	u := *clientManager.Peers[request.RoomId][request.UserId]
+
+	// 校验密码时尚未登录, 其余操作均以登录的房间为准
+	if client.IsLogin() {
+		request.RoomId = client.GetRoomId()
+		request.UserId = client.GetUserId()
+	} else if request.Action != "checkPassword" {
+		code = common.NotLoggedIn
+		return
+	}
+
+	ctx := context.Background()
+	room, err := db.Rooms.GetByID(ctx, request.RoomId)
+	if err != nil {
+		code = common.NotRoom
+		log.Error("[WebSocket] RoomAction 房间不存在: %s, %d", seq, request.RoomId)
+		return
+	}
+
 	switch request.Action {
-	case "lock":
-		u.RoomLock = true
-		u.RoomPasswd = request.Password
-		relayRoomAction(client, request)
-	case "unlock":
-		u.RoomLock = false
-		u.RoomPasswd = ""
+	case "lock", "unlock":
+		locked := request.Action == "lock"
+		passwd := ""
+		if locked {
+			passwd = request.Password
+		}
+		err = db.Rooms.UpdateSettings(ctx, room.Id, db.UpdateRoomSettingsOptions{
+			Locked: &locked,
+			Passwd: &passwd,
+		})
+		if err != nil {
+			code = common.ModelStoreError
+			log.Error("[WebSocket] RoomAction 保存房间设置失败: %s, %v", seq, err)
+			return
+		}
+		clientManager.SetRoomLock(room.Id, locked)
+
+		// 密码不向其他成员转发
+		request.Password = ""
 		relayRoomAction(client, request)
 	case "checkPassword":
-		if u.RoomPasswd == request.Password {
+		if room.ValidatePassword(request.Password) {
 			request.Password = "OK"
 		} else {
 			request.Password = "KO"
@@ -209,95 +430,128 @@ func RoomAction(client *Client, seq string, message []byte) (code uint64, msg st
 	return
 }
 
-func PeerAction(client *Client, seq string, message []byte) (code uint64, msg string, data interface{}) {
+// PeerAction 成员操作, 转发给指定成员或房间内全体成员
+func PeerAction(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
 	code = common.OK
-	request := &models.RoomAction{}
-	if err := json.Unmarshal(message, request); err != nil {
+	request, ok := payload.(*protocol.PeerAction)
+	if !ok {
 		code = common.ParameterIllegal
-		log.Error("[WebSocket] RoomAction 参数解析失败: %s, %v", seq, err)
+		return
+	}
+	if !client.IsLogin() {
+		code = common.NotLoggedIn
 		return
 	}
 
-	switch request.Action {
-	case "lock":
-		u := *clientManager.Peers[request.RoomId][request.UserId]
-		u.RoomLock = true
-		u.RoomPasswd = request.Password
-		relayRoomAction(client, request)
-	case "unlock":
-		u := *clientManager.Peers[request.RoomId][request.UserId]
-		u.RoomLock = false
-		u.RoomPasswd = ""
-		relayRoomAction(client, request)
+	event := &protocol.PeerActionEvent{
+		UserId:     client.GetUserId(),
+		UserName:   operatorName(client),
+		PeerVideo:  request.PeerVideo,
+		PeerAction: request.Action,
+	}
+
+	if request.SendToAll {
+		d := protocol.NewMessage("peerAction", event, 0)
+		clientManager.sendRoomIdAll(d, client.GetRoomId(), client)
+		return
+	}
+
+	if !sendUserMessage(client.GetRoomId(), request.UserId, "peerAction", event) {
+		code = common.NotUser
 	}
 	return
 }
 
-func PeerStatus(client *Client, seq string, message []byte) (code uint64, msg string, data interface{}) {
+// PeerStatus 成员更新自己的媒体状态
+func PeerStatus(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
 	code = common.OK
-	request := &models.RoomStatus{}
-	if err := json.Unmarshal(message, request); err != nil {
+	request, ok := payload.(*protocol.RoomStatus)
+	if !ok {
 		code = common.ParameterIllegal
-		log.Error("[WebSocket] RoomStatus 参数解析失败: %s, %v", seq, err)
-		return
-	}
-	peers := clientManager.GetRoomPeers(request.RoomId)
-	for _, peer := range peers {
-		if peer.UserId == request.UserId {
-			switch request.Action {
-			case "video":
-				peer.VideoStatus = request.Status
-			case "audio":
-				peer.AudioStatus = request.Status
-			case "screen":
-				peer.ScreenStatus = request.Status
-			case "hand":
-				peer.HandStatus = request.Status
-			case "record":
-				peer.RecordStatus = request.Status
-			case "privacy":
-				peer.PrivacyStatus = request.Status
-			}
-		}
+		return
 	}
+	if !client.IsLogin() {
+		code = common.NotLoggedIn
+		return
+	}
+	// 只能更新自己的状态
+	request.RoomId = client.GetRoomId()
+	request.UserId = client.GetUserId()
 
-	d, err := jsoniter.Marshal(models.SendRequest{
-		Seq:  helper.GetOrderIDTime(),
-		Cmd:  "peerStatus",
-		Data: request,
-	})
-
-	if err != nil {
+	if !clientManager.SetPeerStatus(request.RoomId, request.UserId, request.Action, request.Status) {
+		code = common.ParameterIllegal
 		return
 	}
 
+	d := protocol.NewMessage("peerStatus", request, 0)
+
 	clientManager.sendRoomIdAll(d, request.RoomId, client)
 	return
 }
 
-// relayRoomAction 向房间内全体成员转发 RoomAction 信息
-func relayRoomAction(client *Client, request *models.RoomAction) {
-	msg, err := jsoniter.Marshal(models.SendRequest{
-		Seq:  helper.GetOrderIDTime(),
-		Cmd:  request.Action,
-		Data: request,
-	})
+// RoleAction 调整成员角色, 只能调整角色低于自己的成员, 且不能授予高于自己的角色
+func RoleAction(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
+	code = common.OK
+	request, ok := payload.(*protocol.RoleAction)
+	if !ok {
+		code = common.ParameterIllegal
+		return
+	}
+	role := db.RoomRole(request.Role)
+	if request.Action != "setRole" || !role.IsValid() || role == db.RoleOwner {
+		code = common.ParameterIllegal
+		return
+	}
+	if request.UserId == client.GetUserId() {
+		code = common.OperationFailure
+		return
+	}
+
+	ctx := context.Background()
+	current, err := db.RoomMembers.GetRole(ctx, client.GetRoomId(), request.UserId)
 	if err != nil {
+		code = common.ServerError
+		log.Error("[WebSocket] RoleAction 获取角色失败: %s, %v", seq, err)
 		return
 	}
+	clientRole := client.GetRole()
+	if !clientRole.AtLeast(role) || current.AtLeast(clientRole) {
+		code = common.Unauthorized
+		return
+	}
+
+	changes := map[uint64]db.RoomRole{request.UserId: role}
+	// 主持人移交后, 原主持人降为联席主持人
+	if role == db.RoleHost && clientRole == db.RoleHost {
+		changes[client.GetUserId()] = db.RoleCoHost
+	}
+	for userId, role := range changes {
+		if err = db.RoomMembers.SetRole(ctx, client.GetRoomId(), userId, role); err != nil {
+			code = common.ModelStoreError
+			log.Error("[WebSocket] RoleAction 保存角色失败: %s, %v", seq, err)
+			return
+		}
+		clientManager.SetPeerRole(client.GetRoomId(), userId, role)
+
+		d := protocol.NewMessage("peerRole", &protocol.PeerRoleEvent{
+			RoomId: client.GetRoomId(),
+			UserId: userId,
+			Role:   string(role),
+		}, 0)
+		clientManager.sendRoomIdAll(d, client.GetRoomId(), nil)
+	}
+	return
+}
+
+// relayRoomAction 向房间内全体成员转发 RoomAction 信息
+func relayRoomAction(client *Client, request *protocol.RoomAction) {
+	msg := protocol.NewMessage("roomAction", request, 0)
 	clientManager.sendRoomIdAll(msg, request.RoomId, client)
 }
 
 // relayAction 向指定成员 转发 RoomAction 信息
-func relayAction(client *Client, request *models.RoomAction) {
-	msg, err := jsoniter.Marshal(models.SendRequest{
-		Seq:  helper.GetOrderIDTime(),
-		Cmd:  request.Action,
-		Data: request,
-	})
-	if err != nil {
-		return
-	}
+func relayAction(client *Client, request *protocol.RoomAction) {
+	msg := protocol.NewMessage("roomAction", request, 0)
 
-	client.SendMsg(msg)
+	client.SendData(msg)
 }
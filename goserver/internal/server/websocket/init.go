@@ -2,14 +2,17 @@
 package websocket
 
 import (
+	"errors"
 	"fmt"
 	"io.wandao.meeting/internal/server/models"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"io.wandao.meeting/internal/conf"
 	"io.wandao.meeting/internal/helper"
+	"io.wandao.meeting/internal/server/protocol"
 	log "unknwon.dev/clog/v2"
 )
 
@@ -20,8 +23,10 @@ const (
 var (
 	clientManager = NewClientManager()                     // 管理者
 	roomIds       = []uint64{defaultRoomId, 102, 103, 104} // 房间IDs
-	serverIp      string
-	serverPort    string
+	serverIp      string                                   // 节点 Ip
+	serverPort    string                                   // 节点端口, 即 RPC 端口
+	webrtcServer  *http.Server                             // 信令服务
+	draining      atomic.Bool                              // 节点关闭中, 不再接受新连接
 )
 
 // GetRoomIds 所有房间IDs
@@ -61,21 +66,32 @@ func GetDefaultRoomId() (roomId uint64) {
 }
 
 func upgrader(writer http.ResponseWriter, request *http.Request) {
+	// 关闭中的节点不再接受新连接, 客户端应连接其它节点
+	if draining.Load() {
+		http.Error(writer, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
 	// 升级协议
-	conn, err := (&websocket.Upgrader{CheckOrigin: func(r *http.Request) bool {
-		fmt.Println("升级协议", "ua:", r.Header["User-Agent"], "referer:", r.Header["Referer"])
-		return true
-	}}).Upgrade(writer, request, nil)
+	// 按客户端请求的子协议选择信令编码, 未携带子协议时使用 JSON 文本帧
+	conn, err := (&websocket.Upgrader{
+		Subprotocols: protocol.Subprotocols(),
+		CheckOrigin: func(r *http.Request) bool {
+			fmt.Println("升级协议", "ua:", r.Header["User-Agent"], "referer:", r.Header["Referer"])
+			return true
+		},
+	}).Upgrade(writer, request, nil)
 
 	if err != nil {
 		http.NotFound(writer, request)
 		return
 	}
 
-	fmt.Println("webSocket 建立连接:", conn.RemoteAddr().String())
+	codec := protocol.CodecFor(conn.Subprotocol())
+	fmt.Println("webSocket 建立连接:", conn.RemoteAddr().String(), codec.Name())
 
 	currentTime := uint64(time.Now().Unix())
-	client := NewClient(conn.RemoteAddr().String(), conn, currentTime)
+	client := NewClient(conn.RemoteAddr().String(), conn, currentTime, codec)
 	go client.read()
 	go client.write()
 
@@ -83,13 +99,25 @@ func upgrader(writer http.ResponseWriter, request *http.Request) {
 	clientManager.Register <- client
 }
 
-// StartWebRtc 启动程序
+// StartWebRtc 启动程序, 在后台监听 SOCKET_PORT
 func StartWebRtc(path string) {
 	serverIp = helper.GetServerIp()
-	http.HandleFunc(path, upgrader)
+	serverPort = conf.Server.RPCPort
+	startCluster(GetServer(), clientManager)
+	startPersist()
+	closeStaleAttendance()
+	closeStaleBreakouts()
+	closeStaleRecordings()
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, upgrader)
+	webrtcServer = &http.Server{Addr: ":" + conf.Server.SocketPort, Handler: mux}
 
 	// 添加处理程序
 	go clientManager.start()
 	log.Trace("WebRTC Listen on: %s:%s", serverIp, conf.Server.SocketPort)
-	_ = http.ListenAndServe(":"+conf.Server.SocketPort, nil)
+	go func() {
+		if err := webrtcServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("WebRTC Listen on :%s failed: %v", conf.Server.SocketPort, err)
+		}
+	}()
 }
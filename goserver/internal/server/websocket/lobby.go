@@ -0,0 +1,256 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"context"
+	"time"
+
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/models"
+	"io.wandao.meeting/internal/server/protocol"
+	"io.wandao.meeting/internal/server/rpcclient"
+
+	log "unknwon.dev/clog/v2"
+)
+
+// AddLobby 加入等候室, 同一用户重复进入时替换为新的连接
+func (manager *ClientManager) AddLobby(l *login) (replaced *login) {
+	manager.withRoom(l.RoomId, func(room *Room) {
+		replaced = room.addLobby(l)
+	})
+	if cluster != nil {
+		cluster.SaveLobby(l.RoomId, l.Peers)
+	}
+	return
+}
+
+// DelLobby 移出等候室
+func (manager *ClientManager) DelLobby(roomId uint64, userId uint64) (l *login) {
+	if manager.GetRoom(roomId) == nil {
+		return
+	}
+	manager.withRoom(roomId, func(room *Room) {
+		l = room.delLobby(userId, nil)
+	})
+	if l != nil && cluster != nil {
+		cluster.DelLobby(roomId, userId)
+	}
+	return
+}
+
+// DelLobbyClient 连接断开时将其移出等候室
+func (manager *ClientManager) DelLobbyClient(client *Client) (l *login) {
+	for _, room := range manager.GetRooms() {
+		if found := room.findLobby(client); found != nil {
+			manager.withRoom(room.Id, func(room *Room) {
+				l = room.delLobby(found.UserId, client)
+			})
+			if l != nil && cluster != nil {
+				cluster.DelLobby(l.RoomId, l.UserId)
+			}
+			return
+		}
+	}
+	return
+}
+
+// InLobby 连接是否在等候室中
+func (manager *ClientManager) InLobby(client *Client) bool {
+	for _, room := range manager.GetRooms() {
+		if room.findLobby(client) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLobby 获取房间等候室中的成员
+func (manager *ClientManager) GetLobby(roomId uint64) (list []*login) {
+	if room := manager.GetRoom(roomId); room != nil {
+		return room.Lobby()
+	}
+	return make([]*login, 0)
+}
+
+// LobbyAdmitController 主持人准入等候中的成员
+func LobbyAdmitController(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
+	code = common.OK
+	request, ok := payload.(*protocol.LobbyRequest)
+	if !ok {
+		code = common.ParameterIllegal
+		return
+	}
+	// 等候期间成员可能已被禁止进入或房间已满, 准入前重新校验
+	room, err := db.Rooms.GetByID(context.Background(), client.GetRoomId())
+	if err != nil {
+		code = common.NotRoom
+		log.Error("[WebSocket] LobbyAdmit 房间不存在: %s, %v", seq, err)
+		return
+	}
+	banned, err := db.RoomBans.IsBanned(context.Background(), room.Id, request.UserId)
+	if err != nil {
+		code = common.ServerError
+		log.Error("[WebSocket] LobbyAdmit 查询禁入名单失败: %s, %v", seq, err)
+		return
+	}
+	if banned {
+		code = common.UserBanned
+		decideLobby(client, request.UserId, "reject", "已被禁止进入房间")
+		return
+	}
+	if room.MaxPeers > 0 && countMembers(room) >= room.MaxPeers {
+		code = common.RoomFull
+		log.Error("[WebSocket] LobbyAdmit 房间人数已满: roomId:%d, maxPeers:%d", room.Id, room.MaxPeers)
+		return
+	}
+
+	code = decideLobby(client, request.UserId, "admit", "")
+	return
+}
+
+// LobbyRejectController 主持人拒绝等候中的成员
+func LobbyRejectController(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
+	code = common.OK
+	request, ok := payload.(*protocol.LobbyRequest)
+	if !ok {
+		code = common.ParameterIllegal
+		return
+	}
+	code = decideLobby(client, request.UserId, "reject", request.Reason)
+	return
+}
+
+// decideLobby 准入或拒绝操作者所在房间等候室中的用户
+// 用户在其它节点上等候时, 通过 RPC 交由所在节点处理
+func decideLobby(operator *Client, userId uint64, action string, reason string) (code uint64) {
+	roomId := operator.GetRoomId()
+	code = HandleLobby(roomId, userId, operator.GetUserId(), action, reason)
+	if code != common.NotUser || cluster == nil {
+		return
+	}
+	member, ok := cluster.RemoteLobby(roomId)[userId]
+	if !ok {
+		return
+	}
+	code, err := rpcclient.Lobby(member.Server(), &models.LobbyArgs{
+		RoomId:     roomId,
+		UserId:     userId,
+		OperatorId: operator.GetUserId(),
+		Action:     action,
+		Reason:     reason,
+	})
+	if err != nil {
+		code = common.ServerError
+		log.Error("[WebSocket] 处理其它节点上的等候用户失败: %d|%d %v", roomId, userId, err)
+	}
+	return
+}
+
+// HandleLobby 准入或拒绝本节点上等候室中的用户, 用户不在本节点上等候时返回 common.NotUser
+func HandleLobby(roomId uint64, userId uint64, operatorId uint64, action string, reason string) (code uint64) {
+	code = common.OK
+	l := clientManager.DelLobby(roomId, userId)
+	if l == nil {
+		code = common.NotUser
+		return
+	}
+	if action != "admit" {
+		rejectLobby(operatorId, l, reason)
+		return
+	}
+
+	l.Client.SendMessage("lobbyAdmitted", &protocol.LobbyAdmittedEvent{
+		RoomId:      l.RoomId,
+		ResumeToken: issueResumeToken(l.Client),
+	})
+	if err := loginRoom(l, uint64(time.Now().Unix())); err != nil {
+		code = common.ServerError
+		log.Error("[WebSocket] LobbyAdmit 数据缓存失败: %d|%d %v", roomId, userId, err)
+		return
+	}
+	notifyRoomHosts(l.RoomId, "lobbyLeave", &protocol.LobbyLeaveEvent{
+		RoomId:     l.RoomId,
+		UserId:     l.UserId,
+		Action:     "admit",
+		OperatorId: operatorId,
+	})
+	log.Info("[WebSocket] LobbyAdmit: roomId:%d, userId:%d, operatorId:%d", l.RoomId, l.UserId, operatorId)
+	return
+}
+
+// rejectLobby 拒绝已移出等候室的成员并关闭其连接, 避免被拒绝后在同一连接上反复进入等候室
+func rejectLobby(operatorId uint64, l *login, reason string) {
+	l.Client.SendMessage("lobbyRejected", &protocol.LobbyRejectedEvent{
+		RoomId: l.RoomId,
+		Reason: reason,
+	})
+	l.Client.closeFinal()
+	notifyRoomHosts(l.RoomId, "lobbyLeave", &protocol.LobbyLeaveEvent{
+		RoomId:     l.RoomId,
+		UserId:     l.UserId,
+		Action:     "reject",
+		OperatorId: operatorId,
+	})
+	log.Info("[WebSocket] LobbyReject: roomId:%d, userId:%d, operatorId:%d", l.RoomId, l.UserId, operatorId)
+}
+
+// enterLobby 将连接放入等候室, 并通知房间内的主持人
+func enterLobby(l *login) {
+	if replaced := clientManager.AddLobby(l); replaced != nil && replaced.Client != l.Client {
+		replaced.Client.SendMessage("lobbyRejected", &protocol.LobbyRejectedEvent{
+			RoomId: l.RoomId,
+			Reason: "已在其它连接进入等候室",
+		})
+		// 被替换的连接已不在等候室中, 关闭后不能再被准入
+		replaced.Client.closeFinal()
+	}
+	l.Client.SendMessage("lobbyWaiting", &protocol.RoomEvent{RoomId: l.RoomId})
+	notifyRoomHosts(l.RoomId, "lobbyJoin", lobbyPeer(l))
+}
+
+// sendLobbyList 向刚登录的主持人同步等候室中的成员, 包括在其它节点上等候的用户
+func sendLobbyList(client *Client, roomId uint64) {
+	for _, l := range clientManager.GetLobby(roomId) {
+		client.SendMessage("lobbyJoin", lobbyPeer(l))
+	}
+	if cluster == nil {
+		return
+	}
+	for userId, member := range cluster.RemoteLobby(roomId) {
+		event := &protocol.LobbyPeerEvent{RoomId: roomId, UserId: userId}
+		if member.Peers != nil {
+			event.UserName = member.Peers.UserName
+		}
+		client.SendMessage("lobbyJoin", event)
+	}
+}
+
+// notifyRoomHosts 向房间内联席主持人及以上角色发送数据, 包括其它节点上的主持人
+func notifyRoomHosts(roomId uint64, cmd string, data interface{}) {
+	notifyLocalHosts(clientManager, roomId, cmd, data)
+	if cluster != nil {
+		cluster.PublishHosts(roomId, protocol.NewMessage(cmd, data, 0))
+	}
+}
+
+// notifyLocalHosts 向本节点上房间内联席主持人及以上角色发送数据
+func notifyLocalHosts(manager *ClientManager, roomId uint64, cmd string, data interface{}) {
+	room := manager.GetRoom(roomId)
+	if room == nil {
+		return
+	}
+	for _, host := range room.Clients() {
+		if host.GetRole().AtLeast(db.RoleCoHost) {
+			host.SendMessage(cmd, data)
+		}
+	}
+}
+
+func lobbyPeer(l *login) *protocol.LobbyPeerEvent {
+	return &protocol.LobbyPeerEvent{
+		RoomId:   l.RoomId,
+		UserId:   l.UserId,
+		UserName: l.Peers.UserName,
+	}
+}
@@ -2,13 +2,12 @@
 package websocket
 
 import (
-	"fmt"
 	"sync"
 	"time"
 
-	jsoniter "github.com/json-iterator/go"
-	"io.wandao.meeting/internal/helper"
+	"io.wandao.meeting/internal/db"
 	"io.wandao.meeting/internal/server/models"
+	"io.wandao.meeting/internal/server/protocol"
 
 	log "unknwon.dev/clog/v2"
 
@@ -17,35 +16,34 @@ import (
 
 // ClientManager 连接管理
 type ClientManager struct {
-	Clients     map[*Client]bool                    // 全部的连接
-	ClientsLock sync.RWMutex                        // 读写锁
-	Users       map[string]*Client                  // 登录的用户 key=roomId+userId
-	Peers       map[uint64]map[uint64]*models.Peers // [roomId][userId]
-	UserLock    sync.RWMutex                        // 读写锁
-	PeersLock   sync.RWMutex                        // 读写锁
-	Register    chan *Client                        // 连接连接处理
-	Login       chan *login                         // 用户登录处理
-	Unregister  chan *Client                        // 断开连接处理程序
-	Broadcast   chan []byte                         // 广播 向全部成员发送数据
+	Clients     map[*Client]bool       // 全部的连接
+	ClientsLock sync.RWMutex           // 读写锁
+	Rooms       map[uint64]*Room       // 有成员或等候者的房间 key=roomId
+	RoomsLock   sync.RWMutex           // 读写锁
+	Register    chan *Client           // 连接连接处理
+	Login       chan *login            // 用户登录处理
+	Unregister  chan *Client           // 断开连接处理程序
+	Move        chan *move             // 成员移动房间处理
+	Broadcast   chan *protocol.Message // 广播 向全部成员发送数据
 }
 
 // NewClientManager 创建连接管理
 func NewClientManager() (clientManager *ClientManager) {
 	clientManager = &ClientManager{
 		Clients:    make(map[*Client]bool),
-		Users:      make(map[string]*Client),
-		Peers:      make(map[uint64]map[uint64]*models.Peers),
+		Rooms:      make(map[uint64]*Room),
 		Register:   make(chan *Client, 1000),
 		Login:      make(chan *login, 1000),
 		Unregister: make(chan *Client, 1000),
-		Broadcast:  make(chan []byte, 1000),
+		Move:       make(chan *move, 1000),
+		Broadcast:  make(chan *protocol.Message, 1000),
 	}
 	return
 }
 
 // GetUserKey 获取用户key
 func GetUserKey(roomId uint64, userId uint64) (key string) {
-	key = fmt.Sprintf("%d_%d", roomId, userId)
+	key = models.GetUserKey(roomId, userId)
 	return
 }
 
@@ -82,6 +80,8 @@ func (manager *ClientManager) ClientsRange(f func(client *Client, value bool) (r
 
 // GetClientsLen GetClientsLen
 func (manager *ClientManager) GetClientsLen() (clientsLen int) {
+	manager.ClientsLock.RLock()
+	defer manager.ClientsLock.RUnlock()
 	clientsLen = len(manager.Clients)
 	return
 }
@@ -102,108 +102,217 @@ func (manager *ClientManager) DelClients(client *Client) {
 	}
 }
 
+// GetRoom 获取房间, 房间内没有成员时返回 nil
+func (manager *ClientManager) GetRoom(roomId uint64) (room *Room) {
+	manager.RoomsLock.RLock()
+	defer manager.RoomsLock.RUnlock()
+	room = manager.Rooms[roomId]
+	return
+}
+
+// GetRooms 获取全部房间
+func (manager *ClientManager) GetRooms() (rooms []*Room) {
+	manager.RoomsLock.RLock()
+	defer manager.RoomsLock.RUnlock()
+	rooms = make([]*Room, 0, len(manager.Rooms))
+	for _, room := range manager.Rooms {
+		rooms = append(rooms, room)
+	}
+	return
+}
+
+// withRoom 在 RoomsLock 内操作房间, 房间不存在时创建, 操作后房间为空则删除
+// 集群模式下随房间的创建和删除订阅和取消订阅房间频道
+func (manager *ClientManager) withRoom(roomId uint64, f func(room *Room)) {
+	save := func() func() {
+		manager.RoomsLock.Lock()
+		defer manager.RoomsLock.Unlock()
+		room, ok := manager.Rooms[roomId]
+		if !ok {
+			room = NewRoom(roomId)
+		}
+		f(room)
+		if !room.isEmpty() {
+			manager.Rooms[roomId] = room
+			// 在 RoomsLock 内按房间创建和清理订阅, 避免与同时加入的成员交错
+			if !ok && cluster != nil {
+				cluster.SubscribeRoom(roomId)
+			}
+			return nil
+		}
+		if ok && cluster != nil {
+			cluster.UnsubscribeRoom(roomId)
+		}
+		// 在 RoomsLock 内取得快照, 保证重新创建的房间能加载到快照, 释放锁后再写入数据库
+		delete(manager.Rooms, roomId)
+		return room.Whiteboard().release()
+	}()
+	if save != nil {
+		save()
+	}
+}
+
+// JoinRoom 加入房间, 返回房间内已有的其他连接
+func (manager *ClientManager) JoinRoom(l *login) (others []*Client) {
+	manager.withRoom(l.RoomId, func(room *Room) {
+		others = room.join(l)
+	})
+	if cluster != nil {
+		cluster.SaveMember(l.RoomId, l.Peers)
+		cluster.PublishTakeover(l.RoomId, l.UserId)
+	}
+	return
+}
+
+// LeaveRoom 离开房间, 最后一名成员离开后清理房间
+func (manager *ClientManager) LeaveRoom(client *Client) (result bool) {
+	if manager.GetRoom(client.GetRoomId()) == nil {
+		return
+	}
+	manager.withRoom(client.GetRoomId(), func(room *Room) {
+		result = room.leave(client)
+	})
+	if result {
+		manager.leftRoom(client.GetRoomId(), client.GetUserId())
+	}
+	return
+}
+
+// leftRoom 成员离开房间后记录参会时长、停止其开始的录制并清理其它节点可见的成员信息
+func (manager *ClientManager) leftRoom(roomId uint64, userId uint64) {
+	recordLeave(roomId, userId)
+	stopRecordingOnLeave(roomId, userId)
+	if cluster != nil {
+		cluster.DelMember(roomId, userId)
+	}
+}
+
 // GetUserClient 获取用户的连接
 func (manager *ClientManager) GetUserClient(roomId uint64, userId uint64) (client *Client) {
-	manager.UserLock.RLock()
-	defer manager.UserLock.RUnlock()
-	userKey := GetUserKey(roomId, userId)
-	if value, ok := manager.Users[userKey]; ok {
-		client = value
+	if room := manager.GetRoom(roomId); room != nil {
+		client = room.GetClient(userId)
 	}
 	return
 }
 
-// GetUsersLen GetClientsLen
+// GetUsersLen 登录用户数
 func (manager *ClientManager) GetUsersLen() (userLen int) {
-	userLen = len(manager.Users)
+	for _, room := range manager.GetRooms() {
+		userLen += room.Len()
+	}
 	return
 }
 
-// AddUsers 添加用户
-func (manager *ClientManager) AddUsers(key string, client *Client) {
-	manager.UserLock.Lock()
-	defer manager.UserLock.Unlock()
-	manager.Users[key] = client
-}
-
-// AddPeers 添加房间信息
-func (manager *ClientManager) AddPeers(client *Client, roomInfo *models.Peers) {
-	manager.PeersLock.Lock()
-	defer manager.PeersLock.Unlock()
-	if _, ok := manager.Peers[client.RoomId]; !ok {
-		clientManager.Peers[client.RoomId] = make(map[uint64]*models.Peers)
+// SetRoomLock 更新房间内全部成员的房间锁状态
+func (manager *ClientManager) SetRoomLock(roomId uint64, locked bool) {
+	if room := manager.GetRoom(roomId); room != nil {
+		room.SetLock(locked)
 	}
-	manager.Peers[client.RoomId][client.UserId] = roomInfo
 }
 
-// DelPeers 删除房间信息
-func (manager *ClientManager) DelPeers(client *Client) {
-	manager.PeersLock.Lock()
-	defer manager.PeersLock.Unlock()
-	delete(manager.Peers[client.RoomId], client.UserId)
-	if len(manager.Peers[client.RoomId]) == 0 {
-		delete(manager.Peers, client.UserId)
+// GetPeer 获取房间内成员信息
+func (manager *ClientManager) GetPeer(roomId uint64, userId uint64) (peer *protocol.Peers) {
+	if room := manager.GetRoom(roomId); room != nil {
+		peer = room.GetPeer(userId)
 	}
+	return
 }
 
-// DelUsers 删除用户
-func (manager *ClientManager) DelUsers(client *Client) (result bool) {
-	manager.UserLock.Lock()
-	defer manager.UserLock.Unlock()
-	key := GetUserKey(client.RoomId, client.UserId)
-	if value, ok := manager.Users[key]; ok {
-		// 判断是否为相同的用户
-		if value.Addr != client.Addr {
-			return
+// SetPeerStatus 更新成员的媒体状态
+func (manager *ClientManager) SetPeerStatus(roomId uint64, userId uint64, action string, status bool) (ok bool) {
+	room := manager.GetRoom(roomId)
+	if room == nil {
+		return
+	}
+	ok = room.UpdatePeer(userId, func(peer *protocol.Peers) bool {
+		switch action {
+		case "video":
+			peer.VideoStatus = status
+		case "audio":
+			peer.AudioStatus = status
+		case "screen":
+			peer.ScreenStatus = status
+		case "hand":
+			peer.HandStatus = status
+		case "record":
+			peer.RecordStatus = status
+		case "privacy":
+			peer.PrivacyStatus = status
+		default:
+			return false
 		}
-		delete(manager.Users, key)
-		manager.DelPeers(client)
-		result = true
+		return true
+	})
+	if ok && cluster != nil {
+		cluster.SaveMember(roomId, room.GetPeer(userId))
 	}
 	return
 }
 
+// SetPeerRole 更新成员的房间角色
+func (manager *ClientManager) SetPeerRole(roomId uint64, userId uint64, role db.RoomRole) {
+	room := manager.GetRoom(roomId)
+	if room == nil {
+		return
+	}
+	room.UpdatePeer(userId, func(peer *protocol.Peers) bool {
+		peer.Role = string(role)
+		return true
+	})
+	if cluster != nil {
+		cluster.SaveMember(roomId, room.GetPeer(userId))
+	}
+	if client := room.GetClient(userId); client != nil {
+		client.SetRole(role)
+	}
+}
+
 // GetUserKeys 获取用户的key
 func (manager *ClientManager) GetUserKeys() (userKeys []string) {
 	userKeys = make([]string, 0)
-	manager.UserLock.RLock()
-	defer manager.UserLock.RUnlock()
-	for key := range manager.Users {
-		userKeys = append(userKeys, key)
+	for _, room := range manager.GetRooms() {
+		for _, userId := range room.UserIds() {
+			userKeys = append(userKeys, GetUserKey(room.Id, userId))
+		}
 	}
 	return
 }
 
-// GetUserList 获取用户 list
+// GetUserList 获取用户 list, 包括其它节点上的成员
 func (manager *ClientManager) GetUserList(roomId uint64) (userList []uint64) {
 	userList = make([]uint64, 0)
-	manager.UserLock.RLock()
-	defer manager.UserLock.RUnlock()
-	for _, v := range manager.Users {
-		if v.RoomId == roomId {
-			userList = append(userList, v.UserId)
+	if room := manager.GetRoom(roomId); room != nil {
+		userList = room.UserIds()
+	}
+	if cluster != nil {
+		for userId := range cluster.RemotePeers(roomId) {
+			if manager.GetUserClient(roomId, userId) == nil {
+				userList = append(userList, userId)
+			}
 		}
 	}
-	log.Info("GetUserList len: %d", len(manager.Users))
 	return
 }
 
-// GetRoomPeers 获取房间信息
-func (manager *ClientManager) GetRoomPeers(roomId uint64) (peers map[uint64]*models.Peers) {
-	manager.PeersLock.RLock()
-	defer manager.PeersLock.RUnlock()
-	peers = manager.Peers[roomId]
-	log.Info("GetRoomPeers len: %d", len(peers))
+// GetRoomPeers 获取房间信息, 包括其它节点上的成员
+func (manager *ClientManager) GetRoomPeers(roomId uint64) (peers map[uint64]*protocol.Peers) {
+	peers = make(map[uint64]*protocol.Peers)
+	if cluster != nil {
+		peers = cluster.RemotePeers(roomId)
+	}
+	if room := manager.GetRoom(roomId); room != nil {
+		for userId, peer := range room.Peers() {
+			peers[userId] = peer
+		}
+	}
 	return
 }
 
-// GetUserClients 获取用户的key
+// GetUserClients 获取全部登录的连接
 func (manager *ClientManager) GetUserClients() (clients []*Client) {
 	clients = make([]*Client, 0)
-	manager.UserLock.RLock()
-	defer manager.UserLock.RUnlock()
-	for _, v := range manager.Users {
-		clients = append(clients, v)
+	for _, room := range manager.GetRooms() {
+		clients = append(clients, room.Clients()...)
 	}
 	return
 }
@@ -220,26 +329,66 @@ func (manager *ClientManager) EventLogin(login *login) {
 	client := login.Client
 	// 连接存在，在添加
 	if manager.InClient(client) {
-		userKey := login.GetKey()
-		manager.AddPeers(login.Client, login.Peers)
-		CreateRoomRTCPeerConnection(client)
-		manager.AddUsers(userKey, login.Client)
+		manager.enterRoom(login)
+		sendBreakoutState(client)
 	}
 	log.Info("EventLogin 用户登录: %s|%d|%d", client.Addr, login.RoomId, login.UserId)
-	_, _ = SendUserMessageAll(models.MessageCmdConnect, "哈喽~", login.RoomId, login.UserId)
+	_, _ = SendUserMessageAll(protocol.EventConnect, "哈喽~", login.RoomId, login.UserId)
+}
+
+// enterRoom 加入房间, 与房间内的成员互相通知创建连接并同步房间状态
+func (manager *ClientManager) enterRoom(l *login) {
+	client := l.Client
+	others := manager.JoinRoom(l)
+	recordJoin(l.RoomId, l.UserId, l.Peers.UserName)
+	CreateRoomRTCPeerConnection(client, others)
+	createRemoteRTCPeerConnection(client)
+	sendWhiteboardState(client)
+	sendRecordingState(client)
 }
 
 // EventUnregister 用户断开连接
 func (manager *ClientManager) EventUnregister(client *Client) {
 	manager.DelClients(client)
 
+	// 等候中的连接直接移出等候室
+	if l := manager.DelLobbyClient(client); l != nil {
+		notifyRoomHosts(l.RoomId, "lobbyLeave", &protocol.LobbyLeaveEvent{
+			RoomId: l.RoomId,
+			UserId: l.UserId,
+			Action: "leave",
+		})
+		return
+	}
+
+	// 保留会话等待重连
+	if manager.SuspendRoom(client) {
+		log.Info("EventUnregister 用户断开连接, 会话已挂起: %s|%d|%d", client.Addr, client.GetRoomId(), client.GetUserId())
+		return
+	}
+
 	// 删除用户连接
-	deleteResult := manager.DelUsers(client)
-	if deleteResult {
-		// 不是当前连接的客户端
+	deleteResult := manager.LeaveRoom(client)
+	if !deleteResult {
+		// 未登录或不是当前连接的客户端
 		return
 	}
+	log.Info("EventUnregister 用户断开连接: %s|%d|%d", client.Addr, client.GetRoomId(), client.GetUserId())
+	manager.notifyLeave(client)
+}
+
+// EventMove 成员移动房间, 与登录和断开连接在同一协程内处理, 已断开的连接不再移动
+func (manager *ClientManager) EventMove(m *move) {
+	if !manager.InClient(m.client) {
+		return
+	}
+	if manager.moveClient(m.client, m.event) {
+		sendBreakoutState(m.client)
+	}
+}
 
+// notifyLeave 成员离开房间后清除在线信息并通知房间内其他成员
+func (manager *ClientManager) notifyLeave(client *Client) {
 	// 清除redis登录数据
 	userOnline, err := cache.GetUserOnlineInfo(client.GetKey())
 	if err == nil {
@@ -247,23 +396,13 @@ func (manager *ClientManager) EventUnregister(client *Client) {
 		_ = cache.SetUserOnlineInfo(client.GetKey(), userOnline)
 	}
 
-	// 关闭 chan
-	// close(client.Send)
-	log.Info("EventUnregister 用户断开连接: %s|%d|%d", client.Addr, client.RoomId, client.UserId)
-	if client.UserId > 0 {
-		msg, err := jsoniter.Marshal(models.SendRequest{
-			Seq: helper.GetOrderIDTime(),
-			Cmd: models.MessageCmdExit,
-			Data: map[string]interface{}{
-				"roomId":  client.RoomId,
-				"userId":  client.UserId,
-				"message": "用户已经离开",
-			},
-		})
-		if err != nil {
-			return
-		}
-		manager.sendRoomIdAll(msg, client.RoomId, client)
+	if client.GetUserId() > 0 {
+		msg := protocol.NewMessage(protocol.EventExit, &protocol.ExitEvent{
+			RoomId:  client.GetRoomId(),
+			UserId:  client.GetUserId(),
+			Message: "用户已经离开",
+		}, 0)
+		manager.sendRoomIdAll(msg, client.GetRoomId(), client)
 	}
 }
 
@@ -280,15 +419,15 @@ func (manager *ClientManager) start() {
 		case conn := <-manager.Unregister:
 			// 断开连接事件
 			manager.EventUnregister(conn)
+		case m := <-manager.Move:
+			// 成员移动房间事件
+			manager.EventMove(m)
 		case message := <-manager.Broadcast:
 			// 广播事件
+			f := newFrames(message)
 			clients := manager.GetClients()
 			for conn := range clients {
-				select {
-				case conn.Send <- message:
-				default:
-					close(conn.Send)
-				}
+				f.send(conn)
 			}
 		}
 	}
@@ -303,6 +442,8 @@ func GetManagerInfo(isDebug string) (managerInfo map[string]interface{}) {
 	managerInfo["chanLoginLen"] = len(clientManager.Login)           // 未处理登录事件数
 	managerInfo["chanUnregisterLen"] = len(clientManager.Unregister) // 未处理退出登录事件数
 	managerInfo["chanBroadcastLen"] = len(clientManager.Broadcast)   // 未处理广播事件数
+	managerInfo["droppedMessages"] = droppedMessages.Load()          // 因队列已满丢弃的消息数
+	managerInfo["slowConsumerKicks"] = slowConsumerKicks.Load()      // 因队列已满断开的连接数
 	if isDebug == "true" {
 		addrList := make([]string, 0)
 		clientManager.ClientsRange(func(client *Client, value bool) (result bool) {
@@ -322,14 +463,24 @@ func GetUserClient(roomId uint64, userId uint64) (client *Client) {
 	return
 }
 
-// ClearTimeoutConnections 定时清理超时连接
+// ClearTimeoutConnections 定时清理超时连接, 并将连接的心跳同步到 Redis 在线状态
 func ClearTimeoutConnections() {
-	currentTime := uint64(time.Now().Unix())
-	clients := clientManager.GetClients()
-	for client := range clients {
+	clearTimeoutConnections(clientManager, uint64(time.Now().Unix()), refreshUserOnline)
+}
+
+// clearTimeoutConnections 关闭心跳超时的连接, 由 Unregister 清理在线状态并通知房间内其他成员
+// 其余已登录的连接调用 refresh 刷新在线状态, pong 只更新连接的心跳时间
+func clearTimeoutConnections(manager *ClientManager, currentTime uint64, refresh func(client *Client) error) {
+	for client := range manager.GetClients() {
 		if client.IsHeartbeatTimeout(currentTime) {
-			log.Info("[websocket]心跳时间超时 关闭连接 %s %d %d %d", client.Addr, client.UserId, client.LoginTime, client.HeartbeatTime)
-			_ = client.Socket.Close()
+			log.Info("[websocket]心跳时间超时 关闭连接 %s %d %d %d", client.Addr, client.GetUserId(), client.LoginTime(), client.HeartbeatTime())
+			client.close()
+			continue
+		}
+		if client.IsLogin() {
+			if err := refresh(client); err != nil {
+				log.Warn("[websocket]刷新在线状态失败 %s %d %d %v", client.Addr, client.GetRoomId(), client.GetUserId(), err)
+			}
 		}
 	}
 }
@@ -341,37 +492,98 @@ func GetUserList(roomId uint64) (userList []uint64) {
 	return
 }
 
+// LocalUserList 获取本节点上的房间成员
+func LocalUserList(roomId uint64) (userList []uint64) {
+	userList = make([]uint64, 0)
+	if room := clientManager.GetRoom(roomId); room != nil {
+		userList = room.UserIds()
+	}
+	return
+}
+
+// LocalRoomPeers 获取本节点上的房间成员信息
+func LocalRoomPeers(roomId uint64) (peers map[uint64]*protocol.Peers) {
+	if room := clientManager.GetRoom(roomId); room != nil {
+		return room.Peers()
+	}
+	return make(map[uint64]*protocol.Peers)
+}
+
+// SendLocalMessage 向本节点上的成员推送消息, userId 为 0 时发送给房间内全部成员
+func SendLocalMessage(roomId uint64, userId uint64, cmd string, data interface{}) (sent bool) {
+	room := clientManager.GetRoom(roomId)
+	if room == nil {
+		return
+	}
+	if userId == 0 {
+		room.Broadcast(protocol.NewMessage(cmd, data, 0), nil)
+		return room.Len() > 0
+	}
+	if client := room.GetClient(userId); client != nil {
+		client.SendMessage(cmd, data)
+		return true
+	}
+	return
+}
+
 // sendAll 向全部成员(除了自己)发送数据
-func (manager *ClientManager) sendAll(message []byte, self *Client) {
+func (manager *ClientManager) sendAll(message *protocol.Message, self *Client) {
+	f := newFrames(message)
 	clients := manager.GetUserClients()
 	for _, conn := range clients {
 		if conn != self {
-			conn.SendMsg(message)
+			f.send(conn)
 		}
 	}
 }
 
 // sendRoomIdAll 向房间内全部成员(除了自己)发送数据
-func (manager *ClientManager) sendRoomIdAll(message []byte, roomId uint64, self *Client) {
-	clients := manager.GetUserClients()
-	for _, conn := range clients {
-		if conn != self && conn.RoomId == roomId {
-			conn.SendMsg(message)
+// 等候室中的连接尚未加入房间, 不会收到房间广播
+func (manager *ClientManager) sendRoomIdAll(message *protocol.Message, roomId uint64, self *Client) {
+	if room := manager.GetRoom(roomId); room != nil {
+		room.Broadcast(message, self)
+	}
+	if cluster != nil {
+		var exceptUserId uint64
+		if self != nil {
+			exceptUserId = self.GetUserId()
 		}
+		cluster.PublishRoom(roomId, exceptUserId, message)
 	}
 }
 
 // AllSendMessages 全员广播
-func AllSendMessages(roomId uint64, userId uint64, data string) {
-	log.Info("[websocket]全员广播 %d %d %s", roomId, userId, data)
+func AllSendMessages(roomId uint64, userId uint64, message *protocol.Message) {
+	log.Info("[websocket]全员广播 %d %d %s", roomId, userId, message.Cmd)
 	ignoreClient := clientManager.GetUserClient(roomId, userId)
-	clientManager.sendRoomIdAll([]byte(data), roomId, ignoreClient)
+	clientManager.sendRoomIdAll(message, roomId, ignoreClient)
+}
+
+// NotifyRoomLock 房间锁状态变更后同步在线成员
+func NotifyRoomLock(roomId uint64, userId uint64, userName string, locked bool) {
+	clientManager.SetRoomLock(roomId, locked)
+	action := "unlock"
+	if locked {
+		action = "lock"
+	}
+	msg := protocol.NewMessage("roomAction", &protocol.RoomAction{
+		Action:   action,
+		RoomId:   roomId,
+		UserId:   userId,
+		UserName: userName,
+	}, 0)
+	clientManager.sendRoomIdAll(msg, roomId, nil)
+}
+
+// NotifyFileShared 通知房间内全部成员有新的共享文件
+func NotifyFileShared(event *protocol.FileSharedEvent) {
+	msg := protocol.NewMessage("fileShared", event, event.UserId)
+	clientManager.sendRoomIdAll(msg, event.RoomId, nil)
 }
 
 // SendUserMessageAll 给全体用户发消息
 func SendUserMessageAll(cmd string, message string, roomId uint64, userId uint64) (sendResults bool, err error) {
 	sendResults = true
-	data := models.GetTextMsgData(cmd, message, userId)
-	AllSendMessages(roomId, userId, data)
+	AllSendMessages(roomId, userId, protocol.NewMessage(cmd, message, userId))
 	return
 }
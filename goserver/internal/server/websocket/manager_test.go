@@ -0,0 +1,40 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+func TestClearTimeoutConnections(t *testing.T) {
+	conf.SetMockWebSocket(t, conf.WebSocketOpts{SendQueueSize: 8, SlowConsumer: "drop", HeartbeatTimeout: time.Minute})
+	manager := NewClientManager()
+
+	stale := NewClient("127.0.0.1:1234", nil, 100, protocol.JSON)
+	stale.Login(101, 1, db.RoleParticipant, 100)
+	alive := NewClient("127.0.0.1:1235", nil, 100, protocol.JSON)
+	alive.Login(101, 2, db.RoleParticipant, 100)
+	// 只收到 pong 的连接同样视为存活
+	alive.Heartbeat(150)
+	anonymous := NewClient("127.0.0.1:1236", nil, 150, protocol.JSON)
+	for _, client := range []*Client{stale, alive, anonymous} {
+		manager.AddClients(client)
+	}
+
+	refreshed := make([]*Client, 0)
+	clearTimeoutConnections(manager, 160, func(client *Client) error {
+		refreshed = append(refreshed, client)
+		return nil
+	})
+
+	// 超时的连接交由 write 退出后的 Unregister 清理, 这里只关闭
+	assert.True(t, stale.IsClosed())
+	assert.True(t, manager.InClient(stale))
+	assert.False(t, alive.IsClosed())
+	assert.False(t, anonymous.IsClosed())
+	assert.Equal(t, []*Client{alive}, refreshed)
+}
@@ -0,0 +1,28 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"time"
+
+	"io.wandao.meeting/internal/db"
+)
+
+// meetingWindow 检查预约会议的房间当前是否开放
+// 房间没有未结束的会议时不受限制; 有会议正在进行时开放; 会议组织者可以提前进入
+// 不开放时 next 为最近一次会议的开始时间
+func meetingWindow(meetings []*db.Meeting, userId uint64, now time.Time) (open bool, next time.Time) {
+	open = true
+	for _, meeting := range meetings {
+		from, _, ok := meeting.NextWindow(now)
+		if !ok {
+			continue
+		}
+		if !from.After(now) || meeting.OrganizerId == userId {
+			return true, time.Time{}
+		}
+		if open || from.Before(next) {
+			open, next = false, from
+		}
+	}
+	return
+}
@@ -0,0 +1,39 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"io.wandao.meeting/internal/db"
+)
+
+func TestMeetingWindow(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+	meeting := func(organizerId uint64, start time.Time, recurrence string) *db.Meeting {
+		return &db.Meeting{OrganizerId: organizerId, StartAt: start, EndAt: start.Add(time.Hour), Recurrence: recurrence}
+	}
+	past := meeting(1, now.Add(-48*time.Hour), "")
+	upcoming := meeting(1, now.Add(2*time.Hour), "")
+	weekly := meeting(2, now.Add(-7*24*time.Hour-10*time.Minute), "FREQ=WEEKLY")
+
+	for _, tc := range []struct {
+		name     string
+		meetings []*db.Meeting
+		userId   uint64
+		open     bool
+		next     time.Time
+	}{
+		{"没有预约", nil, 3, true, time.Time{}},
+		{"会议均已结束", []*db.Meeting{past}, 3, true, time.Time{}},
+		{"会议未开始", []*db.Meeting{past, upcoming}, 3, false, upcoming.StartAt},
+		{"组织者提前进入", []*db.Meeting{upcoming}, 1, true, time.Time{}},
+		{"重复会议进行中", []*db.Meeting{upcoming, weekly}, 3, true, time.Time{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			open, next := meetingWindow(tc.meetings, tc.userId, now)
+			assert.Equal(t, tc.open, open)
+			assert.Equal(t, tc.next, next)
+		})
+	}
+}
@@ -0,0 +1,270 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"context"
+
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/libs/redislib"
+	"io.wandao.meeting/internal/server/models"
+	"io.wandao.meeting/internal/server/protocol"
+	"io.wandao.meeting/internal/server/rpcclient"
+
+	log "unknwon.dev/clog/v2"
+)
+
+// KickController 将成员踢出房间
+func KickController(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
+	request, code := parseModeration(client, seq, payload)
+	if code != common.OK {
+		return
+	}
+	if !kickMember(client, request.UserId, request.Reason) {
+		code = common.NotUser
+	}
+	return
+}
+
+// kickMember 将操作者所在房间内的成员踢出, 成员不在线时返回 false
+// 成员在其它节点上时, 通过 RPC 交由所在节点踢出
+func kickMember(client *Client, userId uint64, reason string) (kicked bool) {
+	if target := clientManager.GetUserClient(client.GetRoomId(), userId); target != nil {
+		kickClient(client.GetUserId(), operatorName(client), target, reason)
+		return true
+	}
+	if redislib.GetClient() == nil {
+		return false
+	}
+	kicked, err := rpcclient.Kick(&models.KickArgs{
+		RoomId:       client.GetRoomId(),
+		UserId:       userId,
+		OperatorId:   client.GetUserId(),
+		OperatorName: operatorName(client),
+		Reason:       reason,
+	})
+	if err != nil && err != rpcclient.ErrUserOffline {
+		log.Error("[WebSocket] 踢出其它节点上的成员失败: %d|%d %v", client.GetRoomId(), userId, err)
+	}
+	return err == nil && kicked
+}
+
+// MuteController 远程关闭成员的麦克风
+func MuteController(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
+	request, code := parseModeration(client, seq, payload)
+	if code != common.OK {
+		return
+	}
+	code = moderateMedia(client, request.UserId, "audio")
+	return
+}
+
+// StopVideoController 远程关闭成员的摄像头
+func StopVideoController(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
+	request, code := parseModeration(client, seq, payload)
+	if code != common.OK {
+		return
+	}
+	code = moderateMedia(client, request.UserId, "video")
+	return
+}
+
+// MuteAllController 关闭房间内角色低于自己的全部成员的麦克风, 包括其它节点上的成员
+func MuteAllController(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
+	code = common.OK
+	role := client.GetRole()
+	MuteLocal(client.GetRoomId(), client.GetUserId(), operatorName(client), string(role))
+	if cluster != nil {
+		err := rpcclient.MuteAll(&models.ModerateArgs{
+			RoomId:       client.GetRoomId(),
+			OperatorId:   client.GetUserId(),
+			OperatorName: operatorName(client),
+			OperatorRole: string(role),
+			Action:       "audio",
+		}, GetServer())
+		if err != nil {
+			log.Error("[WebSocket] MuteAll 获取节点列表失败: %s, %v", seq, err)
+		}
+	}
+	log.Info("[WebSocket] MuteAll: %s, roomId:%d, userId:%d", seq, client.GetRoomId(), client.GetUserId())
+	return
+}
+
+// BanController 将成员加入房间禁入名单, 在线时同时踢出, 包括其它节点上的成员
+func BanController(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
+	request, code := parseModeration(client, seq, payload)
+	if code != common.OK {
+		return
+	}
+	err := db.RoomBans.Create(context.Background(), &db.RoomBan{
+		RoomId:     client.GetRoomId(),
+		UserId:     request.UserId,
+		OperatorId: client.GetUserId(),
+		Reason:     request.Reason,
+	})
+	if err != nil {
+		code = common.ModelAddError
+		log.Error("[WebSocket] Ban 保存禁入名单失败: %s, %v", seq, err)
+		return
+	}
+	kickMember(client, request.UserId, request.Reason)
+	return
+}
+
+// UnbanController 将用户移出房间禁入名单
+func UnbanController(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
+	code = common.OK
+	request, ok := payload.(*protocol.ModerationRequest)
+	if !ok {
+		code = common.ParameterIllegal
+		return
+	}
+	err := db.RoomBans.Delete(context.Background(), client.GetRoomId(), request.UserId)
+	if err != nil {
+		code = common.ModelDeleteError
+		log.Error("[WebSocket] Unban 删除禁入名单失败: %s, %v", seq, err)
+		return
+	}
+	return
+}
+
+// parseModeration 解析管理请求, 目标成员的角色必须低于操作者
+func parseModeration(client *Client, seq string, payload interface{}) (request *protocol.ModerationRequest, code uint64) {
+	code = common.OK
+	request, ok := payload.(*protocol.ModerationRequest)
+	if !ok {
+		code = common.ParameterIllegal
+		return
+	}
+	if request.UserId == 0 || request.UserId == client.GetUserId() {
+		code = common.InvalidUserId
+		return
+	}
+	role, err := db.RoomMembers.GetRole(context.Background(), client.GetRoomId(), request.UserId)
+	if err != nil {
+		code = common.ServerError
+		log.Error("[WebSocket] 管理请求获取角色失败: %s, %v", seq, err)
+		return
+	}
+	if role.AtLeast(client.GetRole()) {
+		code = common.Unauthorized
+	}
+	return
+}
+
+// KickUser 将本节点上的成员踢出房间, 成员不在本节点上时返回 false
+func KickUser(roomId uint64, userId uint64, operatorId uint64, operatorName string, reason string) (kicked bool) {
+	target := clientManager.GetUserClient(roomId, userId)
+	if target == nil {
+		return
+	}
+	kickClient(operatorId, operatorName, target, reason)
+	return true
+}
+
+// kickClient 通知被踢出的成员并关闭连接, 同时通知房间内其他成员
+func kickClient(operatorId uint64, operatorName string, target *Client, reason string) {
+	target.SendMessage("kickOut", &protocol.KickOutEvent{
+		UserId:   operatorId,
+		UserName: operatorName,
+		Reason:   reason,
+	})
+	target.closeFinal()
+	// 成员已断开时会话可能处于挂起中, 踢出后不能再恢复
+	clientManager.ExpireSession(target)
+
+	msg := protocol.NewMessage("peerKicked", &protocol.PeerKickedEvent{
+		RoomId:     target.GetRoomId(),
+		UserId:     target.GetUserId(),
+		OperatorId: operatorId,
+		Reason:     reason,
+	}, 0)
+	clientManager.sendRoomIdAll(msg, target.GetRoomId(), target)
+	log.Info("[WebSocket] 踢出成员: roomId:%d, userId:%d, operatorId:%d", target.GetRoomId(), target.GetUserId(), operatorId)
+}
+
+// moderateMedia 关闭成员的音频或视频, 并同步房间内的成员状态
+// 成员在其它节点上时, 通过 RPC 交由所在节点处理
+func moderateMedia(operator *Client, userId uint64, action string) (code uint64) {
+	code = common.OK
+	roomId := operator.GetRoomId()
+	if ModerateUser(roomId, userId, operator.GetUserId(), operatorName(operator), action) {
+		return
+	}
+	code = common.NotUser
+	if cluster == nil {
+		return
+	}
+	done, err := rpcclient.Moderate(&models.ModerateArgs{
+		RoomId:       roomId,
+		UserId:       userId,
+		OperatorId:   operator.GetUserId(),
+		OperatorName: operatorName(operator),
+		OperatorRole: string(operator.GetRole()),
+		Action:       action,
+	})
+	if err != nil && err != rpcclient.ErrUserOffline {
+		log.Error("[WebSocket] 关闭其它节点上成员的%s失败: %d|%d %v", action, roomId, userId, err)
+	}
+	if err == nil && done {
+		code = common.OK
+	}
+	return
+}
+
+// ModerateUser 关闭本节点上成员的音频或视频, 成员不在本节点上时返回 false
+func ModerateUser(roomId uint64, userId uint64, operatorId uint64, operatorName string, action string) (done bool) {
+	target := clientManager.GetUserClient(roomId, userId)
+	if target == nil {
+		return
+	}
+	moderateClient(roomId, operatorId, operatorName, target, action)
+	return true
+}
+
+// MuteLocal 关闭本节点上房间内角色低于 role 的全部成员的麦克风
+func MuteLocal(roomId uint64, operatorId uint64, operatorName string, role string) {
+	room := clientManager.GetRoom(roomId)
+	if room == nil {
+		return
+	}
+	operatorRole := db.RoomRole(role)
+	for _, target := range room.Clients() {
+		if target.GetUserId() == operatorId || target.GetRole().AtLeast(operatorRole) {
+			continue
+		}
+		moderateClient(roomId, operatorId, operatorName, target, "audio")
+	}
+}
+
+// moderateClient 通知成员被关闭音频或视频, 并向房间内全部成员同步状态
+func moderateClient(roomId uint64, operatorId uint64, operatorName string, target *Client, action string) {
+	userId := target.GetUserId()
+	clientManager.SetPeerStatus(roomId, userId, action, false)
+
+	peerAction := "muteAudio"
+	if action == "video" {
+		peerAction = "hideVideo"
+	}
+	target.SendMessage("peerAction", &protocol.PeerActionEvent{
+		UserId:     operatorId,
+		UserName:   operatorName,
+		PeerAction: peerAction,
+	})
+
+	msg := protocol.NewMessage("peerStatus", &protocol.RoomStatus{
+		Action: action,
+		RoomId: roomId,
+		UserId: userId,
+		Status: false,
+	}, 0)
+	clientManager.sendRoomIdAll(msg, roomId, nil)
+}
+
+// operatorName 操作者的用户名
+func operatorName(operator *Client) string {
+	if peer := clientManager.GetPeer(operator.GetRoomId(), operator.GetUserId()); peer != nil {
+		return peer.UserName
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"sync"
+
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+var (
+	// commandRoles 命令所需的最低角色, 未设置的命令不限角色
+	commandRoles      = make(map[string]db.RoomRole)
+	commandRolesMutex sync.RWMutex
+
+	// actionRoles 按 action 区分的命令所需最低角色
+	actionRoles = map[string]map[string]db.RoomRole{
+		"roomAction": {
+			"lock":   db.RoleCoHost,
+			"unlock": db.RoleCoHost,
+		},
+		"peerAction": {
+			"muteAudio": db.RoleCoHost,
+			"hideVideo": db.RoleCoHost,
+			"ejectAll":  db.RoleHost,
+		},
+		"whiteboard": {
+			"lock":   db.RoleHost,
+			"unlock": db.RoleHost,
+		},
+	}
+)
+
+// RequireRole 设置命令所需的最低角色
+func RequireRole(cmd string, role db.RoomRole) {
+	commandRolesMutex.Lock()
+	defer commandRolesMutex.Unlock()
+	commandRoles[cmd] = role
+}
+
+func getCommandRole(cmd string) (role db.RoomRole, ok bool) {
+	commandRolesMutex.RLock()
+	defer commandRolesMutex.RUnlock()
+	role, ok = commandRoles[cmd]
+	return
+}
+
+// checkPermission 校验客户端在房间中的角色是否满足命令所需的最低角色
+func checkPermission(client *Client, cmd string, payload interface{}) bool {
+	role, ok := getCommandRole(cmd)
+	if actions, has := actionRoles[cmd]; has {
+		var action string
+		if p, is := payload.(protocol.ActionPayload); is {
+			action = p.GetAction()
+		}
+		if actionRole, has := actions[action]; has && actionRole.Level() > role.Level() {
+			role, ok = actionRole, true
+		}
+	}
+	if !ok {
+		return true
+	}
+	return client.IsLogin() && client.GetRole().AtLeast(role)
+}
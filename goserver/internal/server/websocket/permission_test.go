@@ -0,0 +1,43 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+func TestCheckPermission(t *testing.T) {
+	RequireRole("test.hostOnly", db.RoleHost)
+
+	login := func(role db.RoomRole) *Client {
+		client := NewClient("127.0.0.1:1234", nil, 1, protocol.JSON)
+		client.Login(101, 1, role, 1)
+		return client
+	}
+
+	tests := []struct {
+		name    string
+		client  *Client
+		cmd     string
+		payload interface{}
+		want    bool
+	}{
+		{name: "未限制的命令", client: NewClient("127.0.0.1:1234", nil, 1, protocol.JSON), cmd: "ping", payload: &protocol.Empty{}, want: true},
+		{name: "未登录", client: NewClient("127.0.0.1:1234", nil, 1, protocol.JSON), cmd: "test.hostOnly", payload: &protocol.Empty{}, want: false},
+		{name: "参会者-主持人命令", client: login(db.RoleParticipant), cmd: "test.hostOnly", payload: &protocol.Empty{}, want: false},
+		{name: "主持人-主持人命令", client: login(db.RoleHost), cmd: "test.hostOnly", payload: &protocol.Empty{}, want: true},
+		{name: "所有者-主持人命令", client: login(db.RoleOwner), cmd: "test.hostOnly", payload: &protocol.Empty{}, want: true},
+		{name: "参会者-锁定房间", client: login(db.RoleParticipant), cmd: "roomAction", payload: &protocol.RoomAction{Action: "lock"}, want: false},
+		{name: "联席主持人-锁定房间", client: login(db.RoleCoHost), cmd: "roomAction", payload: &protocol.RoomAction{Action: "lock"}, want: true},
+		{name: "参会者-校验密码", client: login(db.RoleParticipant), cmd: "roomAction", payload: &protocol.RoomAction{Action: "checkPassword"}, want: true},
+		{name: "参会者-录制通知", client: login(db.RoleParticipant), cmd: "peerAction", payload: &protocol.PeerAction{Action: "recStart"}, want: true},
+		{name: "联席主持人-全部踢出", client: login(db.RoleCoHost), cmd: "peerAction", payload: &protocol.PeerAction{Action: "ejectAll"}, want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, checkPermission(test.client, test.cmd, test.payload))
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// persistQueueSize 等待执行的数据库写入数, 队列满时提交方等待
+const persistQueueSize = 10000
+
+var (
+	// persistQueue 按提交顺序执行的数据库写入, 事件循环和房间锁内不直接等待数据库
+	persistQueue   = make(chan func(), persistQueueSize)
+	persistStarted atomic.Bool
+)
+
+// startPersist 启动执行数据库写入的协程, 只启动一次
+func startPersist() {
+	if persistStarted.CompareAndSwap(false, true) {
+		go func() {
+			for task := range persistQueue {
+				task()
+			}
+		}()
+	}
+}
+
+// persist 提交数据库写入, 写入按提交顺序执行
+// 未启动时直接在当前协程执行
+func persist(task func()) {
+	if !persistStarted.Load() {
+		task()
+		return
+	}
+	persistQueue <- task
+}
+
+// flushPersist 等待已提交的数据库写入全部完成, ctx 结束时返回 ctx.Err()
+func flushPersist(ctx context.Context) error {
+	if !persistStarted.Load() {
+		return nil
+	}
+	done := make(chan struct{})
+	select {
+	case persistQueue <- func() { close(done) }:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
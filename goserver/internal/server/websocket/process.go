@@ -2,17 +2,16 @@
 package websocket
 
 import (
-	"encoding/json"
 	"sync"
 
-	"io.wandao.meeting/internal/server/models"
+	"io.wandao.meeting/internal/server/protocol"
 	log "unknwon.dev/clog/v2"
 
 	"io.wandao.meeting/internal/common"
 )
 
-// DisposeFunc 处理函数
-type DisposeFunc func(client *Client, seq string, message []byte) (code uint64, msg string, data interface{})
+// DisposeFunc 处理函数, payload 为 protocol 中按命令定义并校验过的请求数据
+type DisposeFunc func(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{})
 
 var (
 	handlers        = make(map[string]DisposeFunc)
@@ -35,22 +34,16 @@ func getHandlers(key string) (value DisposeFunc, ok bool) {
 
 // ProcessData 处理数据
 func ProcessData(client *Client, message []byte) {
-	log.Trace("[ProcessData]接收: %s | %s", client.Addr, string(message))
+	log.Trace("[ProcessData]接收: %s | %s | %d", client.Addr, client.codec.Name(), len(message))
 	defer func() {
 		if r := recover(); r != nil {
 			log.Error("[ProcessData]处理数据: %v", r)
 		}
 	}()
-	request := &models.SendRequest{}
-	if err := json.Unmarshal(message, request); err != nil {
-		log.Error("[ProcessData]解析数据失败: %v", err)
-		client.SendMsg([]byte("[ProcessData]解析数据失败"))
-		return
-	}
-	requestData, err := json.Marshal(request.Data)
+	request, err := client.codec.DecodeRequest(message)
 	if err != nil {
-		log.Error("[ProcessData]处理数据失败: %v", err)
-		client.SendMsg([]byte("处理数据失败"))
+		log.Error("[ProcessData]解析数据失败: %v", err)
+		sendResponse(client, "", "", common.ParameterIllegal, "解析数据失败", nil)
 		return
 	}
 	seq := request.Seq
@@ -58,15 +51,34 @@ func ProcessData(client *Client, message []byte) {
 	var (
 		code uint64
 		msg  string
-		// data interface{}
+		data interface{}
 	)
 
 	// 采用 map 注册的方式
 	if value, ok := getHandlers(cmd); ok {
-		code, msg, _ = value(client, seq, requestData)
+		// 数据只在这里解析一次, 处理函数直接使用解析后的结构
+		payload, err := protocol.DecodeCommand(client.codec, cmd, request.Data)
+		if err != nil {
+			log.Warn("[ProcessData]数据校验失败: %s | %s | %v", cmd, client.Addr, err)
+			sendResponse(client, seq, cmd, common.ParameterIllegal, err.Error(), nil)
+			return
+		}
+		if checkPermission(client, cmd, payload) {
+			code, msg, data = value(client, seq, payload)
+		} else {
+			code = common.Unauthorized
+			log.Warn("[ProcessData]处理数据 权限不足: %s | %s | role:%s", cmd, client.Addr, client.GetRole())
+		}
 	} else {
 		code = common.RoutingNotExist
 		log.Error("[ProcessData]处理数据 路由不存在: %s | %s", cmd, client.Addr)
 	}
-	log.Info("[ProcessData]应答: %s | roomId:%d | userId:%d | cmd:%s | code:%d | msg:%s", client.Addr, client.RoomId, client.UserId, cmd, code, msg)
+	msg = common.GetErrorMessage(code, msg)
+	sendResponse(client, seq, cmd, code, msg, data)
+	log.Info("[ProcessData]应答: %s | roomId:%d | userId:%d | cmd:%s | code:%d | msg:%s", client.Addr, client.GetRoomId(), client.GetUserId(), cmd, code, msg)
+}
+
+// sendResponse 向客户端发送应答, 回传请求的 seq 和 cmd 以便客户端对应请求
+func sendResponse(client *Client, seq string, cmd string, code uint64, msg string, data interface{}) {
+	client.SendData(protocol.NewResponseHead(seq, cmd, code, msg, data))
 }
@@ -0,0 +1,71 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+func TestProcessData(t *testing.T) {
+	conf.SetMockWebSocket(t, conf.WebSocketOpts{SendQueueSize: 8, SlowConsumer: "drop"})
+	Register("ping", PingController)
+	RequireRole("muteAll", db.RoleCoHost)
+	Register("muteAll", MuteAllController)
+
+	tests := []struct {
+		name     string
+		message  string
+		wantSeq  string
+		wantCmd  string
+		wantCode uint64
+		wantMsg  string
+		wantData interface{}
+	}{
+		{name: "成功", message: `{"seq":"1","cmd":"ping","data":{}}`, wantSeq: "1", wantCmd: "ping", wantCode: common.OK, wantData: "pong"},
+		{name: "路由不存在", message: `{"seq":"2","cmd":"notExist"}`, wantSeq: "2", wantCmd: "notExist", wantCode: common.RoutingNotExist},
+		{name: "权限不足", message: `{"seq":"3","cmd":"muteAll"}`, wantSeq: "3", wantCmd: "muteAll", wantCode: common.Unauthorized},
+		{name: "解析失败", message: `{`, wantCode: common.ParameterIllegal, wantMsg: "解析数据失败"},
+		{name: "校验失败", message: `{"seq":"4","cmd":"ping","data":[]}`, wantSeq: "4", wantCmd: "ping", wantCode: common.ParameterIllegal, wantMsg: "解析 ping 数据: json: cannot unmarshal array into Go value of type protocol.Empty"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := NewClient("127.0.0.1:1234", nil, 1, protocol.JSON)
+			ProcessData(client, []byte(test.message))
+			require.Len(t, client.Send, 1)
+
+			head := &protocol.Head{}
+			require.NoError(t, json.Unmarshal(<-client.Send, head))
+			assert.Equal(t, test.wantSeq, head.Seq)
+			assert.Equal(t, test.wantCmd, head.Cmd)
+			require.NotNil(t, head.Response)
+			assert.Equal(t, test.wantCode, head.Response.Code)
+			assert.Equal(t, common.GetErrorMessage(test.wantCode, test.wantMsg), head.Response.CodeMsg)
+			assert.Equal(t, test.wantData, head.Response.Data)
+		})
+	}
+}
+
+func TestProcessData_Msgpack(t *testing.T) {
+	conf.SetMockWebSocket(t, conf.WebSocketOpts{SendQueueSize: 8, SlowConsumer: "drop"})
+	Register("ping", PingController)
+
+	client := NewClient("127.0.0.1:1234", nil, 1, protocol.Msgpack)
+	frame, err := protocol.Msgpack.Marshal(map[string]interface{}{"seq": "1", "cmd": "ping", "data": map[string]interface{}{}})
+	require.NoError(t, err)
+	ProcessData(client, frame)
+	require.Len(t, client.Send, 1)
+
+	head := &protocol.Head{}
+	require.NoError(t, protocol.Msgpack.Unmarshal(<-client.Send, head))
+	assert.Equal(t, "1", head.Seq)
+	assert.Equal(t, "ping", head.Cmd)
+	require.NotNil(t, head.Response)
+	assert.Equal(t, uint64(common.OK), head.Response.Code)
+	assert.Equal(t, "pong", head.Response.Data)
+}
@@ -0,0 +1,179 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"context"
+	"time"
+
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/protocol"
+	"io.wandao.meeting/internal/server/recording"
+
+	log "unknwon.dev/clog/v2"
+)
+
+// StartRecordingController 主持人开始录制, 通知房间内全部成员
+// 录制文件由开始录制的客户端生成并分片上传
+func StartRecordingController(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
+	code = common.OK
+	if !client.IsLogin() {
+		code = common.NotLoggedIn
+		return
+	}
+	if !conf.Recording.Enabled {
+		code = common.OperationFailure
+		msg = "未启用会议录制"
+		return
+	}
+
+	ctx := context.Background()
+	active, err := db.Recordings.GetActive(ctx, client.GetRoomId())
+	if err != nil {
+		code = common.ServerError
+		log.Error("[WebSocket] 查询录制状态失败: %s, %v", seq, err)
+		return
+	}
+	if active != nil {
+		code = common.OperationFailure
+		msg = "房间正在录制"
+		return
+	}
+
+	r := &db.Recording{
+		RoomId:    client.GetRoomId(),
+		UserId:    client.GetUserId(),
+		Path:      recording.NewPath(client.GetRoomId()),
+		StartedAt: time.Now(),
+	}
+	if err = db.Recordings.Create(ctx, r); db.IsErrRecordingActive(err) {
+		code = common.OperationFailure
+		msg = "房间正在录制"
+		return
+	} else if err != nil {
+		code = common.ModelAddError
+		log.Error("[WebSocket] 保存录制失败: %s, %v", seq, err)
+		return
+	}
+	clientManager.SetPeerStatus(client.GetRoomId(), client.GetUserId(), "record", true)
+
+	event := newRecordingEvent(r, operatorName(client))
+	clientManager.sendRoomIdAll(protocol.NewMessage("recording", event, client.GetUserId()), client.GetRoomId(), client)
+	data = event
+	return
+}
+
+// StopRecordingController 主持人停止房间内正在进行的录制, 通知房间内全部成员
+func StopRecordingController(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
+	code = common.OK
+	if !client.IsLogin() {
+		code = common.NotLoggedIn
+		return
+	}
+	ctx := context.Background()
+	active, err := db.Recordings.GetActive(ctx, client.GetRoomId())
+	if err != nil {
+		code = common.ServerError
+		log.Error("[WebSocket] 查询录制状态失败: %s, %v", seq, err)
+		return
+	}
+	if active == nil {
+		code = common.NotData
+		msg = "房间未在录制"
+		return
+	}
+	event, err := stopRecording(ctx, active, client)
+	if err != nil {
+		code = common.ModelStoreError
+		log.Error("[WebSocket] 停止录制失败: %s, %v", seq, err)
+		return
+	}
+	data = event
+	return
+}
+
+// stopRecording 停止录制并通知房间内除 self 外的成员
+func stopRecording(ctx context.Context, active *db.Recording, self *Client) (*protocol.RecordingEvent, error) {
+	r, err := db.Recordings.Stop(ctx, active.Id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	clientManager.SetPeerStatus(r.RoomId, r.UserId, "record", false)
+
+	var from uint64
+	var userName string
+	if self != nil {
+		from, userName = self.GetUserId(), operatorName(self)
+	}
+	event := newRecordingEvent(r, userName)
+	clientManager.sendRoomIdAll(protocol.NewMessage("recording", event, from), r.RoomId, self)
+	return event, nil
+}
+
+// stopRecordingOnLeave 开始录制的主持人离开房间后停止录制, 之后无人上传录制文件
+// 在事件循环内调用, 由 persist 在后台执行
+func stopRecordingOnLeave(roomId uint64, userId uint64) {
+	if db.Recordings == nil {
+		return
+	}
+	persist(func() {
+		ctx := context.Background()
+		active, err := db.Recordings.GetActive(ctx, roomId)
+		if err != nil || active == nil || active.UserId != userId {
+			return
+		}
+		if _, err = stopRecording(ctx, active, nil); err != nil {
+			log.Error("[WebSocket] 停止录制失败: %d %v", active.Id, err)
+		}
+	})
+}
+
+// closeStaleRecordings 启动时停止开始录制的主持人已不在房间内的录制, 如节点异常退出前未停止的录制
+// 主持人仍在其它节点上的录制不做处理
+func closeStaleRecordings() {
+	if db.Recordings == nil {
+		return
+	}
+	ctx := context.Background()
+	list, err := db.Recordings.ListActive(ctx)
+	if err != nil {
+		log.Error("[WebSocket] 查询录制中的录制失败: %v", err)
+		return
+	}
+	for _, active := range list {
+		if InRoom(active.RoomId, active.UserId) {
+			continue
+		}
+		if _, err = stopRecording(ctx, active, nil); err != nil {
+			log.Error("[WebSocket] 停止遗留的录制失败: %d %v", active.Id, err)
+		}
+	}
+}
+
+// sendRecordingState 向加入正在录制的房间的成员发送录制提示, 由 persist 在后台查询
+func sendRecordingState(client *Client) {
+	if db.Recordings == nil {
+		return
+	}
+	roomId := client.GetRoomId()
+	persist(func() {
+		active, err := db.Recordings.GetActive(context.Background(), roomId)
+		if err != nil || active == nil {
+			return
+		}
+		client.SendMessage("recording", newRecordingEvent(active, ""))
+	})
+}
+
+// newRecordingEvent 录制事件, 录制状态以录制记录为准
+func newRecordingEvent(r *db.Recording, userName string) *protocol.RecordingEvent {
+	return &protocol.RecordingEvent{
+		RoomId:      r.RoomId,
+		RecordingId: r.Id,
+		UserId:      r.UserId,
+		UserName:    userName,
+		Recording:   r.Status == db.RecordingActive,
+		StartedAt:   r.StartedAt.UnixMilli(),
+	}
+}
@@ -0,0 +1,37 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+func TestRecordingController(t *testing.T) {
+	conf.SetMockRecording(t, conf.RecordingOpts{Enabled: false})
+
+	client := NewClient("127.0.0.1:1234", nil, 1, protocol.JSON)
+	code, _, _ := StartRecordingController(client, "1", &protocol.Empty{})
+	assert.Equal(t, uint64(common.NotLoggedIn), code)
+	code, _, _ = StopRecordingController(client, "1", &protocol.Empty{})
+	assert.Equal(t, uint64(common.NotLoggedIn), code)
+
+	client.Login(101, 1, db.RoleHost, 1)
+	code, msg, _ := StartRecordingController(client, "1", &protocol.Empty{})
+	assert.Equal(t, uint64(common.OperationFailure), code)
+	assert.Equal(t, "未启用会议录制", msg)
+}
+
+func TestNewRecordingEvent(t *testing.T) {
+	r := &db.Recording{Id: 3, RoomId: 101, UserId: 1, Status: db.RecordingActive}
+	event := newRecordingEvent(r, "alice")
+	assert.True(t, event.Recording)
+	assert.Equal(t, uint64(3), event.RecordingId)
+	assert.Equal(t, "alice", event.UserName)
+
+	r.Status = db.RecordingStopped
+	assert.False(t, newRecordingEvent(r, "").Recording)
+}
@@ -0,0 +1,218 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"sync"
+
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+// Room 房间内存状态, 持有房间内的连接、成员信息和等候室
+// 房间的全部状态由 lock 保护, 对外只返回副本
+type Room struct {
+	Id      uint64
+	lock    sync.RWMutex
+	clients map[uint64]*Client         // 已登录的连接 key=userId
+	peers   map[uint64]*protocol.Peers // 成员信息 key=userId
+	lobby   map[uint64]*login          // 等候室 key=userId
+	// 连接断开后挂起的会话 key=userId, 挂起期间连接仍保留在 clients 中
+	suspended map[uint64]*session
+	// 白板有自己的锁
+	whiteboard *Whiteboard
+}
+
+// NewRoom 创建房间
+func NewRoom(roomId uint64) *Room {
+	return &Room{
+		Id:      roomId,
+		clients: make(map[uint64]*Client),
+		peers:   make(map[uint64]*protocol.Peers),
+		lobby:   make(map[uint64]*login),
+
+		suspended: make(map[uint64]*session),
+
+		whiteboard: NewWhiteboard(roomId),
+	}
+}
+
+// join 加入房间, 返回加入前房间内的其他连接
+func (r *Room) join(l *login) (others []*Client) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	others = make([]*Client, 0, len(r.clients))
+	for userId, client := range r.clients {
+		if userId != l.UserId {
+			others = append(others, client)
+		}
+	}
+	// 重新登录后不再恢复之前挂起的会话
+	if s, ok := r.suspended[l.UserId]; ok {
+		s.timer.Stop()
+		delete(r.suspended, l.UserId)
+	}
+	r.clients[l.UserId] = l.Client
+	r.peers[l.UserId] = l.Peers
+	return
+}
+
+// leave 离开房间, 只有与当前登录的连接相同时才删除
+func (r *Room) leave(client *Client) (result bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if value, ok := r.clients[client.GetUserId()]; !ok || value != client {
+		return
+	}
+	delete(r.clients, client.GetUserId())
+	delete(r.peers, client.GetUserId())
+	result = true
+	return
+}
+
+// isEmpty 房间内没有成员且等候室为空
+func (r *Room) isEmpty() bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return len(r.clients) == 0 && len(r.lobby) == 0
+}
+
+// Whiteboard 房间白板
+func (r *Room) Whiteboard() *Whiteboard {
+	return r.whiteboard
+}
+
+// Len 房间内成员数
+func (r *Room) Len() int {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return len(r.clients)
+}
+
+// GetClient 获取成员的连接
+func (r *Room) GetClient(userId uint64) (client *Client) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	client = r.clients[userId]
+	return
+}
+
+// Clients 获取房间内全部连接
+func (r *Room) Clients() (clients []*Client) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	clients = make([]*Client, 0, len(r.clients))
+	for _, client := range r.clients {
+		clients = append(clients, client)
+	}
+	return
+}
+
+// UserIds 获取房间内全部成员ID
+func (r *Room) UserIds() (userIds []uint64) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	userIds = make([]uint64, 0, len(r.clients))
+	for userId := range r.clients {
+		userIds = append(userIds, userId)
+	}
+	return
+}
+
+// Peers 获取房间内全部成员信息的副本
+func (r *Room) Peers() (peers map[uint64]*protocol.Peers) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	peers = make(map[uint64]*protocol.Peers, len(r.peers))
+	for userId, peer := range r.peers {
+		value := *peer
+		peers[userId] = &value
+	}
+	return
+}
+
+// GetPeer 获取成员信息的副本
+func (r *Room) GetPeer(userId uint64) (peer *protocol.Peers) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if value, ok := r.peers[userId]; ok {
+		copied := *value
+		peer = &copied
+	}
+	return
+}
+
+// UpdatePeer 在房间锁内更新成员信息, 成员不存在时返回 false
+func (r *Room) UpdatePeer(userId uint64, f func(peer *protocol.Peers) bool) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	peer, ok := r.peers[userId]
+	if !ok {
+		return false
+	}
+	return f(peer)
+}
+
+// SetLock 更新全部成员的房间锁状态
+func (r *Room) SetLock(locked bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, peer := range r.peers {
+		peer.RoomLock = locked
+	}
+}
+
+// Broadcast 向房间内全部成员(除了 self)发送消息, 每种编码方式只序列化一次
+// 会话挂起中的成员会在恢复时收到错过的消息
+func (r *Room) Broadcast(message *protocol.Message, self *Client) {
+	f := newFrames(message)
+	for _, client := range r.Clients() {
+		if client != self {
+			f.send(client)
+		}
+	}
+	r.recordMissed(message, self)
+}
+
+// addLobby 加入等候室, 返回被替换的同一用户的等候信息
+func (r *Room) addLobby(l *login) (replaced *login) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	replaced = r.lobby[l.UserId]
+	r.lobby[l.UserId] = l
+	return
+}
+
+// delLobby 移出等候室, client 不为 nil 时只移除该连接
+func (r *Room) delLobby(userId uint64, client *Client) (l *login) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	value, ok := r.lobby[userId]
+	if !ok || (client != nil && value.Client != client) {
+		return
+	}
+	delete(r.lobby, userId)
+	l = value
+	return
+}
+
+// findLobby 查找连接所在的等候信息
+func (r *Room) findLobby(client *Client) (l *login) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for _, value := range r.lobby {
+		if value.Client == client {
+			return value
+		}
+	}
+	return
+}
+
+// Lobby 获取等候室中的成员
+func (r *Room) Lobby() (list []*login) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	list = make([]*login, 0, len(r.lobby))
+	for _, l := range r.lobby {
+		list = append(list, l)
+	}
+	return
+}
@@ -0,0 +1,132 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+func TestClientManager_Rooms(t *testing.T) {
+	conf.SetMockWebSocket(t, conf.WebSocketOpts{SendQueueSize: 8, SlowConsumer: "drop"})
+	manager := NewClientManager()
+	newLogin := func(roomId uint64, userId uint64) *login {
+		client := NewClient("127.0.0.1:1234", nil, 1, protocol.JSON)
+		client.Login(roomId, userId, db.RoleParticipant, 1)
+		return &login{
+			RoomId: roomId,
+			UserId: userId,
+			Role:   db.RoleParticipant,
+			Client: client,
+			Peers:  &protocol.Peers{RoomId: roomId, UserId: userId},
+		}
+	}
+
+	alice := newLogin(101, 1)
+	bob := newLogin(101, 2)
+	carol := newLogin(102, 3)
+	assert.Empty(t, manager.JoinRoom(alice))
+	assert.Equal(t, []*Client{alice.Client}, manager.JoinRoom(bob))
+	assert.Empty(t, manager.JoinRoom(carol))
+	assert.Equal(t, 3, manager.GetUsersLen())
+
+	t.Run("房间广播只发送给本房间成员", func(t *testing.T) {
+		manager.sendRoomIdAll(protocol.NewMessage("hello", nil, 0), 101, alice.Client)
+		assert.Len(t, bob.Client.Send, 1)
+		assert.Len(t, alice.Client.Send, 0)
+		assert.Len(t, carol.Client.Send, 0)
+		<-bob.Client.Send
+	})
+
+	t.Run("更新成员状态", func(t *testing.T) {
+		assert.True(t, manager.SetPeerStatus(101, 2, "audio", true))
+		assert.False(t, manager.SetPeerStatus(101, 2, "unknown", true))
+		assert.False(t, manager.SetPeerStatus(101, 3, "audio", true))
+		manager.SetRoomLock(101, true)
+
+		peer := manager.GetPeer(101, 2)
+		require.NotNil(t, peer)
+		assert.True(t, peer.AudioStatus)
+		assert.True(t, peer.RoomLock)
+	})
+
+	t.Run("等候中的连接不会收到房间广播", func(t *testing.T) {
+		dave := newLogin(101, 4)
+		manager.AddLobby(dave)
+		assert.True(t, manager.InLobby(dave.Client))
+		manager.sendRoomIdAll(protocol.NewMessage("hello", nil, 0), 101, nil)
+		assert.Len(t, dave.Client.Send, 0)
+		<-alice.Client.Send
+		<-bob.Client.Send
+
+		assert.Equal(t, dave, manager.DelLobbyClient(dave.Client))
+		assert.False(t, manager.InLobby(dave.Client))
+	})
+
+	t.Run("最后一名成员离开后清理房间", func(t *testing.T) {
+		// 同一用户的旧连接不能移除新连接
+		stale := NewClient("127.0.0.1:4321", nil, 1, protocol.JSON)
+		stale.Login(102, 3, db.RoleParticipant, 1)
+		assert.False(t, manager.LeaveRoom(stale))
+
+		assert.True(t, manager.LeaveRoom(carol.Client))
+		assert.Nil(t, manager.GetRoom(102))
+		assert.NotNil(t, manager.GetRoom(101))
+
+		assert.True(t, manager.LeaveRoom(alice.Client))
+		assert.True(t, manager.LeaveRoom(bob.Client))
+		assert.Nil(t, manager.GetRoom(101))
+		assert.Zero(t, manager.GetUsersLen())
+	})
+}
+
+func TestRoom_BroadcastCodecs(t *testing.T) {
+	conf.SetMockWebSocket(t, conf.WebSocketOpts{SendQueueSize: 8, SlowConsumer: "drop"})
+	room := NewRoom(101)
+	clients := map[protocol.Codec]*Client{
+		protocol.JSON:    NewClient("127.0.0.1:1234", nil, 1, protocol.JSON),
+		protocol.Msgpack: NewClient("127.0.0.1:4321", nil, 1, protocol.Msgpack),
+	}
+	userId := uint64(1)
+	for _, client := range clients {
+		client.Login(101, userId, db.RoleParticipant, 1)
+		room.join(&login{RoomId: 101, UserId: userId, Client: client, Peers: &protocol.Peers{UserId: userId}})
+		userId++
+	}
+
+	room.Broadcast(protocol.NewMessage("peerRole", &protocol.PeerRoleEvent{RoomId: 101, UserId: 1, Role: "cohost"}, 0), nil)
+	for codec, client := range clients {
+		require.Len(t, client.Send, 1)
+		message := &protocol.Message{}
+		require.NoError(t, codec.Unmarshal(<-client.Send, message), codec.Name())
+		assert.Equal(t, "peerRole", message.Cmd)
+	}
+}
+
+func TestEnterLobby_replaced(t *testing.T) {
+	conf.SetMockWebSocket(t, conf.WebSocketOpts{SendQueueSize: 8, SlowConsumer: "drop"})
+	newLogin := func() *login {
+		client := NewClient("127.0.0.1:1234", nil, 1, protocol.JSON)
+		return &login{RoomId: 320, UserId: 1, Role: db.RoleParticipant, Client: client, Peers: &protocol.Peers{RoomId: 320, UserId: 1}}
+	}
+	first := newLogin()
+	second := newLogin()
+	enterLobby(first)
+	enterLobby(second)
+	t.Cleanup(func() { clientManager.DelLobby(320, 1) })
+
+	// 同一用户从新连接进入等候室时, 旧连接收到拒绝后被关闭
+	assert.True(t, first.Client.IsClosed())
+	assert.False(t, second.Client.IsClosed())
+	assert.False(t, clientManager.InLobby(first.Client))
+	assert.True(t, clientManager.InLobby(second.Client))
+
+	message := &protocol.Message{}
+	require.Len(t, first.Client.Send, 2)
+	<-first.Client.Send
+	require.NoError(t, protocol.JSON.Unmarshal(<-first.Client.Send, message))
+	assert.Equal(t, "lobbyRejected", message.Cmd)
+}
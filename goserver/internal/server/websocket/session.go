@@ -0,0 +1,208 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"crypto/subtle"
+	"time"
+
+	"github.com/google/uuid"
+	log "unknwon.dev/clog/v2"
+
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+// maxMissedMessages 每个挂起的会话最多保留的房间事件数, 超出后丢弃最早的事件
+const maxMissedMessages = 256
+
+// session 连接断开后挂起的会话
+// 挂起期间成员信息和房间位置保留, 房间事件记录在 missed 中, 恢复时一并返回
+type session struct {
+	token  string
+	client *Client // 断开的连接
+	missed []*protocol.Message
+	timer  *time.Timer // 到期后成员离开房间
+}
+
+// issueResumeToken 为进入房间的连接生成新的恢复凭证
+func issueResumeToken(client *Client) string {
+	client.ResumeToken = uuid.NewString()
+	return client.ResumeToken
+}
+
+// suspend 挂起成员的会话, 只有与当前登录的连接相同时才挂起, 到期后调用 expire
+func (r *Room) suspend(client *Client, grace time.Duration, expire func(s *session)) (s *session) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if value, ok := r.clients[client.GetUserId()]; !ok || value != client {
+		return
+	}
+	s = &session{token: client.ResumeToken, client: client}
+	// 在锁内创建定时器, 保证 resume 和 join 总能停止它
+	s.timer = time.AfterFunc(grace, func() { expire(s) })
+	r.suspended[client.GetUserId()] = s
+	return
+}
+
+// resume 校验凭证后将新连接接入挂起的会话, 凭证不匹配或会话已到期时返回 nil
+func (r *Room) resume(userId uint64, token string, client *Client, currentTime uint64) (s *session) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	value, ok := r.suspended[userId]
+	if !ok || subtle.ConstantTimeCompare([]byte(value.token), []byte(token)) != 1 {
+		return
+	}
+	value.timer.Stop()
+	delete(r.suspended, userId)
+
+	old := value.client
+	client.ProtocolVersion = old.ProtocolVersion
+	client.Login(r.Id, userId, old.GetRole(), currentTime)
+	r.clients[userId] = client
+	return value
+}
+
+// expire 会话到期, 成员离开房间, 会话已恢复或被替换时返回 false
+func (r *Room) expire(s *session) (result bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	userId := s.client.GetUserId()
+	if r.suspended[userId] != s {
+		return
+	}
+	delete(r.suspended, userId)
+	if r.clients[userId] == s.client {
+		delete(r.clients, userId)
+		delete(r.peers, userId)
+	}
+	result = true
+	return
+}
+
+// findSession 查找连接挂起的会话
+func (r *Room) findSession(client *Client) (s *session) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if value, ok := r.suspended[client.GetUserId()]; ok && value.client == client {
+		s = value
+	}
+	return
+}
+
+// getSession 获取成员挂起的会话
+func (r *Room) getSession(userId uint64) *session {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.suspended[userId]
+}
+
+// sessions 获取房间内全部挂起的会话
+func (r *Room) sessions() (list []*session) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	list = make([]*session, 0, len(r.suspended))
+	for _, s := range r.suspended {
+		list = append(list, s)
+	}
+	return
+}
+
+// recordMissed 为挂起的会话(除了 self)记录房间事件
+func (r *Room) recordMissed(message *protocol.Message, self *Client) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, s := range r.suspended {
+		if s.client == self {
+			continue
+		}
+		if len(s.missed) >= maxMissedMessages {
+			s.missed = s.missed[1:]
+		}
+		s.missed = append(s.missed, message)
+	}
+}
+
+// SuspendRoom 连接断开后挂起成员的会话, 在 conf.WebSocket.ResumeGrace 内可使用 resume 恢复
+// 未启用、未进入房间或连接被主动关闭时返回 false, 由调用方按离开房间处理
+func (manager *ClientManager) SuspendRoom(client *Client) (ok bool) {
+	grace := conf.WebSocket.ResumeGrace
+	if grace <= 0 || client.ResumeToken == "" || client.isFinal() {
+		return
+	}
+	room := manager.GetRoom(client.GetRoomId())
+	if room == nil {
+		return
+	}
+	roomId := room.Id
+	s := room.suspend(client, grace, func(s *session) {
+		manager.expireSession(roomId, s)
+	})
+	return s != nil
+}
+
+// ResumeRoom 将新连接接入挂起的会话
+func (manager *ClientManager) ResumeRoom(roomId uint64, userId uint64, token string, client *Client, currentTime uint64) (s *session) {
+	if room := manager.GetRoom(roomId); room != nil {
+		s = room.resume(userId, token, client, currentTime)
+	}
+	return
+}
+
+// ExpireSession 立即结束连接挂起的会话, 例如成员在挂起期间被踢出
+func (manager *ClientManager) ExpireSession(client *Client) {
+	room := manager.GetRoom(client.GetRoomId())
+	if room == nil {
+		return
+	}
+	if s := room.findSession(client); s != nil {
+		s.timer.Stop()
+		manager.expireSession(room.Id, s)
+	}
+}
+
+// ExpireSessions 立即结束全部挂起的会话
+func (manager *ClientManager) ExpireSessions() {
+	for _, room := range manager.GetRooms() {
+		for _, s := range room.sessions() {
+			s.timer.Stop()
+			manager.expireSession(room.Id, s)
+		}
+	}
+}
+
+// DropSession 成员已在其它节点上重新进入房间, 结束其在本节点上挂起的会话
+// 房间内其他成员和其它节点可见的成员信息已由新节点更新, 不再通知离开
+func (manager *ClientManager) DropSession(roomId uint64, userId uint64) {
+	room := manager.GetRoom(roomId)
+	if room == nil {
+		return
+	}
+	s := room.getSession(userId)
+	if s == nil {
+		return
+	}
+	s.timer.Stop()
+	var expired bool
+	manager.withRoom(roomId, func(room *Room) {
+		expired = room.expire(s)
+	})
+	if !expired {
+		return
+	}
+	recordLeave(roomId, userId)
+	log.Info("[WebSocket] 成员已在其它节点进入房间, 结束挂起的会话: %s|%d|%d", s.client.Addr, roomId, userId)
+}
+
+// expireSession 会话到期, 按断开连接通知房间内其他成员
+func (manager *ClientManager) expireSession(roomId uint64, s *session) {
+	var expired bool
+	manager.withRoom(roomId, func(room *Room) {
+		expired = room.expire(s)
+	})
+	if !expired {
+		return
+	}
+	manager.leftRoom(roomId, s.client.GetUserId())
+	log.Info("[WebSocket] 会话已过期: %s|%d|%d", s.client.Addr, roomId, s.client.GetUserId())
+	manager.notifyLeave(s.client)
+}
@@ -0,0 +1,126 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+func TestClientManager_Resume(t *testing.T) {
+	conf.SetMockWebSocket(t, conf.WebSocketOpts{SendQueueSize: 8, SlowConsumer: "drop", ResumeGrace: time.Minute})
+	manager := NewClientManager()
+	newLogin := func(userId uint64) *login {
+		client := NewClient("127.0.0.1:1234", nil, 1, protocol.Msgpack)
+		client.Login(101, userId, db.RoleCoHost, 1)
+		client.ProtocolVersion = protocol.Version
+		issueResumeToken(client)
+		return &login{
+			RoomId: 101,
+			UserId: userId,
+			Role:   db.RoleCoHost,
+			Client: client,
+			Peers:  &protocol.Peers{RoomId: 101, UserId: userId, AudioStatus: true},
+		}
+	}
+
+	alice := newLogin(1)
+	bob := newLogin(2)
+	manager.JoinRoom(alice)
+	manager.JoinRoom(bob)
+	room := manager.GetRoom(101)
+	require.NotNil(t, room)
+
+	t.Run("主动关闭的连接不挂起", func(t *testing.T) {
+		carol := newLogin(3)
+		manager.JoinRoom(carol)
+		carol.Client.closeFinal()
+		assert.False(t, manager.SuspendRoom(carol.Client))
+		assert.True(t, manager.LeaveRoom(carol.Client))
+	})
+
+	t.Run("断开后保留成员信息并记录错过的房间事件", func(t *testing.T) {
+		bob.Client.close()
+		require.True(t, manager.SuspendRoom(bob.Client))
+		assert.Equal(t, 2, manager.GetUsersLen())
+		assert.NotNil(t, manager.GetPeer(101, 2))
+
+		manager.sendRoomIdAll(protocol.NewMessage("hello", nil, 1), 101, alice.Client)
+		manager.sendRoomIdAll(protocol.NewMessage("self", nil, 2), 101, bob.Client)
+		assert.Len(t, bob.Client.Send, 0)
+	})
+
+	t.Run("凭证不匹配时不能恢复", func(t *testing.T) {
+		client := NewClient("127.0.0.1:4321", nil, 2, protocol.JSON)
+		assert.Nil(t, manager.ResumeRoom(101, 2, "invalid", client, 2))
+		assert.False(t, client.IsLogin())
+		assert.Nil(t, manager.ResumeRoom(101, 1, alice.Client.ResumeToken, client, 2))
+	})
+
+	t.Run("恢复后新连接接入原会话", func(t *testing.T) {
+		client := NewClient("127.0.0.1:4321", nil, 2, protocol.JSON)
+		s := manager.ResumeRoom(101, 2, bob.Client.ResumeToken, client, 2)
+		require.NotNil(t, s)
+		assert.Same(t, bob.Client, s.client)
+		require.Len(t, s.missed, 1)
+		assert.Equal(t, "hello", s.missed[0].Cmd)
+
+		assert.True(t, client.IsLogin())
+		assert.Equal(t, db.RoleCoHost, client.GetRole())
+		assert.Equal(t, protocol.Version, client.ProtocolVersion)
+		assert.Same(t, client, manager.GetUserClient(101, 2))
+		assert.True(t, manager.GetPeer(101, 2).AudioStatus)
+
+		// 同一凭证只能使用一次
+		assert.Nil(t, manager.ResumeRoom(101, 2, bob.Client.ResumeToken, NewClient("127.0.0.1:4321", nil, 3, protocol.JSON), 3))
+
+		manager.sendRoomIdAll(protocol.NewMessage("hello", nil, 1), 101, alice.Client)
+		assert.Len(t, client.Send, 1)
+	})
+
+	t.Run("重新登录后挂起的会话失效", func(t *testing.T) {
+		alice.Client.close()
+		require.True(t, manager.SuspendRoom(alice.Client))
+		s := room.findSession(alice.Client)
+		require.NotNil(t, s)
+
+		again := newLogin(1)
+		manager.JoinRoom(again)
+		assert.Nil(t, room.findSession(alice.Client))
+		assert.False(t, room.expire(s))
+		assert.Same(t, again.Client, manager.GetUserClient(101, 1))
+	})
+
+	t.Run("到期后成员离开房间", func(t *testing.T) {
+		client := manager.GetUserClient(101, 1)
+		s := room.suspend(client, time.Minute, func(*session) {})
+		require.NotNil(t, s)
+		s.timer.Stop()
+
+		assert.True(t, room.expire(s))
+		assert.Nil(t, manager.GetUserClient(101, 1))
+		assert.Nil(t, manager.GetPeer(101, 1))
+		assert.Nil(t, manager.ResumeRoom(101, 1, client.ResumeToken, NewClient("127.0.0.1:4321", nil, 3, protocol.JSON), 3))
+	})
+}
+
+func TestRoom_RecordMissed(t *testing.T) {
+	conf.SetMockWebSocket(t, conf.WebSocketOpts{SendQueueSize: 8, SlowConsumer: "drop"})
+	room := NewRoom(101)
+	client := NewClient("127.0.0.1:1234", nil, 1, protocol.JSON)
+	client.Login(101, 1, db.RoleParticipant, 1)
+	room.join(&login{RoomId: 101, UserId: 1, Client: client, Peers: &protocol.Peers{}})
+	s := room.suspend(client, time.Minute, func(*session) {})
+	require.NotNil(t, s)
+	defer s.timer.Stop()
+
+	for i := 0; i < maxMissedMessages+10; i++ {
+		room.recordMissed(protocol.NewMessage("hello", i, 0), nil)
+	}
+	require.Len(t, s.missed, maxMissedMessages)
+	assert.Equal(t, 10, s.missed[0].Data)
+}
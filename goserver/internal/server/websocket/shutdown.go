@@ -0,0 +1,70 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"context"
+	"time"
+
+	log "unknwon.dev/clog/v2"
+
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+const (
+	// shutdownRetryAfter 建议客户端重新连接的等待时间, 留出负载均衡摘除本节点的时间
+	shutdownRetryAfter = 3 * time.Second
+	drainCheckInterval = 100 * time.Millisecond
+)
+
+// Shutdown 停止接受新连接, 通知全部客户端重新连接后关闭连接
+// 等待连接全部断开, ctx 结束时返回 ctx.Err()
+func Shutdown(ctx context.Context) (err error) {
+	draining.Store(true)
+	if webrtcServer != nil {
+		// 只关闭监听, 已升级的 websocket 连接不受影响
+		_ = webrtcServer.Shutdown(ctx)
+	}
+
+	err = drainClients(ctx, clientManager)
+	// 挂起的会话无法再恢复, 通知其它节点上的成员离开
+	clientManager.ExpireSessions()
+	// 等待房间清理时提交的白板快照写入数据库
+	if e := flushPersist(ctx); e != nil {
+		log.Warn("[websocket]关闭服务超时, 未完成全部数据库写入: %v", e)
+		if err == nil {
+			err = e
+		}
+	}
+	if cluster != nil {
+		cluster.Close()
+	}
+	return
+}
+
+// drainClients 向全部连接发送 serverShutdown 事件并关闭, 等待连接断开
+func drainClients(ctx context.Context, manager *ClientManager) error {
+	clients := manager.GetClients()
+	log.Info("[websocket]关闭服务, 断开连接数: %d", len(clients))
+	f := newFrames(protocol.NewMessage(protocol.EventServerShutdown, &protocol.ServerShutdownEvent{
+		Reconnect:  true,
+		RetryAfter: shutdownRetryAfter.Milliseconds(),
+		Message:    "服务器维护中, 请重新连接",
+	}, 0))
+	for client := range clients {
+		f.send(client)
+		// 关闭前会先发出队列中的数据
+		client.closeFinal()
+	}
+
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+	for manager.GetClientsLen() > 0 {
+		select {
+		case <-ctx.Done():
+			log.Warn("[websocket]关闭服务超时, 剩余连接数: %d", manager.GetClientsLen())
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/conf"
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+func TestDrainClients(t *testing.T) {
+	conf.SetMockWebSocket(t, conf.WebSocketOpts{SendQueueSize: 8, SlowConsumer: "drop"})
+
+	t.Run("通知客户端并关闭连接", func(t *testing.T) {
+		manager := NewClientManager()
+		client := NewClient("127.0.0.1:1234", nil, 1, protocol.JSON)
+		manager.AddClients(client)
+		go func() {
+			// 模拟 write 退出后的断开连接事件
+			<-client.done
+			manager.DelClients(client)
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		require.NoError(t, drainClients(ctx, manager))
+		assert.True(t, client.IsClosed())
+
+		require.Len(t, client.Send, 1)
+		message := &struct {
+			Cmd  string                        `json:"cmd"`
+			Data *protocol.ServerShutdownEvent `json:"data"`
+		}{}
+		require.NoError(t, protocol.JSON.Unmarshal(<-client.Send, message))
+		assert.Equal(t, protocol.EventServerShutdown, message.Cmd)
+		require.NotNil(t, message.Data)
+		assert.True(t, message.Data.Reconnect)
+		assert.Positive(t, message.Data.RetryAfter)
+	})
+
+	t.Run("等待超时", func(t *testing.T) {
+		manager := NewClientManager()
+		manager.AddClients(NewClient("127.0.0.1:1234", nil, 1, protocol.JSON))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, drainClients(ctx, manager), context.DeadlineExceeded)
+	})
+}
+
+func TestFlushPersist(t *testing.T) {
+	require.NoError(t, flushPersist(context.Background()), "未启动时直接返回")
+
+	// 模拟执行写入的协程阻塞, 队列中的写入一直未完成
+	persistStarted.Store(true)
+	t.Cleanup(func() {
+		persistStarted.Store(false)
+		for len(persistQueue) > 0 {
+			<-persistQueue
+		}
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, flushPersist(ctx), context.DeadlineExceeded)
+}
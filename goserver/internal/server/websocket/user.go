@@ -18,6 +18,16 @@ func UserList(roomId uint64) (userList []uint64) {
 	return
 }
 
+// InRoom 成员是否在房间内, 包括其它节点上的成员
+func InRoom(roomId uint64, userId uint64) bool {
+	for _, id := range clientManager.GetUserList(roomId) {
+		if id == userId {
+			return true
+		}
+	}
+	return false
+}
+
 // CheckUserOnline 查询用户是否在线
 func CheckUserOnline(roomId uint64, userId uint64) (online bool) {
 	// 全平台查询
@@ -49,3 +59,13 @@ func checkUserOnline(roomId uint64, userId uint64) (online bool, err error) {
 	online = userOnline.IsOnline()
 	return
 }
+
+// refreshUserOnline 按连接的心跳时间刷新 Redis 中的在线状态, 没有在线信息时返回 redis.Nil
+func refreshUserOnline(client *Client) (err error) {
+	userOnline, err := cache.GetUserOnlineInfo(client.GetKey())
+	if err != nil {
+		return
+	}
+	userOnline.Heartbeat(client.HeartbeatTime())
+	return cache.SetUserOnlineInfo(client.GetKey(), userOnline)
+}
@@ -1,68 +1,95 @@
 package websocket
 
 import (
-	"github.com/gin-gonic/gin"
 	"io.wandao.meeting/internal/conf"
-	"io.wandao.meeting/internal/server/models"
+	"io.wandao.meeting/internal/server/protocol"
 )
 
-func getIceServers() (iceServers []map[string]interface{}) {
+func getIceServers() (iceServers []protocol.IceServer) {
 	if conf.Ice.StunEnabled {
-		iceServers = append(iceServers, gin.H{
-			"urls": conf.Ice.StunUrls,
+		iceServers = append(iceServers, protocol.IceServer{
+			Urls: conf.Ice.StunUrls,
 		})
 	}
 
 	if conf.Ice.TurnEnabled {
-		iceServers = append(iceServers, gin.H{
-			"urls":       conf.Ice.TurnUrls,
-			"username":   conf.Ice.TurnUsername,
-			"credential": conf.Ice.TurnCredential,
+		iceServers = append(iceServers, protocol.IceServer{
+			Urls:       conf.Ice.TurnUrls,
+			Username:   conf.Ice.TurnUsername,
+			Credential: conf.Ice.TurnCredential,
 		})
 	}
 	return
 }
 
 // CreateRoomRTCPeerConnection 用户登录后 通知客户端创建 offer
-// 应先于 clientManager.AddUsers 执行该通知
-func CreateRoomRTCPeerConnection(client *Client) {
-	clientManager.UserLock.RLock()
-	defer clientManager.UserLock.RUnlock()
-	// 用户登录后，尚未注册该用户到 clientManager.Users
-	// 因此只会向已经注册的用户发送通知
-	for _, other := range clientManager.Users {
-		if other.RoomId == client.RoomId {
-			// 向其它用户发送通知
-			other.SendCreateRTCPeerConnection(client.UserId, false)
-			// 向自己发送通知
-			client.SendCreateRTCPeerConnection(other.UserId, true)
+// others 为用户加入房间前已在房间内的连接
+func CreateRoomRTCPeerConnection(client *Client, others []*Client) {
+	for _, other := range others {
+		// 向其它用户发送通知
+		other.SendCreateRTCPeerConnection(client.GetUserId(), false)
+		// 向自己发送通知
+		client.SendCreateRTCPeerConnection(other.GetUserId(), true)
+	}
+}
+
+// createRemoteRTCPeerConnection 用户登录后 与其它节点上的房间成员互相通知创建连接
+func createRemoteRTCPeerConnection(client *Client) {
+	if cluster == nil {
+		return
+	}
+	for userId := range cluster.RemotePeers(client.GetRoomId()) {
+		if clientManager.GetUserClient(client.GetRoomId(), userId) != nil {
+			continue
 		}
+		// 向其它节点上的用户发送通知
+		sendUserMessage(client.GetRoomId(), userId, "createRTCPeerConnection", newCreateRTCPeerConnectionEvent(client.GetRoomId(), client.GetUserId(), false))
+		// 向自己发送通知
+		client.SendCreateRTCPeerConnection(userId, true)
+	}
+}
+
+func newCreateRTCPeerConnectionEvent(roomId uint64, userId uint64, createOffer bool) *protocol.CreateRTCPeerConnectionEvent {
+	return &protocol.CreateRTCPeerConnectionEvent{
+		UserId:            userId,
+		Peers:             clientManager.GetRoomPeers(roomId),
+		ShouldCreateOffer: createOffer,
+		IceServers:        getIceServers(),
 	}
 }
 
 func (c *Client) SendCreateRTCPeerConnection(userId uint64, createOffer bool) {
-	iceServers := getIceServers()
-	peers := clientManager.GetRoomPeers(c.RoomId)
-	c.SendMessage("createRTCPeerConnection", gin.H{
-		"userId":            userId,
-		"peers":             peers,
-		"shouldCreateOffer": createOffer,
-		"iceServers":        iceServers,
-	})
+	c.SendMessage("createRTCPeerConnection", newCreateRTCPeerConnectionEvent(c.GetRoomId(), userId, createOffer))
 }
 
-func (c *Client) SendIceCandidate(request *models.IceCandidateRequest) {
-	client := clientManager.GetUserClient(c.RoomId, request.UserId)
-	client.SendMessage("iceCandidate", gin.H{
-		"userId":       c.UserId,
-		"iceCandidate": request.IceCandidate,
+func (c *Client) SendIceCandidate(request *protocol.IceCandidateRequest) {
+	sendUserMessage(c.GetRoomId(), request.UserId, "iceCandidate", &protocol.IceCandidateEvent{
+		UserId:       c.GetUserId(),
+		IceCandidate: request.IceCandidate,
 	})
 }
 
-func (c *Client) SendSessionDescription(request *models.SessionDescriptionRequest) {
-	client := clientManager.GetUserClient(c.RoomId, request.UserId)
-	client.SendMessage("sessionDescription", gin.H{
-		"userId":             c.UserId,
-		"sessionDescription": request.SessionDescription,
+func (c *Client) SendSessionDescription(request *protocol.SessionDescriptionRequest) {
+	sendUserMessage(c.GetRoomId(), request.UserId, "sessionDescription", &protocol.SessionDescriptionEvent{
+		UserId:             c.GetUserId(),
+		SessionDescription: request.SessionDescription,
 	})
 }
+
+// sendUserMessage 向房间内的成员发送消息, 成员不在本节点时经 Redis 转发到所在节点
+// 成员不在房间内时返回 false
+func sendUserMessage(roomId uint64, userId uint64, cmd string, data interface{}) (ok bool) {
+	return sendToUser(roomId, userId, protocol.NewMessage(cmd, data, userId))
+}
+
+// sendToUser 向房间内的成员发送已构造的消息, 规则同 sendUserMessage
+func sendToUser(roomId uint64, userId uint64, message *protocol.Message) (ok bool) {
+	if client := clientManager.GetUserClient(roomId, userId); client != nil {
+		client.SendData(message)
+		return true
+	}
+	if cluster != nil {
+		return cluster.SendToUser(roomId, userId, message)
+	}
+	return
+}
@@ -0,0 +1,390 @@
+// Package websocket 处理
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/libs/cache"
+	"io.wandao.meeting/internal/libs/redislib"
+	"io.wandao.meeting/internal/server/protocol"
+
+	log "unknwon.dev/clog/v2"
+)
+
+// maxWhiteboardOps 每个白板最多保留的绘制数, 超出后需清空白板才能继续绘制
+const maxWhiteboardOps = 5000
+
+// Whiteboard 房间白板, 按顺序记录当前画布上的绘制, 加入房间的成员据此还原画布
+// 首次使用时加载快照, 房间内最后一名成员离开时在房间锁内取得快照, 释放锁后写入数据库
+// 连接 Redis 时绘制保存在 Redis 中, 各节点共享同一份绘制列表和绘制序号
+type Whiteboard struct {
+	roomId uint64
+	lock   sync.Mutex
+	loaded bool
+	shared bool   // 绘制是否保存在 Redis 中
+	seq    uint64 // 已分配的最大绘制序号
+	ops    []*protocol.WhiteboardOp
+	locked bool // 锁定后只有主持人可以操作
+}
+
+var (
+	// pendingWhiteboards 已取得但尚未写入数据库的白板快照, 重新创建的白板优先从这里加载
+	pendingWhiteboards     = make(map[uint64]*db.RoomWhiteboard)
+	pendingWhiteboardsLock sync.Mutex
+)
+
+// NewWhiteboard 创建白板
+func NewWhiteboard(roomId uint64) *Whiteboard {
+	return &Whiteboard{roomId: roomId, shared: redislib.GetClient() != nil, ops: make([]*protocol.WhiteboardOp, 0)}
+}
+
+// load 在锁内加载快照, 只加载一次
+// 绘制保存在 Redis 中时, 由首个使用白板的节点用快照初始化
+func (w *Whiteboard) load() {
+	if w.loaded {
+		return
+	}
+	w.loaded = true
+	if w.shared {
+		if exists, err := cache.WhiteboardExists(w.roomId); err != nil || exists {
+			return
+		}
+	}
+	board := w.loadSnapshot()
+	if board == nil {
+		board = &db.RoomWhiteboard{RoomId: w.roomId}
+	}
+	if w.shared {
+		raw := make([]json.RawMessage, 0)
+		if board.Ops != "" {
+			if err := json.Unmarshal([]byte(board.Ops), &raw); err != nil {
+				log.Error("[Whiteboard]解析白板快照失败: %d %v", w.roomId, err)
+			}
+		}
+		ops := make([]string, 0, len(raw))
+		for _, op := range raw {
+			ops = append(ops, string(op))
+		}
+		_ = cache.InitWhiteboard(w.roomId, board.Seq, ops, board.Locked)
+		return
+	}
+	ops := make([]*protocol.WhiteboardOp, 0)
+	if board.Ops != "" {
+		if err := json.Unmarshal([]byte(board.Ops), &ops); err != nil {
+			log.Error("[Whiteboard]解析白板快照失败: %d %v", w.roomId, err)
+			return
+		}
+	}
+	w.seq, w.ops, w.locked = board.Seq, ops, board.Locked
+}
+
+// loadSnapshot 待写入的快照或数据库中的快照, 都没有时返回 nil
+func (w *Whiteboard) loadSnapshot() *db.RoomWhiteboard {
+	pendingWhiteboardsLock.Lock()
+	board := pendingWhiteboards[w.roomId]
+	pendingWhiteboardsLock.Unlock()
+	if board != nil || db.RoomWhiteboards == nil {
+		return board
+	}
+	board, err := db.RoomWhiteboards.Get(context.Background(), w.roomId)
+	if err != nil {
+		log.Error("[Whiteboard]加载白板快照失败: %d %v", w.roomId, err)
+		return nil
+	}
+	return board
+}
+
+// apply 执行本节点成员的白板操作, 返回需要广播的事件
+// host 为 true 时不受锁定限制
+func (w *Whiteboard) apply(userId uint64, host bool, request *protocol.WhiteboardRequest) (event *protocol.WhiteboardEvent, code uint64) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.load()
+	if w.shared {
+		return w.applyShared(userId, host, request)
+	}
+	code = common.OK
+	if w.locked && !host {
+		code = common.Unauthorized
+		return
+	}
+
+	event = &protocol.WhiteboardEvent{RoomId: w.roomId, UserId: userId, Action: request.Action}
+	switch request.Action {
+	case "draw":
+		if request.Op == nil {
+			code = common.ParameterIllegal
+			return nil, code
+		}
+		if len(w.ops) >= maxWhiteboardOps {
+			code = common.OperationFailure
+			return nil, code
+		}
+		w.seq++
+		event.Seq, event.Op = w.seq, request.Op
+	case "undo":
+		// 只撤销自己最近的一次绘制
+		for i := len(w.ops) - 1; i >= 0; i-- {
+			if w.ops[i].UserId == userId {
+				event.Seq = w.ops[i].Seq
+				break
+			}
+		}
+		if event.Seq == 0 {
+			code = common.NotData
+			return nil, code
+		}
+	}
+	w.mirror(event)
+	return
+}
+
+// applyShared 在 Redis 中执行白板操作, 绘制序号由 Redis 分配
+func (w *Whiteboard) applyShared(userId uint64, host bool, request *protocol.WhiteboardRequest) (event *protocol.WhiteboardEvent, code uint64) {
+	length, locked, err := cache.GetWhiteboardMeta(w.roomId)
+	if err != nil {
+		return nil, common.ServerError
+	}
+	if locked && !host {
+		return nil, common.Unauthorized
+	}
+
+	code = common.OK
+	event = &protocol.WhiteboardEvent{RoomId: w.roomId, UserId: userId, Action: request.Action}
+	switch request.Action {
+	case "draw":
+		if request.Op == nil {
+			return nil, common.ParameterIllegal
+		}
+		if length >= maxWhiteboardOps {
+			return nil, common.OperationFailure
+		}
+		if event.Seq, err = cache.IncrWhiteboardSeq(w.roomId); err != nil {
+			return nil, common.ServerError
+		}
+		event.Op = request.Op
+		data, err := json.Marshal(&protocol.WhiteboardOp{Seq: event.Seq, UserId: userId, Op: event.Op})
+		if err != nil {
+			return nil, common.ParameterIllegal
+		}
+		err = cache.PushWhiteboardOp(w.roomId, string(data))
+	case "undo":
+		// 只撤销自己最近的一次绘制
+		_, ops, _, err := cache.GetWhiteboard(w.roomId)
+		if err != nil {
+			return nil, common.ServerError
+		}
+		for i := len(ops) - 1; i >= 0; i-- {
+			op := &protocol.WhiteboardOp{}
+			if json.Unmarshal([]byte(ops[i]), op) != nil || op.UserId != userId {
+				continue
+			}
+			if removed, _ := cache.RemoveWhiteboardOp(w.roomId, ops[i]); removed {
+				event.Seq = op.Seq
+			}
+			break
+		}
+		if event.Seq == 0 {
+			return nil, common.NotData
+		}
+	case "clear":
+		err = cache.ClearWhiteboard(w.roomId)
+	case "lock", "unlock":
+		err = cache.SetWhiteboardLocked(w.roomId, request.Action == "lock")
+	}
+	if err != nil {
+		return nil, common.ServerError
+	}
+	return
+}
+
+// mirror 在锁内将白板事件应用到绘制列表
+func (w *Whiteboard) mirror(event *protocol.WhiteboardEvent) {
+	switch event.Action {
+	case "draw":
+		w.ops = append(w.ops, &protocol.WhiteboardOp{Seq: event.Seq, UserId: event.UserId, Op: event.Op})
+	case "undo":
+		for i := len(w.ops) - 1; i >= 0; i-- {
+			if w.ops[i].UserId == event.UserId && w.ops[i].Seq == event.Seq {
+				w.ops = append(w.ops[:i], w.ops[i+1:]...)
+				break
+			}
+		}
+	case "clear":
+		w.ops = make([]*protocol.WhiteboardOp, 0)
+	case "lock":
+		w.locked = true
+	case "unlock":
+		w.locked = false
+	}
+}
+
+// State 白板当前状态的副本
+func (w *Whiteboard) State() *protocol.WhiteboardStateEvent {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.load()
+	if w.shared {
+		state := &protocol.WhiteboardStateEvent{RoomId: w.roomId, Ops: make([]*protocol.WhiteboardOp, 0)}
+		_, ops, locked, err := cache.GetWhiteboard(w.roomId)
+		if err != nil {
+			return state
+		}
+		state.Locked = locked
+		for _, data := range ops {
+			op := &protocol.WhiteboardOp{}
+			if err = json.Unmarshal([]byte(data), op); err == nil {
+				state.Ops = append(state.Ops, op)
+			}
+		}
+		return state
+	}
+	ops := make([]*protocol.WhiteboardOp, len(w.ops))
+	copy(ops, w.ops)
+	return &protocol.WhiteboardStateEvent{RoomId: w.roomId, Locked: w.locked, Ops: ops}
+}
+
+// sharedSnapshot 读取 Redis 中的白板, 读取失败时返回 nil
+func (w *Whiteboard) sharedSnapshot() *db.RoomWhiteboard {
+	seq, ops, locked, err := cache.GetWhiteboard(w.roomId)
+	if err != nil {
+		return nil
+	}
+	board := &db.RoomWhiteboard{RoomId: w.roomId, Locked: locked, Seq: seq}
+	if len(ops) > 0 {
+		raw := make([]json.RawMessage, 0, len(ops))
+		for _, op := range ops {
+			raw = append(raw, json.RawMessage(op))
+		}
+		data, err := json.Marshal(raw)
+		if err != nil {
+			log.Error("[Whiteboard]编码白板快照失败: %d %v", w.roomId, err)
+			return nil
+		}
+		board.Ops = string(data)
+	}
+	return board
+}
+
+// release 房间清理时在 RoomsLock 内调用, 返回释放锁后写入快照的操作, 无需写入时返回 nil
+// 绘制保存在 Redis 中时, 写入时读取各节点共享的最新白板
+func (w *Whiteboard) release() func() {
+	if w.shared {
+		w.lock.Lock()
+		loaded := w.loaded
+		w.lock.Unlock()
+		if !loaded || db.RoomWhiteboards == nil {
+			return nil
+		}
+		return func() {
+			persist(func() {
+				if board := w.sharedSnapshot(); board != nil {
+					writeWhiteboard(board)
+				}
+			})
+		}
+	}
+	board := w.snapshot()
+	if board == nil {
+		return nil
+	}
+	return func() { saveWhiteboard(board) }
+}
+
+// snapshot 取得白板快照并记录为待写入, 未加载过的白板返回 nil
+// 在 RoomsLock 内调用, 保证重新创建的白板能加载到最新的快照
+func (w *Whiteboard) snapshot() *db.RoomWhiteboard {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if !w.loaded || db.RoomWhiteboards == nil {
+		return nil
+	}
+	board := &db.RoomWhiteboard{RoomId: w.roomId, Locked: w.locked, Seq: w.seq}
+	if len(w.ops) > 0 {
+		data, err := json.Marshal(w.ops)
+		if err != nil {
+			log.Error("[Whiteboard]编码白板快照失败: %d %v", w.roomId, err)
+			return nil
+		}
+		board.Ops = string(data)
+	}
+	pendingWhiteboardsLock.Lock()
+	pendingWhiteboards[w.roomId] = board
+	pendingWhiteboardsLock.Unlock()
+	return board
+}
+
+// saveWhiteboard 提交快照的写入, 写入完成前重新创建的白板从 pendingWhiteboards 加载
+func saveWhiteboard(board *db.RoomWhiteboard) {
+	persist(func() {
+		defer func() {
+			pendingWhiteboardsLock.Lock()
+			if pendingWhiteboards[board.RoomId] == board {
+				delete(pendingWhiteboards, board.RoomId)
+			}
+			pendingWhiteboardsLock.Unlock()
+		}()
+		writeWhiteboard(board)
+	})
+}
+
+// writeWhiteboard 将快照写入数据库, 白板为空且未锁定时删除快照
+func writeWhiteboard(board *db.RoomWhiteboard) {
+	ctx := context.Background()
+	if board.Ops == "" && !board.Locked {
+		if err := db.RoomWhiteboards.Delete(ctx, board.RoomId); err != nil {
+			log.Error("[Whiteboard]删除白板快照失败: %d %v", board.RoomId, err)
+		}
+		return
+	}
+	if err := db.RoomWhiteboards.Save(ctx, board); err != nil {
+		log.Error("[Whiteboard]保存白板快照失败: %d %v", board.RoomId, err)
+	}
+}
+
+// WhiteboardController 白板操作, 广播给房间内的其他成员, 操作者从应答中获得事件
+func WhiteboardController(client *Client, seq string, payload interface{}) (code uint64, msg string, data interface{}) {
+	request, ok := payload.(*protocol.WhiteboardRequest)
+	if !ok {
+		code = common.ParameterIllegal
+		return
+	}
+	if !client.IsLogin() {
+		code = common.NotLoggedIn
+		return
+	}
+	room := clientManager.GetRoom(client.GetRoomId())
+	if room == nil {
+		code = common.NotRoom
+		return
+	}
+	event, code := room.Whiteboard().apply(client.GetUserId(), client.GetRole().AtLeast(db.RoleHost), request)
+	switch code {
+	case common.OK:
+	case common.Unauthorized:
+		msg = "白板已锁定"
+		return
+	case common.OperationFailure:
+		msg = "白板内容过多, 请清空后继续"
+		return
+	default:
+		return
+	}
+
+	clientManager.sendRoomIdAll(protocol.NewMessage("whiteboard", event, client.GetUserId()), client.GetRoomId(), client)
+	data = event
+	return
+}
+
+// sendWhiteboardState 向加入房间的成员发送白板当前状态
+func sendWhiteboardState(client *Client) {
+	room := clientManager.GetRoom(client.GetRoomId())
+	if room == nil {
+		return
+	}
+	client.SendMessage("whiteboardState", room.Whiteboard().State())
+}
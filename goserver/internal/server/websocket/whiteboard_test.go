@@ -0,0 +1,114 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/common"
+	"io.wandao.meeting/internal/db"
+	"io.wandao.meeting/internal/server/protocol"
+)
+
+func TestWhiteboard(t *testing.T) {
+	board := NewWhiteboard(101)
+	draw := func(userId uint64, op string) *protocol.WhiteboardEvent {
+		event, code := board.apply(userId, false, &protocol.WhiteboardRequest{Action: "draw", Op: op})
+		require.Equal(t, uint64(common.OK), code)
+		return event
+	}
+
+	t.Run("按顺序记录绘制", func(t *testing.T) {
+		assert.Equal(t, uint64(1), draw(1, "a").Seq)
+		assert.Equal(t, uint64(2), draw(2, "b").Seq)
+		assert.Equal(t, uint64(3), draw(1, "c").Seq)
+
+		_, code := board.apply(1, false, &protocol.WhiteboardRequest{Action: "draw"})
+		assert.Equal(t, uint64(common.ParameterIllegal), code)
+
+		state := board.State()
+		require.Len(t, state.Ops, 3)
+		assert.Equal(t, "a", state.Ops[0].Op)
+		assert.Equal(t, "c", state.Ops[2].Op)
+	})
+
+	t.Run("只撤销自己最近的绘制", func(t *testing.T) {
+		event, code := board.apply(1, false, &protocol.WhiteboardRequest{Action: "undo"})
+		require.Equal(t, uint64(common.OK), code)
+		assert.Equal(t, uint64(3), event.Seq)
+
+		_, code = board.apply(3, false, &protocol.WhiteboardRequest{Action: "undo"})
+		assert.Equal(t, uint64(common.NotData), code)
+
+		ops := board.State().Ops
+		require.Len(t, ops, 2)
+		assert.Equal(t, uint64(2), ops[1].Seq)
+	})
+
+	t.Run("锁定后只有主持人可以操作", func(t *testing.T) {
+		_, code := board.apply(1, true, &protocol.WhiteboardRequest{Action: "lock"})
+		require.Equal(t, uint64(common.OK), code)
+		assert.True(t, board.State().Locked)
+
+		_, code = board.apply(2, false, &protocol.WhiteboardRequest{Action: "draw", Op: "d"})
+		assert.Equal(t, uint64(common.Unauthorized), code)
+		_, code = board.apply(1, true, &protocol.WhiteboardRequest{Action: "unlock"})
+		require.Equal(t, uint64(common.OK), code)
+	})
+
+	t.Run("清空白板", func(t *testing.T) {
+		_, code := board.apply(2, false, &protocol.WhiteboardRequest{Action: "clear"})
+		require.Equal(t, uint64(common.OK), code)
+		assert.Empty(t, board.State().Ops)
+	})
+}
+
+// memWhiteboards 内存中的白板快照存储
+type memWhiteboards struct {
+	boards map[uint64]*db.RoomWhiteboard
+}
+
+func (m *memWhiteboards) Get(_ context.Context, roomId uint64) (*db.RoomWhiteboard, error) {
+	if board, ok := m.boards[roomId]; ok {
+		return board, nil
+	}
+	return &db.RoomWhiteboard{RoomId: roomId}, nil
+}
+
+func (m *memWhiteboards) Save(_ context.Context, board *db.RoomWhiteboard) error {
+	m.boards[board.RoomId] = board
+	return nil
+}
+
+func (m *memWhiteboards) Delete(_ context.Context, roomId uint64) error {
+	delete(m.boards, roomId)
+	return nil
+}
+
+func TestWhiteboard_snapshot(t *testing.T) {
+	store := &memWhiteboards{boards: make(map[uint64]*db.RoomWhiteboard)}
+	before := db.RoomWhiteboards
+	db.RoomWhiteboards = store
+	t.Cleanup(func() { db.RoomWhiteboards = before })
+
+	board := NewWhiteboard(102)
+	_, code := board.apply(1, false, &protocol.WhiteboardRequest{Action: "draw", Op: "a"})
+	require.Equal(t, uint64(common.OK), code)
+	snapshot := board.snapshot()
+	require.NotNil(t, snapshot)
+
+	// 写入数据库前重新创建的白板加载待写入的快照
+	assert.Len(t, NewWhiteboard(102).State().Ops, 1)
+	assert.Empty(t, store.boards)
+
+	saveWhiteboard(snapshot)
+	assert.Equal(t, uint64(1), store.boards[102].Seq)
+	assert.Empty(t, pendingWhiteboards)
+
+	// 清空后删除快照
+	_, code = board.apply(1, false, &protocol.WhiteboardRequest{Action: "clear"})
+	require.Equal(t, uint64(common.OK), code)
+	saveWhiteboard(board.snapshot())
+	assert.Empty(t, store.boards)
+}
@@ -0,0 +1,167 @@
+package icalutil
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType iCalendar 文件的 MIME 类型
+const ContentType = "text/calendar; charset=utf-8"
+
+// dateTimeLayout UTC 时间格式
+const dateTimeLayout = "20060102T150405Z"
+
+// maxLineOctets 内容行最大字节数, 超出后折行
+const maxLineOctets = 75
+
+// Person 组织者或参会人
+type Person struct {
+	Name  string
+	Email string
+}
+
+// Event 日历事件(VEVENT)
+type Event struct {
+	UID         string
+	Sequence    int // 每次修改递增, 日历客户端据此更新已导入的事件
+	Summary     string
+	Description string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	Stamp       time.Time
+	Recurrence  *Recurrence
+	Canceled    bool
+	Organizer   *Person
+	Attendees   []*Person
+}
+
+// Calendar 日历(VCALENDAR)
+type Calendar struct {
+	Name   string
+	Events []*Event
+}
+
+// WriteTo 按 RFC 5545 输出日历, 行以 CRLF 结尾, 超长行折行
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+	cw := &contentWriter{w: bufio.NewWriter(w)}
+	cw.line("BEGIN", "VCALENDAR")
+	cw.line("VERSION", "2.0")
+	cw.line("PRODID", "-//wandao//meeting//CN")
+	cw.line("CALSCALE", "GREGORIAN")
+	cw.line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		cw.line("X-WR-CALNAME", escape(c.Name))
+	}
+	for _, event := range c.Events {
+		cw.event(event)
+	}
+	cw.line("END", "VCALENDAR")
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// contentWriter 逐行输出内容行, 记录第一个错误
+type contentWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *contentWriter) event(e *Event) {
+	cw.line("BEGIN", "VEVENT")
+	cw.line("UID", escape(e.UID))
+	cw.line("SEQUENCE", strconv.Itoa(e.Sequence))
+	cw.line("DTSTAMP", formatTime(e.Stamp))
+	cw.line("DTSTART", formatTime(e.Start))
+	cw.line("DTEND", formatTime(e.End))
+	if e.Recurrence != nil {
+		cw.line("RRULE", e.Recurrence.String())
+	}
+	cw.line("SUMMARY", escape(e.Summary))
+	if e.Description != "" {
+		cw.line("DESCRIPTION", escape(e.Description))
+	}
+	if e.URL != "" {
+		cw.line("URL", e.URL)
+	}
+	if e.Organizer != nil {
+		cw.line("ORGANIZER"+personParams(e.Organizer), mailto(e.Organizer))
+	}
+	for _, attendee := range e.Attendees {
+		cw.line("ATTENDEE"+personParams(attendee), mailto(attendee))
+	}
+	if e.Canceled {
+		cw.line("STATUS", "CANCELLED")
+	} else {
+		cw.line("STATUS", "CONFIRMED")
+	}
+	cw.line("END", "VEVENT")
+}
+
+// line 输出一个内容行, 按字节数折行且不拆分 UTF-8 字符
+func (cw *contentWriter) line(name, value string) {
+	if cw.err != nil {
+		return
+	}
+	s := name + ":" + value
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		cw.write(s[:cut] + "\r\n ")
+		s = s[cut:]
+		// 续行以空格开头, 占用一个字节
+		limit = maxLineOctets - 1
+	}
+	cw.write(s + "\r\n")
+}
+
+func (cw *contentWriter) write(s string) {
+	if cw.err != nil {
+		return
+	}
+	n, err := cw.w.WriteString(s)
+	cw.n += int64(n)
+	cw.err = err
+}
+
+// escape 转义 TEXT 类型的值
+func escape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", "",
+	).Replace(s)
+}
+
+// personParams 组织者或参会人的 CN 参数, 名称含特殊字符时加引号
+func personParams(p *Person) string {
+	if p.Name == "" {
+		return ""
+	}
+	name := strings.NewReplacer(`"`, "", "\r", "", "\n", "").Replace(p.Name)
+	if strings.ContainsAny(name, ":;,") {
+		name = `"` + name + `"`
+	}
+	return ";CN=" + name
+}
+
+func mailto(p *Person) string {
+	return "mailto:" + p.Email
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(dateTimeLayout)
+}
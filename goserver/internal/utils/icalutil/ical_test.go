@@ -0,0 +1,60 @@
+package icalutil
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendar_WriteTo(t *testing.T) {
+	start := time.Date(2026, 1, 5, 17, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	recurrence, err := ParseRecurrence("FREQ=WEEKLY;COUNT=4")
+	require.NoError(t, err)
+
+	calendar := &Calendar{
+		Name: "会议",
+		Events: []*Event{{
+			UID:         "meeting-1@example.com",
+			Sequence:    2,
+			Summary:     "周会; 第一季度, 规划",
+			Description: "议程:\n1. 回顾\n2. " + strings.Repeat("计划", 30),
+			Start:       start,
+			End:         start.Add(time.Hour),
+			Stamp:       start,
+			Recurrence:  recurrence,
+			Organizer:   &Person{Name: "alice", Email: "alice@example.com"},
+			Attendees:   []*Person{{Name: "bob, jr", Email: "bob@example.com"}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	n, err := calendar.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+	for _, want := range []string{
+		"\r\nDTSTART:20260105T090000Z\r\n",
+		"\r\nDTEND:20260105T100000Z\r\n",
+		"\r\nRRULE:FREQ=WEEKLY;COUNT=4\r\n",
+		"\r\nSUMMARY:周会\\; 第一季度\\, 规划\r\n",
+		"\r\nORGANIZER;CN=alice:mailto:alice@example.com\r\n",
+		"\r\nATTENDEE;CN=\"bob, jr\":mailto:bob@example.com\r\n",
+		"\r\nSTATUS:CONFIRMED\r\n",
+	} {
+		assert.Contains(t, out, want)
+	}
+
+	// 折行后每行不超过 75 字节, 展开后还原描述
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), maxLineOctets)
+	}
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	assert.Contains(t, unfolded, "DESCRIPTION:议程:\\n1. 回顾\\n2. "+strings.Repeat("计划", 30)+"\r\n")
+}
@@ -0,0 +1,179 @@
+// Package icalutil iCalendar(RFC 5545) 相关的工具
+package icalutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// 支持的重复频率
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+// untilLayout RRULE 中 UNTIL 的 UTC 时间格式
+const untilLayout = "20060102T150405Z"
+
+// Recurrence 重复规则, 仅支持 RRULE 中的 FREQ、INTERVAL、COUNT 和 UNTIL
+// 按月或按年重复时跳过不存在的日期(如 2 月 30 日), 与 RFC 5545 一致, 跳过的日期不计入 COUNT
+type Recurrence struct {
+	Freq     string
+	Interval int       // 间隔, 最小为 1
+	Count    int       // 重复次数(含首次), 0 表示不限
+	Until    time.Time // 最后一次开始时间的上限, 零值表示不限
+}
+
+// ParseRecurrence 解析 RRULE, 如 FREQ=WEEKLY;INTERVAL=2;COUNT=10
+// 可带 RRULE: 前缀, 为空时返回 nil
+func ParseRecurrence(rule string) (*Recurrence, error) {
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if rule == "" {
+		return nil, nil
+	}
+
+	r := &Recurrence{Interval: 1}
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, errors.Errorf("无效的重复规则: %s", part)
+		}
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+			if err == nil && r.Interval < 1 {
+				err = errors.New("INTERVAL 必须大于 0")
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+			if err == nil && r.Count < 1 {
+				err = errors.New("COUNT 必须大于 0")
+			}
+		case "UNTIL":
+			r.Until, err = time.Parse(untilLayout, value)
+		default:
+			err = errors.Errorf("不支持的重复规则: %s", key)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "无效的重复规则: %s", part)
+		}
+	}
+
+	switch r.Freq {
+	case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+	default:
+		return nil, errors.Errorf("不支持的重复频率: %q", r.Freq)
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, errors.New("COUNT 与 UNTIL 不能同时使用")
+	}
+	return r, nil
+}
+
+// String 规范化的 RRULE, 不含 RRULE: 前缀
+func (r *Recurrence) String() string {
+	if r == nil {
+		return ""
+	}
+	s := "FREQ=" + r.Freq
+	if r.Interval > 1 {
+		s += fmt.Sprintf(";INTERVAL=%d", r.Interval)
+	}
+	if r.Count > 0 {
+		s += fmt.Sprintf(";COUNT=%d", r.Count)
+	}
+	if !r.Until.IsZero() {
+		s += ";UNTIL=" + r.Until.UTC().Format(untilLayout)
+	}
+	return s
+}
+
+// Period 两次重复间的最短间隔, 用于校验单次会议时长
+func (r *Recurrence) Period() time.Duration {
+	days := 0
+	switch r.Freq {
+	case FreqDaily:
+		days = 1
+	case FreqWeekly:
+		days = 7
+	case FreqMonthly:
+		days = 28
+	case FreqYearly:
+		days = 365
+	}
+	return time.Duration(days*r.Interval) * 24 * time.Hour
+}
+
+// nth 第 n 个周期(从 0 开始)的开始时间, 该周期内没有首次的日期时 ok 为 false
+func (r *Recurrence) nth(start time.Time, n int) (t time.Time, ok bool) {
+	step := n * r.Interval
+	switch r.Freq {
+	case FreqDaily:
+		return start.AddDate(0, 0, step), true
+	case FreqWeekly:
+		return start.AddDate(0, 0, 7*step), true
+	case FreqMonthly:
+		t = start.AddDate(0, step, 0)
+	default:
+		t = start.AddDate(step, 0, 0)
+	}
+	// time.AddDate 会将 1 月 31 日加一个月规范化为 3 月 3 日
+	return t, t.Day() == start.Day()
+}
+
+// skip 估算在 t 之前至少已经结束的次数, 避免从首次开始逐次计算
+func (r *Recurrence) skip(start, t time.Time) int {
+	var units int
+	switch r.Freq {
+	case FreqDaily:
+		units = int(t.Sub(start).Hours() / 24)
+	case FreqWeekly:
+		units = int(t.Sub(start).Hours() / 24 / 7)
+	case FreqMonthly:
+		units = (t.Year()-start.Year())*12 + int(t.Month()) - int(start.Month())
+	default:
+		units = t.Year() - start.Year()
+	}
+	// 少算两次, 抵消时长和夏令时带来的误差
+	if n := units/r.Interval - 2; n > 0 {
+		return n
+	}
+	return 0
+}
+
+// Next 返回结束时间晚于 t 的第一次的开始和结束时间, 即正在进行或下一次
+// start 和 end 为首次的时间, r 为 nil 时表示不重复. 全部结束后 ok 为 false
+func (r *Recurrence) Next(start, end, t time.Time) (from, to time.Time, ok bool) {
+	duration := end.Sub(start)
+	if r == nil {
+		return start, end, end.After(t)
+	}
+	// 跳过的日期不计入 COUNT, 设置了 COUNT 时从首次开始计算
+	n := 0
+	if r.Count == 0 {
+		n = r.skip(start, t)
+	}
+	for count := 0; r.Count == 0 || count < r.Count; n++ {
+		from, valid := r.nth(start, n)
+		if !r.Until.IsZero() && from.After(r.Until) {
+			break
+		}
+		if !valid {
+			continue
+		}
+		count++
+		if to = from.Add(duration); to.After(t) {
+			return from, to, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
@@ -0,0 +1,168 @@
+package icalutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   string
+		expVal string
+		expErr bool
+	}{
+		{name: "empty", rule: "", expVal: ""},
+		{name: "weekly", rule: "RRULE:FREQ=WEEKLY;INTERVAL=2;COUNT=10", expVal: "FREQ=WEEKLY;INTERVAL=2;COUNT=10"},
+		{name: "lower case", rule: "freq=daily;interval=1", expVal: "FREQ=DAILY"},
+		{name: "until", rule: "FREQ=MONTHLY;UNTIL=20261231T000000Z", expVal: "FREQ=MONTHLY;UNTIL=20261231T000000Z"},
+		{name: "missing freq", rule: "COUNT=3", expErr: true},
+		{name: "unsupported part", rule: "FREQ=WEEKLY;BYDAY=MO", expErr: true},
+		{name: "invalid interval", rule: "FREQ=DAILY;INTERVAL=0", expErr: true},
+		{name: "count and until", rule: "FREQ=DAILY;COUNT=2;UNTIL=20261231T000000Z", expErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := ParseRecurrence(test.rule)
+			if test.expErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expVal, r.String())
+		})
+	}
+}
+
+func TestRecurrence_Next(t *testing.T) {
+	start := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	t.Run("不重复", func(t *testing.T) {
+		var r *Recurrence
+		from, _, ok := r.Next(start, end, at(1, 5, 9, 30))
+		require.True(t, ok)
+		assert.Equal(t, start, from)
+
+		_, _, ok = r.Next(start, end, at(1, 5, 10, 0))
+		assert.False(t, ok)
+	})
+
+	t.Run("每周重复", func(t *testing.T) {
+		r, err := ParseRecurrence("FREQ=WEEKLY;COUNT=3")
+		require.NoError(t, err)
+
+		// 进行中
+		from, to, ok := r.Next(start, end, at(1, 12, 9, 30))
+		require.True(t, ok)
+		assert.Equal(t, at(1, 12, 9, 0), from)
+		assert.Equal(t, at(1, 12, 10, 0), to)
+
+		// 两次之间返回下一次
+		from, _, ok = r.Next(start, end, at(1, 13, 0, 0))
+		require.True(t, ok)
+		assert.Equal(t, at(1, 19, 9, 0), from)
+
+		// 首次之前
+		from, _, ok = r.Next(start, end, at(1, 1, 0, 0))
+		require.True(t, ok)
+		assert.Equal(t, start, from)
+
+		_, _, ok = r.Next(start, end, at(1, 19, 10, 0))
+		assert.False(t, ok)
+	})
+
+	t.Run("按月重复至截止时间", func(t *testing.T) {
+		r, err := ParseRecurrence("FREQ=MONTHLY;UNTIL=20260405T090000Z")
+		require.NoError(t, err)
+
+		from, _, ok := r.Next(start, end, at(3, 20, 0, 0))
+		require.True(t, ok)
+		assert.Equal(t, at(4, 5, 9, 0), from)
+
+		_, _, ok = r.Next(start, end, at(4, 6, 0, 0))
+		assert.False(t, ok)
+	})
+
+	t.Run("按月重复跳过不存在的日期", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			rule   string
+			start  time.Time
+			expVal []time.Time
+		}{
+			{
+				name:   "29 日",
+				rule:   "FREQ=MONTHLY;COUNT=3",
+				start:  time.Date(2026, 1, 29, 9, 0, 0, 0, time.UTC),
+				expVal: []time.Time{at(1, 29, 9, 0), at(3, 29, 9, 0), at(4, 29, 9, 0)},
+			},
+			{
+				name:   "30 日",
+				rule:   "FREQ=MONTHLY;COUNT=3",
+				start:  time.Date(2026, 1, 30, 9, 0, 0, 0, time.UTC),
+				expVal: []time.Time{at(1, 30, 9, 0), at(3, 30, 9, 0), at(4, 30, 9, 0)},
+			},
+			{
+				name:   "31 日",
+				rule:   "FREQ=MONTHLY;COUNT=4",
+				start:  time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC),
+				expVal: []time.Time{at(1, 31, 9, 0), at(3, 31, 9, 0), at(5, 31, 9, 0), at(7, 31, 9, 0)},
+			},
+			{
+				name:  "闰年 2 月 29 日",
+				rule:  "FREQ=YEARLY;COUNT=2",
+				start: time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC),
+				expVal: []time.Time{
+					time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC),
+					time.Date(2028, 2, 29, 9, 0, 0, 0, time.UTC),
+				},
+			},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				r, err := ParseRecurrence(test.rule)
+				require.NoError(t, err)
+
+				got := make([]time.Time, 0)
+				now := test.start.Add(-time.Hour)
+				for {
+					from, to, ok := r.Next(test.start, test.start.Add(time.Hour), now)
+					if !ok {
+						break
+					}
+					got = append(got, from)
+					now = to
+				}
+				assert.Equal(t, test.expVal, got)
+			})
+		}
+
+		// 不限次数时跳过的日期同样不出现
+		r, err := ParseRecurrence("FREQ=MONTHLY")
+		require.NoError(t, err)
+		start := time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC)
+		from, _, ok := r.Next(start, start.Add(time.Hour), at(2, 1, 0, 0))
+		require.True(t, ok)
+		assert.Equal(t, at(3, 31, 9, 0), from)
+	})
+
+	t.Run("长期每日重复", func(t *testing.T) {
+		r, err := ParseRecurrence("FREQ=DAILY;INTERVAL=3")
+		require.NoError(t, err)
+
+		now := time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+		from, _, ok := r.Next(start, end, now)
+		require.True(t, ok)
+		assert.True(t, from.After(now))
+		assert.True(t, from.Sub(now) <= 3*24*time.Hour)
+		assert.Zero(t, int(from.Sub(start).Hours())%(3*24))
+	})
+}
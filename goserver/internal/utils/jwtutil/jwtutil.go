@@ -4,24 +4,49 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"io.wandao.meeting/internal/conf"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	TokenTypeAccess  = "access"  // 访问令牌
+	TokenTypeRefresh = "refresh" // 刷新令牌
 )
 
 type UserClaims struct {
-	Id   uint64 `json:"id"`
-	Name string `json:"name"`
+	Id      uint64 `json:"id"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Version uint64 `json:"ver"` // 签发时的用户令牌版本, 与当前版本不同时令牌失效
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成 token
-func GenerateToken(id uint64, name string) (string, error) {
+// GenerateToken 生成访问 token, version 为用户当前的令牌版本
+func GenerateToken(id uint64, name string, version uint64) (string, error) {
+	return generateToken(id, name, version, TokenTypeAccess, conf.Security.AccessTokenTTL)
+}
+
+// GenerateRefreshToken 生成刷新 token, version 为用户当前的令牌版本
+func GenerateRefreshToken(id uint64, name string, version uint64) (string, error) {
+	return generateToken(id, name, version, TokenTypeRefresh, conf.Security.RefreshTokenTTL)
+}
+
+func generateToken(id uint64, name string, version uint64, tokenType string, ttl time.Duration) (string, error) {
+	now := time.Now()
 	UserClaim := &UserClaims{
-		Id:               id,
-		Name:             name,
-		RegisteredClaims: jwt.RegisteredClaims{},
+		Id:      id,
+		Name:    name,
+		Type:    tokenType,
+		Version: version,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, UserClaim)
 	tokenString, err := token.SignedString([]byte(conf.Security.SecretKey))
@@ -31,18 +56,30 @@ func GenerateToken(id uint64, name string) (string, error) {
 	return tokenString, nil
 }
 
-// AnalyseToken 解析 token
+// AnalyseToken 解析访问 token
 func AnalyseToken(tokenString string) (*UserClaims, error) {
+	return analyseToken(tokenString, TokenTypeAccess)
+}
+
+// AnalyseRefreshToken 解析刷新 token
+func AnalyseRefreshToken(tokenString string) (*UserClaims, error) {
+	return analyseToken(tokenString, TokenTypeRefresh)
+}
+
+func analyseToken(tokenString string, tokenType string) (*UserClaims, error) {
 	userClaim := new(UserClaims)
 	claims, err := jwt.ParseWithClaims(tokenString, userClaim, func(token *jwt.Token) (interface{}, error) {
 		return []byte(conf.Security.SecretKey), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
 	if err != nil {
 		return nil, err
 	}
 	if !claims.Valid {
 		return nil, fmt.Errorf("analyse Token Error:%v", err)
 	}
+	if userClaim.Type != tokenType {
+		return nil, fmt.Errorf("analyse Token Error: unexpected token type %q", userClaim.Type)
+	}
 	return userClaim, nil
 }
 
@@ -0,0 +1,67 @@
+package jwtutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io.wandao.meeting/internal/conf"
+)
+
+func TestGenerateToken(t *testing.T) {
+	conf.SetMockSecurity(t, conf.SecurityOpts{
+		SecretKey:       "secret",
+		AccessTokenTTL:  time.Hour,
+		RefreshTokenTTL: 24 * time.Hour,
+	})
+
+	t.Run("访问令牌", func(t *testing.T) {
+		token, err := GenerateToken(1, "admin", 0)
+		require.NoError(t, err)
+
+		claims, err := AnalyseToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), claims.Id)
+		assert.Equal(t, "admin", claims.Name)
+		assert.NotEmpty(t, claims.ID)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), claims.ExpiresAt.Time, time.Minute)
+
+		_, err = AnalyseRefreshToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("刷新令牌", func(t *testing.T) {
+		token, err := GenerateRefreshToken(1, "admin", 2)
+		require.NoError(t, err)
+
+		claims, err := AnalyseRefreshToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, TokenTypeRefresh, claims.Type)
+		assert.Equal(t, uint64(2), claims.Version)
+
+		_, err = AnalyseToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("令牌唯一", func(t *testing.T) {
+		a, err := GenerateToken(1, "admin", 0)
+		require.NoError(t, err)
+		b, err := GenerateToken(1, "admin", 0)
+		require.NoError(t, err)
+		assert.NotEqual(t, a, b)
+	})
+}
+
+func TestAnalyseToken_Expired(t *testing.T) {
+	conf.SetMockSecurity(t, conf.SecurityOpts{
+		SecretKey:      "secret",
+		AccessTokenTTL: -time.Minute,
+	})
+
+	token, err := GenerateToken(1, "admin", 0)
+	require.NoError(t, err)
+
+	_, err = AnalyseToken(token)
+	assert.Error(t, err)
+}
@@ -0,0 +1,26 @@
+package tool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MimeType(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		expVal string
+	}{
+		{name: "png", data: []byte("\x89PNG\x0D\x0A\x1A\x0A"), expVal: "image/png"},
+		{name: "pdf", data: []byte("%PDF-1.7"), expVal: "application/pdf"},
+		{name: "text", data: []byte("hello"), expVal: "text/plain"},
+		{name: "empty", data: nil, expVal: "text/plain"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expVal, MimeType(test.data))
+		})
+	}
+}
@@ -30,6 +30,7 @@ export class Client {
   isPeerAuthEnabled: boolean = false
   isPeerReconnected: boolean = false
   isOwner: boolean = false // 是否主持人
+  resumeToken: string = '' // 断线重连后恢复会话的凭证, 进入房间后由服务端下发
   isRulesActive = true // 是否主持人可以做任何事情 false 所有人平等
   userLimits: KeyValue = {
     // 是否限制每个房间的用户数
@@ -56,7 +57,7 @@ export class Client {
     this.socket = new WebSocketServer(import.meta.env.RENDERER_VITE_WEBRTC_URL)
     this.socket.onOpen(this.handleConnect.bind(this))
     this.socket.onMessage(this.onMessage.bind(this))
-    this.socket.onClose(this.handleDisconnect.bind(this))
+    this.socket.onClose(this.handleSocketClose.bind(this))
   }
 
   onMessage(cmd: string, args: KeyValue) {
@@ -118,14 +119,31 @@ export class Client {
       case 'exit':
         this.handleRemovePeer(args)
         break
+      case 'serverShutdown':
+        this.handleServerShutdown(args)
+        break
+      case 'lobbyAdmitted':
+        this.resumeToken = args.resumeToken || ''
+        break
       default:
         break
     }
   }
 
+  // 服务器即将关闭, 连接断开后按服务端建议的时间重连
+  handleServerShutdown(args: KeyValue) {
+    console.log('服务器即将关闭', args.message)
+    if (args.reconnect) {
+      this.socket.reconnectAfter(args.retryAfter)
+    }
+  }
+
   // 连接成功
   async handleConnect() {
     console.log('03. 信令服务器连接成功')
+    if (this.resumeToken && (await this.resume())) {
+      return
+    }
     if (this.mediaServer.localVideoStream && this.mediaServer.localAudioStream) {
       this.login()
     } else {
@@ -134,11 +152,42 @@ export class Client {
     }
   }
 
+  // 断线重连后恢复会话, 补发断开期间错过的房间事件, 失败时需重新登录
+  async resume(): Promise<boolean> {
+    try {
+      const data = await this.socket.request('resume', {
+        roomId: local.value.roomId,
+        userId: local.value.userId,
+        token: this.resumeToken,
+      })
+      this.resumeToken = data.resumeToken
+      for (const message of data.missed || []) {
+        this.onMessage(message.cmd, message.data)
+      }
+      return true
+    } catch (error) {
+      console.warn('恢复会话失败, 重新登录', error)
+      this.resumeToken = ''
+      this.handleDisconnect({})
+      return false
+    }
+  }
+
+  // 信令连接断开, 有恢复凭证时保留 peer 连接等待恢复会话
+  handleSocketClose(args: KeyValue) {
+    if (this.resumeToken) {
+      console.log('信令连接断开, 等待恢复会话', { args })
+      return
+    }
+    this.handleDisconnect(args)
+  }
+
   // 进入房间
   async login() {
     console.log('12. join to room', local.value.roomId)
-    this.sendToServer('login', {
-      token: '',
+    this.socket.request('login', {
+      token: webrtcStore.token,
+      version: 1,
 
       roomId: local.value.roomId,
       roomName: local.value.roomName,
@@ -159,6 +208,10 @@ export class Client {
       handStatus: local.value.handStatus,
       recordStatus: local.value.recordStatus,
       privacyStatus: local.value.privacyStatus,
+    }).then((data) => {
+      this.resumeToken = data?.resumeToken || ''
+    }).catch((error) => {
+      console.error('[WebSocket] login 失败:', error)
     })
   }
 
@@ -765,9 +818,7 @@ export class Client {
       action,
       userId,
       roomId: local.value.roomId,
-      roomName: '',
-
-      useVideo: local.value.useVideo,
+      peerVideo: local.value.useVideo,
       sendToAll: false,
     })
   }
@@ -7,11 +7,15 @@ export class WebSocketServer {
   private reconnectInterval: number
   private socket: WebSocket | null = null
   private reconnectTimeout: NodeJS.Timeout | null = null // 重连间隔 ms
+  private nextReconnectDelay: number | null = null // 服务端指定的下次重连等待时间 ms
   private heartbeatTimeout: NodeJS.Timeout | null = null // 心跳间隔 ms
   private onOpenCallback?: (event: Event) => void
   private onMessageCallback?: (type: string, args: KeyValue) => void
   private onErrorCallback?: (error: Event) => void
   private onCloseCallback?: (event: CloseEvent) => void
+  private seqId: number = 0
+  // 等待服务端应答的请求 seq => 回调
+  private pending = new Map<string, { resolve: (data: any) => void; reject: (error: Error) => void }>()
 
   constructor(url: string, heartbeatInterval: number = 30000, reconnectInterval: number = 3000) {
     this.url = url
@@ -35,8 +39,12 @@ export class WebSocketServer {
     }
 
     this.socket.onmessage = (event) => {
-      const { cmd, data } = JSON.parse(event.data)
-      this.onMessageCallback?.(cmd, data)
+      const { seq, cmd, data, response } = JSON.parse(event.data)
+      if (response) {
+        this.onResponse(seq, cmd, response)
+      } else {
+        this.onMessageCallback?.(cmd, data)
+      }
       this.resetHeartbeat()
     }
 
@@ -48,6 +56,8 @@ export class WebSocketServer {
     this.socket.onclose = (event) => {
       console.log('[WebSocket]: 已关闭')
       this.stopHeartbeat()
+      // 连接断开后不会再收到这些请求的应答
+      this.rejectPending()
       this.reconnect()
       this.onCloseCallback?.(event)
     }
@@ -80,20 +90,55 @@ export class WebSocketServer {
       clearTimeout(this.reconnectTimeout)
     }
     console.log('[WebSocket] 尝试重新连接...')
+    const delay = this.nextReconnectDelay ?? this.reconnectInterval
+    this.nextReconnectDelay = null
     this.reconnectTimeout = setTimeout(() => {
       this.connect()
-    }, this.reconnectInterval)
+    }, delay)
+  }
+
+  // 服务端关闭前通知的重连等待时间, 在连接关闭后生效
+  public reconnectAfter(delay: number): void {
+    this.nextReconnectDelay = delay
+  }
+
+  private onResponse(seq: string, cmd: string, response: KeyValue): void {
+    const { code, codeMsg, data } = response
+    const pending = this.pending.get(seq)
+    if (pending) {
+      this.pending.delete(seq)
+      if (code === 200) {
+        pending.resolve(data)
+      } else {
+        pending.reject(new Error(codeMsg))
+      }
+    } else if (code !== 200) {
+      console.error(`[WebSocket] ${cmd} 失败:`, code, codeMsg)
+    }
   }
 
-  public send(cmd: string, message: KeyValue = {}): void {
+  public send(cmd: string, message: KeyValue = {}): string | undefined {
     if (this.socket && this.socket.readyState === WebSocket.OPEN) {
-      const seq = Date.now().toString()
+      const seq = `${Date.now()}-${++this.seqId}`
       this.socket.send(JSON.stringify({ seq, cmd, data: message || {} }))
+      return seq
     } else {
       console.error('[WebSocket]未打开, 消息未能发送:', message)
     }
   }
 
+  // 发送请求并等待服务端应答, 应答 code 不为 200 时 reject
+  public request(cmd: string, message: KeyValue = {}): Promise<any> {
+    return new Promise((resolve, reject) => {
+      const seq = this.send(cmd, message)
+      if (!seq) {
+        reject(new Error('[WebSocket]未打开'))
+        return
+      }
+      this.pending.set(seq, { resolve, reject })
+    })
+  }
+
   public onOpen(callback: (event: Event) => void): void {
     this.onOpenCallback = callback
   }
@@ -110,7 +155,13 @@ export class WebSocketServer {
     this.onCloseCallback = callback
   }
 
+  private rejectPending(): void {
+    this.pending.forEach(({ reject }) => reject(new Error('[WebSocket]已关闭')))
+    this.pending.clear()
+  }
+
   public close(): void {
+    this.rejectPending()
     if (this.socket) {
       this.stopHeartbeat()
       this.socket.close()